@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// IdempotencyStoreAdapter adapts the infra repository to the idempotency store
+// of the Connect interceptor.
+type IdempotencyStoreAdapter struct {
+	infraRepo *persistence.IdempotencyRepo
+}
+
+func NewIdempotencyStoreAdapter(infraRepo *persistence.IdempotencyRepo) *IdempotencyStoreAdapter {
+	return &IdempotencyStoreAdapter{infraRepo: infraRepo}
+}
+
+// Ensure interface compliance.
+var _ connectx.IdempotencyStore = (*IdempotencyStoreAdapter)(nil)
+
+func (a *IdempotencyStoreAdapter) Reserve(
+	ctx context.Context,
+	client, key, procedure, reservation string,
+	fingerprint []byte,
+	lease time.Duration,
+) (*connectx.IdempotencyRecord, error) {
+	existing, err := a.infraRepo.Reserve(ctx, client, key, procedure, reservation, fingerprint, lease)
+	if err != nil {
+		return nil, fmt.Errorf("adapter: failed to reserve idempotency key: %w", err)
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	return &connectx.IdempotencyRecord{
+		Fingerprint: existing.Fingerprint,
+		Completed:   existing.CompletedAt.Valid,
+		Response:    existing.Response,
+	}, nil
+}
+
+func (a *IdempotencyStoreAdapter) Complete(
+	ctx context.Context, client, key, procedure, reservation string, response []byte, ttl time.Duration,
+) error {
+	err := a.infraRepo.Complete(ctx, client, key, procedure, reservation, response, ttl)
+	if errors.Is(err, persistence.ErrIdempotencyLeaseLost) {
+		return connectx.ErrIdempotencyLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("adapter: failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (a *IdempotencyStoreAdapter) Release(ctx context.Context, client, key, procedure, reservation string) error {
+	err := a.infraRepo.Delete(ctx, client, key, procedure, reservation)
+	if errors.Is(err, persistence.ErrIdempotencyLeaseLost) {
+		return connectx.ErrIdempotencyLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("adapter: failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Purge deletes the expired keys and returns how many were deleted.
+// Expired keys are replaced on reuse anyway: the purge only reclaims space.
+func (a *IdempotencyStoreAdapter) Purge(ctx context.Context) (int64, error) {
+	deleted, err := a.infraRepo.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("adapter: failed to purge idempotency keys: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,43 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// RateLimitStoreAdapter adapts the infra repository to the rate limit store
+// of the Connect interceptor.
+type RateLimitStoreAdapter struct {
+	infraRepo *persistence.RateLimitRepo
+}
+
+func NewRateLimitStoreAdapter(infraRepo *persistence.RateLimitRepo) *RateLimitStoreAdapter {
+	return &RateLimitStoreAdapter{infraRepo: infraRepo}
+}
+
+// Ensure interface compliance.
+var _ connectx.RateLimitStore = (*RateLimitStoreAdapter)(nil)
+
+func (a *RateLimitStoreAdapter) Take(ctx context.Context, key string, limit connectx.RateLimit) (time.Duration, error) {
+	tokens, err := a.infraRepo.Take(ctx, key, float64(limit.Burst), limit.Rate())
+	if err != nil {
+		return 0, fmt.Errorf("adapter: failed to take rate limit token: %w", err)
+	}
+
+	return limit.RetryAfter(tokens), nil
+}
+
+// Purge deletes the full buckets, which are the same as new ones, and returns
+// how many were deleted.
+func (a *RateLimitStoreAdapter) Purge(ctx context.Context) (int64, error) {
+	deleted, err := a.infraRepo.DeleteFull(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("adapter: failed to purge rate limit buckets: %w", err)
+	}
+
+	return deleted, nil
+}
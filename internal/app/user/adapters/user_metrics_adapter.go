@@ -0,0 +1,59 @@
+package adapters
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+)
+
+// UserMetricsAdapter counts the changes of the users in Prometheus counters,
+// the created users by role.
+type UserMetricsAdapter struct {
+	created *prometheus.CounterVec
+	updated prometheus.Counter
+	deleted prometheus.Counter
+}
+
+func NewUserMetricsAdapter(reg *metrics.Registry) (*UserMetricsAdapter, error) {
+	a := &UserMetricsAdapter{
+		created: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "users_created_total",
+			Help: "Number of users created, by role.",
+		}, []string{"role"}),
+		updated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_updated_total",
+			Help: "Number of users updated.",
+		}),
+		deleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_deleted_total",
+			Help: "Number of users deleted.",
+		}),
+	}
+	// The roles are known in advance, their counters start at zero.
+	for _, role := range entity.RoleValues() {
+		a.created.WithLabelValues(role.String())
+	}
+
+	if err := reg.Register(a.created, a.updated, a.deleted); err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped by the registry
+	}
+
+	return a, nil
+}
+
+// Ensure interface compliance.
+var _ ports.UserMetrics = (*UserMetricsAdapter)(nil)
+
+func (a *UserMetricsAdapter) UserCreated(role entity.Role) {
+	a.created.WithLabelValues(role.String()).Inc()
+}
+
+func (a *UserMetricsAdapter) UserUpdated() {
+	a.updated.Inc()
+}
+
+func (a *UserMetricsAdapter) UserDeleted() {
+	a.deleted.Inc()
+}
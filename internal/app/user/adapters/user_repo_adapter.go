@@ -66,6 +66,32 @@ func (a *UserRepositoryAdapter) List(ctx context.Context, offset, limit int) ([]
 	return users, total, nil
 }
 
+func (a *UserRepositoryAdapter) Search(
+	ctx context.Context,
+	filter ports.UserFilter,
+	offset, limit int,
+) ([]*entity.User, int64, error) {
+	users, total, err := a.infraRepo.Search(ctx, persistence.SearchFilter{
+		Role:  filter.Role.String(),
+		Email: filter.Email,
+		Name:  filter.Name,
+	}, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adapter: failed to search users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (a *UserRepositoryAdapter) GetByIDs(ctx context.Context, ids []int64) ([]*entity.User, error) {
+	users, err := a.infraRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("adapter: failed to get users by ids: %w", err)
+	}
+
+	return users, nil
+}
+
 func (a *UserRepositoryAdapter) Update(ctx context.Context, user *entity.User) (*entity.User, error) {
 	result, err := a.infraRepo.Update(ctx, user)
 	if errors.Is(err, persistence.ErrUserNotFound) {
@@ -89,3 +115,43 @@ func (a *UserRepositoryAdapter) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+func (a *UserRepositoryAdapter) CreateBatch(
+	ctx context.Context,
+	users []*entity.User,
+	policy ports.ConflictPolicy,
+) ([]ports.BatchResult, error) {
+	onConflict := persistence.OnConflictNothing
+	if policy == ports.ConflictPolicyUpdate {
+		onConflict = persistence.OnConflictUpdate
+	}
+
+	rows, err := a.infraRepo.CreateBatch(ctx, users, onConflict)
+	if err != nil {
+		return nil, fmt.Errorf("adapter: failed to create user batch: %w", err)
+	}
+
+	results := make([]ports.BatchResult, len(rows))
+	for i, row := range rows {
+		results[i] = toBatchResult(row, policy)
+	}
+
+	return results, nil
+}
+
+func toBatchResult(row persistence.BatchRow, policy ports.ConflictPolicy) ports.BatchResult {
+	switch row.Status {
+	case persistence.BatchInserted:
+		return ports.BatchResult{Outcome: ports.BatchCreated, User: row.User}
+	case persistence.BatchUpdated:
+		return ports.BatchResult{Outcome: ports.BatchUpdated, User: row.User}
+	case persistence.BatchConflict:
+		if policy == ports.ConflictPolicySkip {
+			return ports.BatchResult{Outcome: ports.BatchSkipped}
+		}
+
+		return ports.BatchResult{Outcome: ports.BatchConflict}
+	default:
+		return ports.BatchResult{Outcome: ports.BatchFailed, Err: row.Err}
+	}
+}
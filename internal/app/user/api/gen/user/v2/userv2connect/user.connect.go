@@ -0,0 +1,267 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: user/v2/user.proto
+
+package userv2connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	v2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// UserServiceName is the fully-qualified name of the UserService service.
+	UserServiceName = "user.v2.UserService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// UserServiceCreateUserProcedure is the fully-qualified name of the UserService's CreateUser RPC.
+	UserServiceCreateUserProcedure = "/user.v2.UserService/CreateUser"
+	// UserServiceGetUserProcedure is the fully-qualified name of the UserService's GetUser RPC.
+	UserServiceGetUserProcedure = "/user.v2.UserService/GetUser"
+	// UserServiceGetUserByEmailProcedure is the fully-qualified name of the UserService's
+	// GetUserByEmail RPC.
+	UserServiceGetUserByEmailProcedure = "/user.v2.UserService/GetUserByEmail"
+	// UserServiceListUsersProcedure is the fully-qualified name of the UserService's ListUsers RPC.
+	UserServiceListUsersProcedure = "/user.v2.UserService/ListUsers"
+	// UserServiceUpdateUserProcedure is the fully-qualified name of the UserService's UpdateUser RPC.
+	UserServiceUpdateUserProcedure = "/user.v2.UserService/UpdateUser"
+	// UserServiceDeleteUserProcedure is the fully-qualified name of the UserService's DeleteUser RPC.
+	UserServiceDeleteUserProcedure = "/user.v2.UserService/DeleteUser"
+)
+
+// UserServiceClient is a client for the user.v2.UserService service.
+type UserServiceClient interface {
+	// Creates a user. The password is hashed before being stored.
+	CreateUser(context.Context, *connect.Request[v2.CreateUserRequest]) (*connect.Response[v2.CreateUserResponse], error)
+	// Returns a user by its identifier.
+	GetUser(context.Context, *connect.Request[v2.GetUserRequest]) (*connect.Response[v2.GetUserResponse], error)
+	// Returns a user by its email address.
+	GetUserByEmail(context.Context, *connect.Request[v2.GetUserByEmailRequest]) (*connect.Response[v2.GetUserByEmailResponse], error)
+	// Lists users, most recent first, with offset pagination.
+	ListUsers(context.Context, *connect.Request[v2.ListUsersRequest]) (*connect.Response[v2.ListUsersResponse], error)
+	// Updates the fields listed in the update mask, the other ones are left untouched.
+	UpdateUser(context.Context, *connect.Request[v2.UpdateUserRequest]) (*connect.Response[v2.UpdateUserResponse], error)
+	// Soft-deletes a user.
+	DeleteUser(context.Context, *connect.Request[v2.DeleteUserRequest]) (*connect.Response[v2.DeleteUserResponse], error)
+}
+
+// NewUserServiceClient constructs a client for the user.v2.UserService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewUserServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) UserServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	userServiceMethods := v2.File_user_v2_user_proto.Services().ByName("UserService").Methods()
+	return &userServiceClient{
+		createUser: connect.NewClient[v2.CreateUserRequest, v2.CreateUserResponse](
+			httpClient,
+			baseURL+UserServiceCreateUserProcedure,
+			connect.WithSchema(userServiceMethods.ByName("CreateUser")),
+			connect.WithClientOptions(opts...),
+		),
+		getUser: connect.NewClient[v2.GetUserRequest, v2.GetUserResponse](
+			httpClient,
+			baseURL+UserServiceGetUserProcedure,
+			connect.WithSchema(userServiceMethods.ByName("GetUser")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		getUserByEmail: connect.NewClient[v2.GetUserByEmailRequest, v2.GetUserByEmailResponse](
+			httpClient,
+			baseURL+UserServiceGetUserByEmailProcedure,
+			connect.WithSchema(userServiceMethods.ByName("GetUserByEmail")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		listUsers: connect.NewClient[v2.ListUsersRequest, v2.ListUsersResponse](
+			httpClient,
+			baseURL+UserServiceListUsersProcedure,
+			connect.WithSchema(userServiceMethods.ByName("ListUsers")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		updateUser: connect.NewClient[v2.UpdateUserRequest, v2.UpdateUserResponse](
+			httpClient,
+			baseURL+UserServiceUpdateUserProcedure,
+			connect.WithSchema(userServiceMethods.ByName("UpdateUser")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteUser: connect.NewClient[v2.DeleteUserRequest, v2.DeleteUserResponse](
+			httpClient,
+			baseURL+UserServiceDeleteUserProcedure,
+			connect.WithSchema(userServiceMethods.ByName("DeleteUser")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// userServiceClient implements UserServiceClient.
+type userServiceClient struct {
+	createUser     *connect.Client[v2.CreateUserRequest, v2.CreateUserResponse]
+	getUser        *connect.Client[v2.GetUserRequest, v2.GetUserResponse]
+	getUserByEmail *connect.Client[v2.GetUserByEmailRequest, v2.GetUserByEmailResponse]
+	listUsers      *connect.Client[v2.ListUsersRequest, v2.ListUsersResponse]
+	updateUser     *connect.Client[v2.UpdateUserRequest, v2.UpdateUserResponse]
+	deleteUser     *connect.Client[v2.DeleteUserRequest, v2.DeleteUserResponse]
+}
+
+// CreateUser calls user.v2.UserService.CreateUser.
+func (c *userServiceClient) CreateUser(ctx context.Context, req *connect.Request[v2.CreateUserRequest]) (*connect.Response[v2.CreateUserResponse], error) {
+	return c.createUser.CallUnary(ctx, req)
+}
+
+// GetUser calls user.v2.UserService.GetUser.
+func (c *userServiceClient) GetUser(ctx context.Context, req *connect.Request[v2.GetUserRequest]) (*connect.Response[v2.GetUserResponse], error) {
+	return c.getUser.CallUnary(ctx, req)
+}
+
+// GetUserByEmail calls user.v2.UserService.GetUserByEmail.
+func (c *userServiceClient) GetUserByEmail(ctx context.Context, req *connect.Request[v2.GetUserByEmailRequest]) (*connect.Response[v2.GetUserByEmailResponse], error) {
+	return c.getUserByEmail.CallUnary(ctx, req)
+}
+
+// ListUsers calls user.v2.UserService.ListUsers.
+func (c *userServiceClient) ListUsers(ctx context.Context, req *connect.Request[v2.ListUsersRequest]) (*connect.Response[v2.ListUsersResponse], error) {
+	return c.listUsers.CallUnary(ctx, req)
+}
+
+// UpdateUser calls user.v2.UserService.UpdateUser.
+func (c *userServiceClient) UpdateUser(ctx context.Context, req *connect.Request[v2.UpdateUserRequest]) (*connect.Response[v2.UpdateUserResponse], error) {
+	return c.updateUser.CallUnary(ctx, req)
+}
+
+// DeleteUser calls user.v2.UserService.DeleteUser.
+func (c *userServiceClient) DeleteUser(ctx context.Context, req *connect.Request[v2.DeleteUserRequest]) (*connect.Response[v2.DeleteUserResponse], error) {
+	return c.deleteUser.CallUnary(ctx, req)
+}
+
+// UserServiceHandler is an implementation of the user.v2.UserService service.
+type UserServiceHandler interface {
+	// Creates a user. The password is hashed before being stored.
+	CreateUser(context.Context, *connect.Request[v2.CreateUserRequest]) (*connect.Response[v2.CreateUserResponse], error)
+	// Returns a user by its identifier.
+	GetUser(context.Context, *connect.Request[v2.GetUserRequest]) (*connect.Response[v2.GetUserResponse], error)
+	// Returns a user by its email address.
+	GetUserByEmail(context.Context, *connect.Request[v2.GetUserByEmailRequest]) (*connect.Response[v2.GetUserByEmailResponse], error)
+	// Lists users, most recent first, with offset pagination.
+	ListUsers(context.Context, *connect.Request[v2.ListUsersRequest]) (*connect.Response[v2.ListUsersResponse], error)
+	// Updates the fields listed in the update mask, the other ones are left untouched.
+	UpdateUser(context.Context, *connect.Request[v2.UpdateUserRequest]) (*connect.Response[v2.UpdateUserResponse], error)
+	// Soft-deletes a user.
+	DeleteUser(context.Context, *connect.Request[v2.DeleteUserRequest]) (*connect.Response[v2.DeleteUserResponse], error)
+}
+
+// NewUserServiceHandler builds an HTTP handler from the service implementation. It returns the path
+// on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewUserServiceHandler(svc UserServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	userServiceMethods := v2.File_user_v2_user_proto.Services().ByName("UserService").Methods()
+	userServiceCreateUserHandler := connect.NewUnaryHandler(
+		UserServiceCreateUserProcedure,
+		svc.CreateUser,
+		connect.WithSchema(userServiceMethods.ByName("CreateUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	userServiceGetUserHandler := connect.NewUnaryHandler(
+		UserServiceGetUserProcedure,
+		svc.GetUser,
+		connect.WithSchema(userServiceMethods.ByName("GetUser")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	userServiceGetUserByEmailHandler := connect.NewUnaryHandler(
+		UserServiceGetUserByEmailProcedure,
+		svc.GetUserByEmail,
+		connect.WithSchema(userServiceMethods.ByName("GetUserByEmail")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	userServiceListUsersHandler := connect.NewUnaryHandler(
+		UserServiceListUsersProcedure,
+		svc.ListUsers,
+		connect.WithSchema(userServiceMethods.ByName("ListUsers")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	userServiceUpdateUserHandler := connect.NewUnaryHandler(
+		UserServiceUpdateUserProcedure,
+		svc.UpdateUser,
+		connect.WithSchema(userServiceMethods.ByName("UpdateUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	userServiceDeleteUserHandler := connect.NewUnaryHandler(
+		UserServiceDeleteUserProcedure,
+		svc.DeleteUser,
+		connect.WithSchema(userServiceMethods.ByName("DeleteUser")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/user.v2.UserService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case UserServiceCreateUserProcedure:
+			userServiceCreateUserHandler.ServeHTTP(w, r)
+		case UserServiceGetUserProcedure:
+			userServiceGetUserHandler.ServeHTTP(w, r)
+		case UserServiceGetUserByEmailProcedure:
+			userServiceGetUserByEmailHandler.ServeHTTP(w, r)
+		case UserServiceListUsersProcedure:
+			userServiceListUsersHandler.ServeHTTP(w, r)
+		case UserServiceUpdateUserProcedure:
+			userServiceUpdateUserHandler.ServeHTTP(w, r)
+		case UserServiceDeleteUserProcedure:
+			userServiceDeleteUserHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedUserServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedUserServiceHandler struct{}
+
+func (UnimplementedUserServiceHandler) CreateUser(context.Context, *connect.Request[v2.CreateUserRequest]) (*connect.Response[v2.CreateUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.CreateUser is not implemented"))
+}
+
+func (UnimplementedUserServiceHandler) GetUser(context.Context, *connect.Request[v2.GetUserRequest]) (*connect.Response[v2.GetUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.GetUser is not implemented"))
+}
+
+func (UnimplementedUserServiceHandler) GetUserByEmail(context.Context, *connect.Request[v2.GetUserByEmailRequest]) (*connect.Response[v2.GetUserByEmailResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.GetUserByEmail is not implemented"))
+}
+
+func (UnimplementedUserServiceHandler) ListUsers(context.Context, *connect.Request[v2.ListUsersRequest]) (*connect.Response[v2.ListUsersResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.ListUsers is not implemented"))
+}
+
+func (UnimplementedUserServiceHandler) UpdateUser(context.Context, *connect.Request[v2.UpdateUserRequest]) (*connect.Response[v2.UpdateUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.UpdateUser is not implemented"))
+}
+
+func (UnimplementedUserServiceHandler) DeleteUser(context.Context, *connect.Request[v2.DeleteUserRequest]) (*connect.Response[v2.DeleteUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("user.v2.UserService.DeleteUser is not implemented"))
+}
@@ -0,0 +1,52 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/graphql/model"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+)
+
+func entityToModel(user *entity.User) *model.User {
+	if user == nil {
+		return nil
+	}
+
+	m := &model.User{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      roleToModel(user.Role),
+		CreatedAt: user.CreatedAt,
+	}
+
+	if user.FirstName.IsSet() && !user.FirstName.IsNull() {
+		val := user.FirstName.MustGet()
+		m.FirstName = &val
+	}
+
+	if user.LastName.IsSet() && !user.LastName.IsNull() {
+		val := user.LastName.MustGet()
+		m.LastName = &val
+	}
+
+	if user.UpdatedAt.IsSet() && !user.UpdatedAt.IsNull() {
+		val := user.UpdatedAt.MustGet()
+		m.UpdatedAt = &val
+	}
+
+	return m
+}
+
+func entitiesToModel(users []*entity.User) []*model.User {
+	out := make([]*model.User, len(users))
+	for i, user := range users {
+		out[i] = entityToModel(user)
+	}
+
+	return out
+}
+
+// GraphQL enum values are the upper-cased entity roles.
+func roleToModel(role entity.Role) model.Role { return model.Role(strings.ToUpper(role.String())) }
+
+func roleFromModel(role model.Role) entity.Role { return entity.Role(strings.ToLower(string(role))) }
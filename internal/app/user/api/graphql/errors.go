@@ -0,0 +1,41 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+// resolverError classifies a service error with the apperr codes of the user API.
+func resolverError(err error, fallback apperr.Definition) error {
+	return handler.ToAppError(err, fallback)
+}
+
+// presentError exposes apperr errors with their code and HTTP status in the
+// extensions field. Private errors keep their message server-side.
+// Other errors (parsing, validation, input coercion) are presented as is.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var ae *apperr.AppError
+	if !errors.As(err, &ae) {
+		return gqlErr
+	}
+
+	gqlErr.Message = ae.Message
+	if ae.IsPrivate() {
+		gqlErr.Message = "internal error"
+	}
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]any{}
+	}
+	gqlErr.Extensions["code"] = ae.Code
+	gqlErr.Extensions["httpStatus"] = ae.HTTPStatus
+
+	return gqlErr
+}
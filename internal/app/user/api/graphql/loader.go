@@ -0,0 +1,144 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+const (
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// userLoader batches the user lookups of one request into GetUsersByIDs calls,
+// so that resolving N users costs one query instead of N. Results are cached
+// for the lifetime of the request.
+type userLoader struct {
+	ctx   context.Context //nolint:containedctx // the loader lives as long as the request
+	fetch func(ctx context.Context, ids []int64) ([]*entity.User, error)
+
+	mu      sync.Mutex
+	results map[int64]*loadResult
+	pending []int64
+	timer   *time.Timer
+}
+
+type loadResult struct {
+	done chan struct{}
+	user *entity.User
+	err  error
+}
+
+func newUserLoader(ctx context.Context, svc *service.UserService) *userLoader {
+	return &userLoader{
+		ctx:     ctx,
+		fetch:   svc.GetUsersByIDs,
+		results: map[int64]*loadResult{},
+	}
+}
+
+type loaderKey struct{}
+
+// withLoader gives every request its own userLoader.
+func withLoader(svc *service.UserService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loaderKey{}, newUserLoader(r.Context(), svc))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func loaderFrom(ctx context.Context) *userLoader {
+	l, _ := ctx.Value(loaderKey{}).(*userLoader)
+
+	return l
+}
+
+// Load returns the user with the given id, or nil when it does not exist.
+func (l *userLoader) Load(ctx context.Context, id int64) (*entity.User, error) {
+	l.mu.Lock()
+	res, ok := l.results[id]
+	if !ok {
+		res = &loadResult{done: make(chan struct{})}
+		l.results[id] = res
+		l.pending = append(l.pending, id)
+
+		switch {
+		case len(l.pending) >= loaderMaxBatch:
+			l.dispatchLocked()
+		case l.timer == nil:
+			l.timer = time.AfterFunc(loaderWait, l.dispatch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.user, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err() //nolint:wrapcheck // context errors are returned as is
+	}
+}
+
+// LoadMany returns the users with the given ids, in the same order, with nil
+// for the unknown ones.
+func (l *userLoader) LoadMany(ctx context.Context, ids []int64) ([]*entity.User, error) {
+	users := make([]*entity.User, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Go(func() { users[i], errs[i] = l.Load(ctx, id) })
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+func (l *userLoader) dispatch() {
+	l.mu.Lock()
+	l.dispatchLocked()
+	l.mu.Unlock()
+}
+
+// dispatchLocked fetches the pending ids in the background. l.mu must be held.
+func (l *userLoader) dispatchLocked() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if len(l.pending) == 0 {
+		return
+	}
+
+	ids := l.pending
+	l.pending = nil
+	batch := make(map[int64]*loadResult, len(ids))
+	for _, id := range ids {
+		batch[id] = l.results[id]
+	}
+
+	go func() {
+		users, err := l.fetch(l.ctx, ids)
+
+		byID := make(map[int64]*entity.User, len(users))
+		for _, u := range users {
+			byID[u.ID] = u
+		}
+
+		for id, res := range batch {
+			res.user, res.err = byID[id], err
+			close(res.done)
+		}
+	}()
+}
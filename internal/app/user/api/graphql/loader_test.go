@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+)
+
+type fakeFetcher struct {
+	mu    sync.Mutex
+	calls [][]int64
+	err   error
+}
+
+func (f *fakeFetcher) fetch(_ context.Context, ids []int64) ([]*entity.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, ids)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var users []*entity.User
+	for _, id := range ids {
+		if id > 0 {
+			users = append(users, &entity.User{ID: id})
+		}
+	}
+
+	return users, nil
+}
+
+func newTestLoader(f *fakeFetcher) *userLoader {
+	return &userLoader{ctx: context.Background(), fetch: f.fetch, results: map[int64]*loadResult{}}
+}
+
+func TestUserLoader_BatchesConcurrentLoads(t *testing.T) {
+	f := &fakeFetcher{}
+	l := newTestLoader(f)
+
+	users, err := l.LoadMany(context.Background(), []int64{1, 2, -3, 2})
+	require.NoError(t, err)
+
+	require.Len(t, users, 4)
+	assert.Equal(t, int64(1), users[0].ID)
+	assert.Equal(t, int64(2), users[1].ID)
+	assert.Nil(t, users[2])
+	assert.Equal(t, int64(2), users[3].ID)
+
+	require.Len(t, f.calls, 1)
+	assert.ElementsMatch(t, []int64{1, 2, -3}, f.calls[0])
+}
+
+func TestUserLoader_CachesResults(t *testing.T) {
+	f := &fakeFetcher{}
+	l := newTestLoader(f)
+
+	_, err := l.Load(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = l.Load(context.Background(), 1)
+	require.NoError(t, err)
+
+	assert.Len(t, f.calls, 1)
+}
+
+func TestUserLoader_SplitsLargeBatches(t *testing.T) {
+	f := &fakeFetcher{}
+	l := newTestLoader(f)
+
+	ids := make([]int64, loaderMaxBatch+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	_, err := l.LoadMany(context.Background(), ids)
+	require.NoError(t, err)
+
+	assert.Len(t, f.calls, 2)
+}
+
+func TestUserLoader_Error(t *testing.T) {
+	f := &fakeFetcher{err: errors.New("database error")}
+	l := newTestLoader(f)
+
+	_, err := l.Load(context.Background(), 1)
+	assert.EqualError(t, err, "database error")
+}
@@ -0,0 +1,120 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type CreateUserInput struct {
+	Email string `json:"email"`
+	// Clear text password, at least 8 characters.
+	Password  string  `json:"password"`
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Role      Role    `json:"role"`
+}
+
+type Mutation struct {
+}
+
+type Query struct {
+}
+
+// Fields to update, omitted fields are left untouched.
+type UpdateUserInput struct {
+	Email     *string `json:"email,omitempty"`
+	Password  *string `json:"password,omitempty"`
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Role      *Role   `json:"role,omitempty"`
+}
+
+// A user of the application.
+type User struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	FirstName *string    `json:"firstName,omitempty"`
+	LastName  *string    `json:"lastName,omitempty"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Narrows a user search. Omitted fields do not filter.
+type UserFilter struct {
+	Role *Role `json:"role,omitempty"`
+	// Case-insensitive substring of the email.
+	Email *string `json:"email,omitempty"`
+	// Case-insensitive substring of the first or last name.
+	Name *string `json:"name,omitempty"`
+}
+
+// A page of users.
+type UserPage struct {
+	Items []*User `json:"items"`
+	// Total number of users matching the filter, regardless of pagination.
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// Role of a user.
+type Role string
+
+const (
+	RoleUser  Role = "USER"
+	RoleAdmin Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,31 @@
+package graphql
+
+//go:generate go tool gqlgen generate
+
+import (
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/graphql/model"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+// Resolver is the root resolver. Resolvers call the service directly.
+type Resolver struct {
+	service *service.UserService
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pagination returns an empty page with sane offset and limit.
+func pagination(offset, limit *int) *model.UserPage {
+	page := &model.UserPage{Items: []*model.User{}, Limit: defaultPageSize}
+	if offset != nil && *offset > 0 {
+		page.Offset = *offset
+	}
+	if limit != nil && *limit > 0 {
+		page.Limit = min(*limit, maxPageSize)
+	}
+
+	return page
+}
@@ -0,0 +1,144 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/graphql/model"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+)
+
+// CreateUser is the resolver for the createUser field.
+func (r *mutationResolver) CreateUser(ctx context.Context, input model.CreateUserInput) (*model.User, error) {
+	user := entity.NewUser(input.Email, input.Password, roleFromModel(input.Role))
+	if input.FirstName != nil {
+		user.SetFirstName(*input.FirstName)
+	}
+	if input.LastName != nil {
+		user.SetLastName(*input.LastName)
+	}
+
+	created, err := r.service.CreateUser(ctx, user)
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeCreateRejected)
+	}
+
+	return entityToModel(created), nil
+}
+
+// UpdateUser is the resolver for the updateUser field.
+func (r *mutationResolver) UpdateUser(ctx context.Context, id int64, input model.UpdateUserInput) (*model.User, error) {
+	user := &entity.User{ID: id}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	if input.Password != nil {
+		user.Password = *input.Password
+	}
+	if input.FirstName != nil {
+		user.SetFirstName(*input.FirstName)
+	}
+	if input.LastName != nil {
+		user.SetLastName(*input.LastName)
+	}
+	if input.Role != nil {
+		user.Role = roleFromModel(*input.Role)
+	}
+
+	updated, err := r.service.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	return entityToModel(updated), nil
+}
+
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id int64) (bool, error) {
+	if err := r.service.DeleteUser(ctx, id); err != nil {
+		return false, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	return true, nil
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id int64) (*model.User, error) {
+	user, err := loaderFrom(ctx).Load(ctx, id)
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	return entityToModel(user), nil
+}
+
+// UserByEmail is the resolver for the userByEmail field.
+func (r *queryResolver) UserByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := r.service.GetUserByEmail(ctx, email)
+	if errors.Is(err, ports.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	return entityToModel(user), nil
+}
+
+// UsersByIds is the resolver for the usersByIds field.
+func (r *queryResolver) UsersByIds(ctx context.Context, ids []int64) ([]*model.User, error) {
+	if len(ids) > maxPageSize {
+		return nil, handler.ErrCodeInvalidArgument.New("at most 100 ids can be requested at once")
+	}
+
+	users, err := loaderFrom(ctx).LoadMany(ctx, ids)
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	return entitiesToModel(users), nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, filter *model.UserFilter, offset *int, limit *int) (*model.UserPage, error) {
+	page := pagination(offset, limit)
+
+	var f ports.UserFilter
+	if filter != nil {
+		if filter.Role != nil {
+			f.Role = roleFromModel(*filter.Role)
+		}
+		if filter.Email != nil {
+			f.Email = *filter.Email
+		}
+		if filter.Name != nil {
+			f.Name = *filter.Name
+		}
+	}
+
+	users, total, err := r.service.SearchUsers(ctx, f, page.Offset, page.Limit)
+	if err != nil {
+		return nil, resolverError(err, handler.ErrCodeInternal)
+	}
+
+	page.Items = entitiesToModel(users)
+	page.Total = int(total)
+
+	return page, nil
+}
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
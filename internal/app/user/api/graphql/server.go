@@ -0,0 +1,37 @@
+package graphql
+
+import (
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+const (
+	Path           = "/graphql"
+	PlaygroundPath = "/graphql/playground"
+
+	complexityLimit = 500
+)
+
+// NewHandler returns the GraphQL endpoint of the user application.
+func NewHandler(svc *service.UserService) http.Handler {
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{service: svc}}))
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(complexityLimit))
+	srv.SetErrorPresenter(presentError)
+
+	return withLoader(svc, srv)
+}
+
+// NewPlaygroundHandler returns the GraphQL playground, querying Path.
+func NewPlaygroundHandler() http.Handler {
+	return playground.Handler("User GraphQL playground", Path)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// Error codes returned by the user API, published in the OpenAPI document.
+var (
+	ErrCodeInvalidArgument = apperr.Register(apperr.Definition{
+		Code:        "user.invalid_argument",
+		HTTPStatus:  http.StatusBadRequest,
+		Description: "The request is invalid: malformed email, password too short, unknown role...",
+	})
+	ErrCodeNotFound = apperr.Register(apperr.Definition{
+		Code:        "user.not_found",
+		HTTPStatus:  http.StatusNotFound,
+		Description: "No user matches the given id or email, or the user was deleted.",
+	})
+	ErrCodeCreateRejected = apperr.Register(apperr.Definition{
+		Code:        "user.create_rejected",
+		HTTPStatus:  http.StatusBadRequest,
+		Description: "The user could not be created, typically because the email is already taken.",
+	})
+	ErrCodeInternal = apperr.Register(apperr.Definition{
+		Code:        "user.internal",
+		HTTPStatus:  http.StatusInternalServerError,
+		Description: "Unexpected server error. The details are logged, never returned.",
+	})
+)
+
+var validationErrors = []error{
+	entity.ErrEmailRequired,
+	entity.ErrEmailInvalid,
+	entity.ErrPasswordRequired,
+	entity.ErrPasswordTooShort,
+	entity.ErrPasswordHash,
+	entity.ErrRoleInvalid,
+}
+
+// ToAppError classifies a service error with the codes of this package.
+// fallback is used for errors that are neither validation nor not found errors.
+// It is shared by every transport of the user API.
+func ToAppError(err error, fallback apperr.Definition) *apperr.AppError {
+	if ae := apperr.As(err); ae != nil {
+		return ae
+	}
+
+	var ae *apperr.AppError
+	switch {
+	case errors.Is(err, ports.ErrUserNotFound):
+		ae = ErrCodeNotFound.New(err.Error())
+	case isValidationError(err):
+		ae = ErrCodeInvalidArgument.New(err.Error())
+	case fallback.Code == ErrCodeInternal.Code:
+		return ErrCodeInternal.Wrap(err)
+	default:
+		ae = fallback.New(err.Error())
+	}
+	ae.Cause = err
+
+	return ae
+}
+
+// toConnectError is ToAppError followed by connectx.ToConnectError.
+func toConnectError(err error, fallback apperr.Definition) error {
+	return connectx.ToConnectError(ToAppError(err, fallback))
+}
+
+func isValidationError(err error) bool {
+	for _, target := range validationErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/pivaldi/presence"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userv1 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1"
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+)
+
+// userView is the part of an entity.User exposed by the API, shared by every
+// version so that they only differ by their encoding.
+type userView struct {
+	ID        int64
+	Email     string
+	FirstName *string
+	LastName  *string
+	Role      entity.Role
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+func viewOf(user *entity.User) userView {
+	return userView{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: valueOf(user.FirstName),
+		LastName:  valueOf(user.LastName),
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: valueOf(user.UpdatedAt),
+	}
+}
+
+// valueOf returns the value of v, or nil when it is unset or null.
+func valueOf[T any](v presence.Of[T]) *T {
+	if !v.IsSet() || v.IsNull() {
+		return nil
+	}
+	val := v.MustGet()
+
+	return &val
+}
+
+// newUser returns the user to create from the fields of a create request.
+func newUser(email, password string, role entity.Role, firstName, lastName *string) *entity.User {
+	user := entity.NewUser(email, password, role)
+	if firstName != nil {
+		user.SetFirstName(*firstName)
+	}
+	if lastName != nil {
+		user.SetLastName(*lastName)
+	}
+
+	return user
+}
+
+func userToV1(user *entity.User) *userv1.User {
+	v := viewOf(user)
+	u := &userv1.User{
+		Id:        v.ID,
+		Email:     v.Email,
+		FirstName: v.FirstName,
+		LastName:  v.LastName,
+		Role:      v.Role.String(),
+		CreatedAt: v.CreatedAt.Format(time.RFC3339),
+	}
+	if v.UpdatedAt != nil {
+		formatted := v.UpdatedAt.Format(time.RFC3339)
+		u.UpdatedAt = &formatted
+	}
+
+	return u
+}
+
+func userToV2(user *entity.User) *userv2.User {
+	v := viewOf(user)
+	u := &userv2.User{
+		Id:         v.ID,
+		Email:      v.Email,
+		FirstName:  v.FirstName,
+		LastName:   v.LastName,
+		Role:       roleToV2(v.Role),
+		CreateTime: timestamppb.New(v.CreatedAt),
+	}
+	if v.UpdatedAt != nil {
+		u.UpdateTime = timestamppb.New(*v.UpdatedAt)
+	}
+
+	return u
+}
+
+func roleToV2(role entity.Role) userv2.Role {
+	switch role {
+	case entity.RoleAdmin:
+		return userv2.Role_ROLE_ADMIN
+	case entity.RoleUser:
+		return userv2.Role_ROLE_USER
+	default:
+		return userv2.Role_ROLE_UNSPECIFIED
+	}
+}
+
+func roleFromV2(role userv2.Role) (entity.Role, error) {
+	switch role {
+	case userv2.Role_ROLE_ADMIN:
+		return entity.RoleAdmin, nil
+	case userv2.Role_ROLE_USER:
+		return entity.RoleUser, nil
+	default:
+		return "", entity.ErrRoleInvalid
+	}
+}
@@ -2,14 +2,12 @@ package handler
 
 import (
 	"context"
-	"errors"
 
 	"connectrpc.com/connect"
 
 	userv1 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1/userv1connect"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
-	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
 )
 
@@ -29,25 +27,18 @@ func (h *UserHandler) CreateUser(
 ) (*connect.Response[userv1.CreateUserResponse], error) {
 	role, err := entity.ParseRole(req.Msg.Role)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		return nil, toConnectError(err, ErrCodeInvalidArgument)
 	}
 
-	user := entity.NewUser(req.Msg.Email, req.Msg.Password, role)
-
-	if req.Msg.FirstName != nil {
-		user.SetFirstName(*req.Msg.FirstName)
-	}
-	if req.Msg.LastName != nil {
-		user.SetLastName(*req.Msg.LastName)
-	}
+	user := newUser(req.Msg.Email, req.Msg.Password, role, req.Msg.FirstName, req.Msg.LastName)
 
 	created, err := h.service.CreateUser(ctx, user)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		return nil, toConnectError(err, ErrCodeCreateRejected)
 	}
 
 	return connect.NewResponse(&userv1.CreateUserResponse{
-		User: h.entityToProto(created),
+		User: userToV1(created),
 	}), nil
 }
 
@@ -57,15 +48,11 @@ func (h *UserHandler) GetUser(
 ) (*connect.Response[userv1.GetUserResponse], error) {
 	user, err := h.service.GetUserByID(ctx, req.Msg.Id)
 	if err != nil {
-		if errors.Is(err, ports.ErrUserNotFound) {
-			return nil, connect.NewError(connect.CodeNotFound, err)
-		}
-
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, toConnectError(err, ErrCodeInternal)
 	}
 
 	return connect.NewResponse(&userv1.GetUserResponse{
-		User: h.entityToProto(user),
+		User: userToV1(user),
 	}), nil
 }
 
@@ -75,15 +62,11 @@ func (h *UserHandler) GetUserByEmail(
 ) (*connect.Response[userv1.GetUserByEmailResponse], error) {
 	user, err := h.service.GetUserByEmail(ctx, req.Msg.Email)
 	if err != nil {
-		if errors.Is(err, ports.ErrUserNotFound) {
-			return nil, connect.NewError(connect.CodeNotFound, err)
-		}
-
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, toConnectError(err, ErrCodeInternal)
 	}
 
 	return connect.NewResponse(&userv1.GetUserByEmailResponse{
-		User: h.entityToProto(user),
+		User: userToV1(user),
 	}), nil
 }
 
@@ -93,12 +76,12 @@ func (h *UserHandler) ListUsers(
 ) (*connect.Response[userv1.ListUsersResponse], error) {
 	users, total, err := h.service.ListUsers(ctx, int(req.Msg.Offset), int(req.Msg.Limit))
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, toConnectError(err, ErrCodeInternal)
 	}
 
 	protoUsers := make([]*userv1.User, len(users))
 	for i, user := range users {
-		protoUsers[i] = h.entityToProto(user)
+		protoUsers[i] = userToV1(user)
 	}
 
 	return connect.NewResponse(&userv1.ListUsersResponse{
@@ -128,22 +111,18 @@ func (h *UserHandler) UpdateUser(
 	if req.Msg.Role != nil {
 		role, err := entity.ParseRole(*req.Msg.Role)
 		if err != nil {
-			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+			return nil, toConnectError(err, ErrCodeInvalidArgument)
 		}
 		user.Role = role
 	}
 
 	updated, err := h.service.UpdateUser(ctx, user)
 	if err != nil {
-		if errors.Is(err, ports.ErrUserNotFound) {
-			return nil, connect.NewError(connect.CodeNotFound, err)
-		}
-
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, toConnectError(err, ErrCodeInternal)
 	}
 
 	return connect.NewResponse(&userv1.UpdateUserResponse{
-		User: h.entityToProto(updated),
+		User: userToV1(updated),
 	}), nil
 }
 
@@ -152,39 +131,8 @@ func (h *UserHandler) DeleteUser(
 	req *connect.Request[userv1.DeleteUserRequest],
 ) (*connect.Response[userv1.DeleteUserResponse], error) {
 	if err := h.service.DeleteUser(ctx, req.Msg.Id); err != nil {
-		if errors.Is(err, ports.ErrUserNotFound) {
-			return nil, connect.NewError(connect.CodeNotFound, err)
-		}
-
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, toConnectError(err, ErrCodeInternal)
 	}
 
 	return connect.NewResponse(&userv1.DeleteUserResponse{}), nil
 }
-
-func (h *UserHandler) entityToProto(user *entity.User) *userv1.User {
-	proto := &userv1.User{
-		Id:        user.ID,
-		Email:     user.Email,
-		Role:      user.Role.String(),
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}
-
-	if user.FirstName.IsSet() && !user.FirstName.IsNull() {
-		val := user.FirstName.MustGet()
-		proto.FirstName = &val
-	}
-
-	if user.LastName.IsSet() && !user.LastName.IsNull() {
-		val := user.LastName.MustGet()
-		proto.LastName = &val
-	}
-
-	if user.UpdatedAt.IsSet() && !user.UpdatedAt.IsNull() {
-		val := user.UpdatedAt.MustGet()
-		formatted := val.Format("2006-01-02T15:04:05Z07:00")
-		proto.UpdatedAt = &formatted
-	}
-
-	return proto
-}
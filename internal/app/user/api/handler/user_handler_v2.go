@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/pivaldi/presence"
+
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+// UserHandlerV2 serves the user.v2 API.
+type UserHandlerV2 struct {
+	service *service.UserService
+}
+
+func NewUserHandlerV2(svc *service.UserService) *UserHandlerV2 {
+	return &UserHandlerV2{service: svc}
+}
+
+var _ userv2connect.UserServiceHandler = (*UserHandlerV2)(nil)
+
+func (h *UserHandlerV2) CreateUser(
+	ctx context.Context,
+	req *connect.Request[userv2.CreateUserRequest],
+) (*connect.Response[userv2.CreateUserResponse], error) {
+	role, err := roleFromV2(req.Msg.Role)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInvalidArgument)
+	}
+
+	user := newUser(req.Msg.Email, req.Msg.Password, role, req.Msg.FirstName, req.Msg.LastName)
+
+	created, err := h.service.CreateUser(ctx, user)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeCreateRejected)
+	}
+
+	return connect.NewResponse(&userv2.CreateUserResponse{User: userToV2(created)}), nil
+}
+
+func (h *UserHandlerV2) GetUser(
+	ctx context.Context,
+	req *connect.Request[userv2.GetUserRequest],
+) (*connect.Response[userv2.GetUserResponse], error) {
+	user, err := h.service.GetUserByID(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	return connect.NewResponse(&userv2.GetUserResponse{User: userToV2(user)}), nil
+}
+
+func (h *UserHandlerV2) GetUserByEmail(
+	ctx context.Context,
+	req *connect.Request[userv2.GetUserByEmailRequest],
+) (*connect.Response[userv2.GetUserByEmailResponse], error) {
+	user, err := h.service.GetUserByEmail(ctx, req.Msg.Email)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	return connect.NewResponse(&userv2.GetUserByEmailResponse{User: userToV2(user)}), nil
+}
+
+func (h *UserHandlerV2) ListUsers(
+	ctx context.Context,
+	req *connect.Request[userv2.ListUsersRequest],
+) (*connect.Response[userv2.ListUsersResponse], error) {
+	users, total, err := h.service.ListUsers(ctx, int(req.Msg.Offset), int(req.Msg.Limit))
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	protoUsers := make([]*userv2.User, len(users))
+	for i, user := range users {
+		protoUsers[i] = userToV2(user)
+	}
+
+	return connect.NewResponse(&userv2.ListUsersResponse{Users: protoUsers, Total: total}), nil
+}
+
+// UpdateUser applies the fields of the update mask to the current user, so
+// that the fields outside the mask are left untouched.
+func (h *UserHandlerV2) UpdateUser(
+	ctx context.Context,
+	req *connect.Request[userv2.UpdateUserRequest],
+) (*connect.Response[userv2.UpdateUserResponse], error) {
+	user, err := h.service.GetUserByID(ctx, req.Msg.Id)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	// The stored hash is kept unless the password is in the mask.
+	user.Password = ""
+
+	for _, path := range req.Msg.GetUpdateMask().GetPaths() {
+		switch path {
+		case "email":
+			user.Email = req.Msg.Email
+		case "password":
+			if req.Msg.Password == "" {
+				return nil, toConnectError(entity.ErrPasswordRequired, ErrCodeInvalidArgument)
+			}
+			user.Password = req.Msg.Password
+		case "first_name":
+			user.FirstName = optionalName(req.Msg.FirstName)
+		case "last_name":
+			user.LastName = optionalName(req.Msg.LastName)
+		case "role":
+			if user.Role, err = roleFromV2(req.Msg.Role); err != nil {
+				return nil, toConnectError(err, ErrCodeInvalidArgument)
+			}
+		default:
+			return nil, toConnectError(fmt.Errorf("unknown update_mask path %q", path), ErrCodeInvalidArgument)
+		}
+	}
+
+	if user.Email == "" {
+		return nil, toConnectError(entity.ErrEmailRequired, ErrCodeInvalidArgument)
+	}
+
+	updated, err := h.service.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	return connect.NewResponse(&userv2.UpdateUserResponse{User: userToV2(updated)}), nil
+}
+
+func (h *UserHandlerV2) DeleteUser(
+	ctx context.Context,
+	req *connect.Request[userv2.DeleteUserRequest],
+) (*connect.Response[userv2.DeleteUserResponse], error) {
+	if err := h.service.DeleteUser(ctx, req.Msg.Id); err != nil {
+		return nil, toConnectError(err, ErrCodeInternal)
+	}
+
+	return connect.NewResponse(&userv2.DeleteUserResponse{}), nil
+}
+
+// optionalName returns a null name for the empty string, which clears it.
+func optionalName(name string) presence.Of[string] {
+	if name == "" {
+		return presence.Null[string]()
+	}
+
+	return presence.FromValue(name)
+}
@@ -0,0 +1,8 @@
+// Package openapi embeds the OpenAPI document generated from user.proto by
+// protoc-gen-openapi (see buf.gen.yaml).
+package openapi
+
+import _ "embed"
+
+//go:embed openapi.yaml
+var Spec []byte
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"connectrpc.com/connect"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// Middleware wraps the HTTP handler of the server.
+type Middleware func(http.Handler) http.Handler
+
+// WithInterceptors adds Connect interceptors after the default chain built
+// from the [platform.server.interceptors] config. The first one is the outermost.
+func WithInterceptors(interceptors ...connect.Interceptor) Option {
+	return func(s *Server) {
+		s.interceptors = append(s.interceptors, interceptors...)
+	}
+}
+
+// WithMiddleware wraps every route of the server, Connect, REST, GraphQL and
+// docs alike, with plain HTTP middlewares. The first one is the outermost.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, middlewares...)
+	}
+}
+
+// WithHandlerOptions adds options to every Connect handler of the server.
+func WithHandlerOptions(opts ...connect.HandlerOption) Option {
+	return func(s *Server) {
+		s.handlerOptions = append(s.handlerOptions, opts...)
+	}
+}
+
+// WithIdempotencyStore sets the store of the Idempotency-Key header, required
+// when [platform.server.idempotency] is enabled.
+func WithIdempotencyStore(store connectx.IdempotencyStore) Option {
+	return func(s *Server) {
+		s.idempotencyStore = store
+	}
+}
+
+// WithRateLimitStore sets the store of the rate limit buckets, required when
+// [platform.server.rate-limit] uses the postgres store.
+func WithRateLimitStore(store connectx.RateLimitStore) Option {
+	return func(s *Server) {
+		s.rateLimitStore = store
+	}
+}
+
+// WithReadinessCheck adds a check to the /readyz probe, along with the
+// database ping and the shutdown state.
+func WithReadinessCheck(name string, check connectx.CheckFunc) Option {
+	return func(s *Server) {
+		s.probes.Register(name, check)
+	}
+}
+
+// WithMetrics serves the metrics of reg, shared with the other components of
+// the application, when [platform.metrics] is enabled. Without it, the server
+// has a registry of its own.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Server) {
+		s.metrics = reg
+	}
+}
@@ -1,62 +1,535 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"connectrpc.com/grpcreflect"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
+	userv1 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1/userv1connect"
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/graphql"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/openapi"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/apidocs"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
 )
 
-const defaultTimeout = 30 * time.Second
+const (
+	defaultTimeout = 30 * time.Second
+	// deadlineGrace is left to the calls past their maximum deadline to send their error.
+	deadlineGrace = 5 * time.Second
+)
+
+// v1Deprecation is announced on every user.v1 response, clients should move to user.v2.
+// Set Sunset once the removal date of v1 is decided.
+var v1Deprecation = connectx.Deprecation{
+	Since:     time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+	Successor: "/v2/users",
+}
 
 type Server struct {
-	port        int
+	cfg         config.ServerConfig
+	appEnv      config.AppEnv
 	userService *service.UserService
 	logger      logging.Logger
+	health      *connectx.HealthChecker
+	probes      *connectx.Probes
+	metricsCfg  config.MetricsConfig
+	metrics     *metrics.Registry
+	// adminMetrics serves the metrics on the admin listener rather than the public port.
+	adminMetrics bool
+	tracing      bool
+
+	interceptors     []connect.Interceptor
+	middlewares      []Middleware
+	handlerOptions   []connect.HandlerOption
+	idempotencyStore connectx.IdempotencyStore
+	rateLimitStore   connectx.RateLimitStore
+
+	// rateLimiter and cors are built by Handler, when enabled, and reloaded by Reload.
+	rateLimiter *connectx.RateLimitInterceptor
+	cors        *connectx.CORSMiddleware
+
+	drainer *connectx.Drainer
+	// baseCtx is the parent context of the requests, canceled when they outlive the drain timeout.
+	baseCtx       context.Context //nolint:containedctx // lives as long as the server
+	cancelBaseCtx context.CancelFunc
+
+	mu         sync.Mutex
+	httpServer *http.Server
+	shutdown   bool
 }
 
-func NewServer(port int, userService *service.UserService, logger logging.Logger) *Server {
-	return &Server{
-		port:        port,
-		userService: userService,
-		logger:      logger,
+var errShuttingDown = errors.New("the server is shutting down")
+
+// NewServer returns the API server. db drives the gRPC health status and the
+// readiness probe.
+func NewServer(
+	cfg config.Platform,
+	userService *service.UserService,
+	db connectx.Pinger,
+	logger logging.Logger,
+	opts ...Option,
+) *Server {
+	s := &Server{
+		cfg:          cfg.Server,
+		appEnv:       cfg.AppEnv,
+		metricsCfg:   cfg.Metrics,
+		adminMetrics: cfg.Admin.Addr != "",
+		tracing:      cfg.Tracing.Enabled,
+		userService:  userService,
+		logger:       logger,
+		health:       connectx.NewHealthChecker(db, userv1connect.UserServiceName, userv2connect.UserServiceName),
+		probes:       connectx.NewProbes(cfg.Server.Health),
+		drainer:      connectx.NewDrainer(),
+	}
+	if db != nil {
+		s.probes.Register("database", db.PingContext)
+	}
+	s.probes.Register("shutdown", func(context.Context) error {
+		if s.health.ShuttingDown() {
+			return errShuttingDown
+		}
+
+		return nil
+	})
+	s.baseCtx, s.cancelBaseCtx = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	switch {
+	case !cfg.Metrics.Enabled:
+		s.metrics = nil
+	case s.metrics == nil:
+		s.metrics = metrics.NewRegistry(cfg.Metrics)
 	}
+
+	return s
 }
 
-func (s *Server) Start() error {
+// Handler returns the HTTP handler serving every route of the server.
+func (s *Server) Handler() (http.Handler, error) {
+	interceptors, err := connectx.NewInterceptors(s.cfg, s.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build interceptors: %w", err)
+	}
+	if s.cfg.RateLimit.Enabled {
+		rateLimiter, err := s.rateLimitInterceptor()
+		if err != nil {
+			return nil, err
+		}
+		interceptors = append(interceptors, rateLimiter)
+		s.mu.Lock()
+		s.rateLimiter = rateLimiter
+		s.mu.Unlock()
+	}
+	if s.cfg.Idempotency.Enabled {
+		if s.idempotencyStore == nil {
+			return nil, errors.New("idempotency is enabled without store, see WithIdempotencyStore")
+		}
+		if idem := s.cfg.Idempotency; idem.Lease <= 0 || idem.Lease > idem.TTL {
+			return nil, fmt.Errorf("idempotency lease %s must be positive and at most the ttl %s", idem.Lease, idem.TTL)
+		}
+		if longest := s.longestDeadline(); s.cfg.Idempotency.Lease < longest {
+			return nil, fmt.Errorf("idempotency lease %s is shorter than the longest deadline %s, "+
+				"a call could outlive its lease and run twice", s.cfg.Idempotency.Lease, longest)
+		}
+		interceptors = append(interceptors,
+			connectx.NewIdempotencyInterceptor(s.idempotencyStore, s.cfg.Idempotency, s.cfg.RateLimit.ClientIPHeader))
+	}
+	if s.metrics != nil {
+		// Outermost, to also measure the calls rejected by the other interceptors.
+		metricsInterceptor, err := connectx.NewMetricsInterceptor(s.metrics, s.metricsCfg.IgnoredProcedures)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build metrics interceptor: %w", err)
+		}
+		interceptors = append([]connect.Interceptor{metricsInterceptor}, interceptors...)
+	}
+	if s.tracing {
+		// Outermost, so that the logs and the other spans of the call belong to its trace.
+		interceptors = append([]connect.Interceptor{connectx.NewTracingInterceptor()}, interceptors...)
+	}
+	compression, err := connectx.NewCompressionOptions(s.cfg.HTTP.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build compression: %w", err)
+	}
+	handlerOpts := append([]connect.HandlerOption{
+		connect.WithInterceptors(append(interceptors, s.interceptors...)...),
+		connect.WithReadMaxBytes(s.cfg.HTTP.MaxRequestBytes),
+		connect.WithSendMaxBytes(s.cfg.HTTP.MaxResponseBytes),
+	}, append(compression, s.handlerOptions...)...)
+
 	mux := http.NewServeMux()
 
-	userHandler := handler.NewUserHandler(s.userService)
-	path, h := userv1connect.NewUserServiceHandler(userHandler)
-	mux.Handle(path, h)
+	// user.v1 and user.v2 are served side by side, v1 responses carry deprecation headers.
+	// The deprecation interceptor is the outermost one, to also mark replayed and rejected calls.
+	v1Opts := append([]connect.HandlerOption{
+		connect.WithInterceptors(connectx.NewDeprecationInterceptor(v1Deprecation)),
+	}, handlerOpts...)
+	path, v1 := userv1connect.NewUserServiceHandler(handler.NewUserHandler(s.userService), v1Opts...)
+	mux.Handle(path, v1)
+	path, v2 := userv2connect.NewUserServiceHandler(handler.NewUserHandlerV2(s.userService), handlerOpts...)
+	mux.Handle(path, v2)
+
+	// Plain REST routes declared by the google.api.http annotations of user.proto.
+	for _, rest := range []struct {
+		prefix  string
+		next    http.Handler
+		service protoreflect.ServiceDescriptor
+	}{
+		{"/v1/", v1, userv1.File_user_v1_user_proto.Services().ByName("UserService")},
+		{"/v2/", v2, userv2.File_user_v2_user_proto.Services().ByName("UserService")},
+	} {
+		restHandler, err := connectx.NewRESTHandler(rest.next, s.cfg.HTTP.MaxRequestBytes, rest.service)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s REST handler: %w", rest.prefix, err)
+		}
+		mux.Handle(rest.prefix, restHandler)
+	}
+
+	if err := apidocs.Register(mux, openapi.Spec, apperr.Catalog()); err != nil {
+		return nil, fmt.Errorf("failed to build API docs: %w", err)
+	}
+
+	// Liveness and readiness probes, for orchestrators and load balancers.
+	mux.Handle(connectx.LivenessPath, s.probes.LivenessHandler())
+	mux.Handle(connectx.ReadinessPath, s.probes.ReadinessHandler())
+
+	if s.metrics != nil && !s.adminMetrics {
+		mux.Handle(s.metricsCfg.Path, s.metrics.Handler())
+	}
+
+	// gRPC health checking and server reflection, for probes and tools like grpcurl.
+	mux.Handle(grpchealth.NewHandler(s.health, handlerOpts...))
+	reflector := grpcreflect.NewStaticReflector(
+		userv1connect.UserServiceName,
+		userv2connect.UserServiceName,
+		grpchealth.HealthV1ServiceName,
+	)
+	mux.Handle(grpcreflect.NewHandlerV1(reflector, handlerOpts...))
+	mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector, handlerOpts...))
+
+	if s.cfg.GraphQL.Enabled {
+		var graphqlHandler http.Handler = graphql.NewHandler(s.userService)
+		if s.cfg.HTTP.MaxRequestBytes > 0 {
+			graphqlHandler = http.MaxBytesHandler(graphqlHandler, int64(s.cfg.HTTP.MaxRequestBytes))
+		}
+		mux.Handle(graphql.Path, graphqlHandler)
+
+		if s.cfg.GraphQL.Playground && s.appEnv.IsDevelopment() {
+			mux.Handle(graphql.PlaygroundPath, graphql.NewPlaygroundHandler())
+		}
+	}
+
+	root := connectx.NewCompressionMiddleware(s.cfg.HTTP.Compression)(mux)
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		root = s.middlewares[i](root)
+	}
+	// The identity of the client certificate is the principal of the calls, for the middlewares.
+	root = connectx.ClientCertMiddleware(root)
+
+	// CORS is outermost so that the preflight requests skip the other middlewares.
+	if s.cfg.CORS.Enabled {
+		cors, err := connectx.NewCORSMiddleware(s.cfg.CORS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build CORS middleware: %w", err)
+		}
+		root = cors.Handler(root)
+		s.mu.Lock()
+		s.cors = cors
+		s.mu.Unlock()
+	}
+
+	return s.drainer.Middleware(root), nil
+}
+
+// rateLimitInterceptor returns the rate limiter of the configured policies,
+// with the configured store.
+func (s *Server) rateLimitInterceptor() (*connectx.RateLimitInterceptor, error) {
+	var store connectx.RateLimitStore
+	switch s.cfg.RateLimit.Store {
+	case config.RateLimitStoreMemory:
+		store = connectx.NewMemoryRateLimitStore()
+	case config.RateLimitStorePostgres:
+		if s.rateLimitStore == nil {
+			return nil, errors.New("the postgres rate limit store is enabled without store, see WithRateLimitStore")
+		}
+		store = s.rateLimitStore
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", s.cfg.RateLimit.Store)
+	}
+
+	interceptor, err := connectx.NewRateLimitInterceptor(s.cfg.RateLimit, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rate limiter: %w", err)
+	}
+
+	return interceptor, nil
+}
+
+// Reload validates the reloadable settings of cfg, the rate limit policies
+// and the CORS origins, and returns the function applying them to the next
+// requests, see config.Watcher. Enabling or disabling rate limiting or CORS
+// requires a restart.
+func (s *Server) Reload(cfg config.Platform) (func(), error) {
+	s.mu.Lock()
+	rateLimiter, cors := s.rateLimiter, s.cors
+	s.mu.Unlock()
+
+	applies := []func(){}
+	if rateLimiter != nil {
+		apply, err := rateLimiter.Reload(cfg.Server.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit config: %w", err)
+		}
+		applies = append(applies, apply)
+	}
+	if cors != nil {
+		apply, err := cors.Reload(cfg.Server.CORS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS config: %w", err)
+		}
+		applies = append(applies, apply)
+	}
+
+	return func() {
+		for _, apply := range applies {
+			apply()
+		}
+	}, nil
+}
+
+func (s *Server) Start() error {
+	root, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	httpCfg := s.cfg.HTTP
+	httpCfg.WriteTimeout, err = s.writeTimeout()
+	if err != nil {
+		return err
+	}
+
+	var (
+		certs     *connectx.CertReloader
+		tlsConfig *tls.Config
+	)
+	switch {
+	case s.cfg.TLS.Enabled:
+		certs, err = connectx.NewCertReloader(s.cfg.TLS)
+		if err != nil {
+			return fmt.Errorf("invalid TLS config: %w", err)
+		}
+		tlsConfig = certs.TLSConfig()
+	case !s.cleartextAllowed():
+		return fmt.Errorf("cleartext HTTP is for development, unix and systemd sockets only, "+
+			"enable [platform.server.tls] in %s", s.appEnv)
+	}
+
+	httpServer, err := connectx.NewHTTPServer("", root, httpCfg, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("invalid HTTP server config: %w", err)
+	}
+
+	httpServer.BaseContext = func(net.Listener) context.Context { return s.baseCtx }
+
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+
+		return nil
+	}
+	listeners, err := connectx.Listen(s.cfg)
+	if err != nil {
+		s.mu.Unlock()
+
+		return fmt.Errorf("invalid listen config: %w", err)
+	}
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
+	if certs != nil {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go certs.Watch(watchCtx, s.logger)
+	}
+
+	return s.serve(httpServer, listeners, certs != nil)
+}
+
+// serve serves httpServer on every listener until Shutdown, or until one of
+// them fails, which stops the others.
+func (s *Server) serve(httpServer *http.Server, listeners []net.Listener, withTLS bool) error {
+	served := make(chan error, len(listeners))
+	for _, lis := range listeners {
+		if withTLS {
+			s.logger.Info("starting HTTPS server", logging.String("address", connectx.ListenerAddr(lis)),
+				logging.String("client_auth", s.cfg.TLS.ClientAuth))
+		} else {
+			s.logger.Info("starting HTTP server", logging.String("address", connectx.ListenerAddr(lis)))
+		}
+		go func() {
+			if withTLS {
+				served <- httpServer.ServeTLS(lis, "", "")
+			} else {
+				served <- httpServer.Serve(lis)
+			}
+		}()
+	}
+
+	var errs []error
+	for range listeners {
+		if err := <-served; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			if len(errs) == 0 {
+				_ = httpServer.Close()
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("server failed: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// cleartextAllowed reports whether the server may serve cleartext HTTP: in
+// development, or when it only listens on unix and systemd sockets, whose
+// proxy terminates TLS.
+func (s *Server) cleartextAllowed() bool {
+	if s.appEnv.IsDevelopment() {
+		return true
+	}
+	for _, addr := range s.cfg.Listen {
+		parsed, err := connectx.ParseListenAddress(addr)
+		if err != nil || parsed.Scheme == connectx.ListenTCP {
+			return false
+		}
+	}
+
+	return true
+}
+
+// longestDeadline returns the longest deadline, default or maximum, of the
+// calls, zero when the deadlines are not applied.
+func (s *Server) longestDeadline() time.Duration {
+	if !s.cfg.Interceptors.Deadline {
+		return 0
+	}
+	longest := max(s.cfg.Deadlines.Default, s.cfg.Deadlines.Max)
+	for _, o := range s.cfg.Deadlines.Overrides {
+		longest = max(longest, o.Default, o.Max)
+	}
+
+	return longest
+}
+
+// writeTimeout returns the configured write timeout, failing when it is
+// shorter than the longest maximum deadline. When unset, it leaves the calls
+// the time of the longest maximum deadline to respond, defaultTimeout when
+// client deadlines are not capped.
+func (s *Server) writeTimeout() (time.Duration, error) {
+	longest := s.cfg.Deadlines.Max
+	for _, o := range s.cfg.Deadlines.Overrides {
+		longest = max(longest, o.Max)
+	}
+	if !s.cfg.Interceptors.Deadline {
+		longest = 0
+	}
+
+	switch {
+	case s.cfg.HTTP.WriteTimeout > 0 && s.cfg.HTTP.WriteTimeout < longest:
+		return 0, fmt.Errorf("http write-timeout %s is shorter than the longest maximum deadline %s",
+			s.cfg.HTTP.WriteTimeout, longest)
+	case s.cfg.HTTP.WriteTimeout > 0:
+		return s.cfg.HTTP.WriteTimeout, nil
+	case longest == 0:
+		return defaultTimeout, nil
+	default:
+		return max(defaultTimeout, longest+deadlineGrace), nil
+	}
+}
+
+// MetricsHandler serves the metrics on the admin listener, when configured,
+// and not on the public port. It is nil when the metrics are disabled.
+func (s *Server) MetricsHandler() http.Handler {
+	if s.metrics == nil {
+		return nil
+	}
 
-	addr := fmt.Sprintf(":%d", s.port)
-	s.logger.Info("starting HTTP server", logging.String("address", addr))
+	return s.metrics.Handler()
+}
+
+// ReadinessDetailsHandler serves the readiness report with the errors of the
+// checks, which /readyz leaves out, for the admin listener.
+func (s *Server) ReadinessDetailsHandler() http.Handler {
+	return s.probes.ReadinessDetailsHandler()
+}
+
+// Shutdown stops the server gracefully. It reports the server as not serving
+// to health checks and not ready to /readyz, keeps serving for the pre-stop
+// delay, then stops accepting requests and waits for the in-flight ones until
+// the drain timeout, past which they are canceled. Start returns once Shutdown
+// is called, and does not serve when called after it.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.SetShuttingDown()
+
+	s.mu.Lock()
+	s.shutdown = true
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	if httpServer == nil {
+		return nil
+	}
+
+	if delay := s.cfg.Shutdown.PreStopDelay; delay > 0 {
+		s.logger.Info("waiting for the load balancers before draining", logging.Duration("delay", delay))
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
 
-	h2server := &http2.Server{
-		IdleTimeout:      defaultTimeout,
-		WriteByteTimeout: defaultTimeout,
+	if timeout := s.cfg.Shutdown.DrainTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	httpServer := &http.Server{
-		Addr:         addr,
-		Handler:      h2c.NewHandler(mux, h2server),
-		ReadTimeout:  defaultTimeout,
-		WriteTimeout: defaultTimeout,
-		IdleTimeout:  defaultTimeout,
+	s.logger.Info("draining HTTP server", logging.Int("in_flight", s.drainer.InFlight()))
+	err := httpServer.Shutdown(ctx)
+	if err == nil {
+		// Shutdown does not wait for the streams of the h2c connections.
+		err = s.drainer.Wait(ctx)
 	}
+	if err != nil {
+		s.cancelBaseCtx()
+		_ = httpServer.Close()
 
-	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("server failed: %w", err)
+		return fmt.Errorf("failed to drain server: %w", err)
 	}
+	s.cancelBaseCtx()
 
 	return nil
 }
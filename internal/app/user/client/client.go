@@ -0,0 +1,197 @@
+// Package client is the Go SDK of the user service, calling its user.v2 API.
+//
+// Failed calls return the *apperr.AppError sent by the server, whose Code is
+// one of the error codes of the API, e.g. "user.not_found".
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// Client calls the user service of a remote server.
+type Client struct {
+	baseURL       string
+	protocol      Protocol
+	httpClient    *http.Client
+	bearerToken   TokenProvider
+	apiKey        TokenProvider
+	retry         RetryPolicy
+	timeout       time.Duration
+	clientOptions []connect.ClientOption
+
+	users userv2connect.UserServiceClient
+}
+
+// New returns a client of the server at the base URL set by the options.
+// The calls forward the request id of their context, see reqid.With.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{
+		baseURL:  defaultBaseURL,
+		protocol: ProtocolConnect,
+		retry:    DefaultRetryPolicy,
+		timeout:  defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if u, err := url.Parse(c.baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", c.baseURL)
+	}
+
+	// From the outermost: the request id, the trace and the deadline are the
+	// same for every attempt, whose credentials are renewed.
+	interceptors := []connect.Interceptor{
+		connectx.NewRequestIDInterceptor(),
+		connectx.NewTracingInterceptor(),
+		newTimeoutInterceptor(c.timeout),
+		newRetryInterceptor(c.retry),
+	}
+	if c.bearerToken != nil {
+		interceptors = append(interceptors, newCredentialInterceptor(connectx.AuthorizationHeader, "Bearer ", c.bearerToken))
+	}
+	if c.apiKey != nil {
+		interceptors = append(interceptors, newCredentialInterceptor(connectx.APIKeyHeader, "", c.apiKey))
+	}
+	clientOpts := []connect.ClientOption{connect.WithInterceptors(interceptors...)}
+
+	switch c.protocol {
+	case ProtocolConnect:
+	case ProtocolGRPC:
+		clientOpts = append(clientOpts, connect.WithGRPC())
+	case ProtocolGRPCWeb:
+		clientOpts = append(clientOpts, connect.WithGRPCWeb())
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", c.protocol)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = defaultHTTPClient(c.protocol)
+	}
+	c.users = userv2connect.NewUserServiceClient(c.httpClient, c.baseURL, append(clientOpts, c.clientOptions...)...)
+
+	return c, nil
+}
+
+// defaultHTTPClient returns http.DefaultClient, or a client speaking HTTP/2
+// only, in cleartext too, for gRPC.
+func defaultHTTPClient(protocol Protocol) *http.Client {
+	if protocol != ProtocolGRPC {
+		return http.DefaultClient
+	}
+
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	transport, _ := http.DefaultTransport.(*http.Transport)
+	transport = transport.Clone()
+	transport.Protocols = &protocols
+
+	return &http.Client{Transport: transport}
+}
+
+// CreateUser creates user, with its clear text password, and returns it as stored.
+func (c *Client) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	res, err := c.users.CreateUser(ctx, connect.NewRequest(&userv2.CreateUserRequest{
+		Email:     user.Email,
+		Password:  user.Password,
+		FirstName: user.FirstName.Ptr(),
+		LastName:  user.LastName.Ptr(),
+		Role:      roleToV2(user.Role),
+	}))
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return userFromV2(res.Msg.GetUser()), nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id int64) (*entity.User, error) {
+	res, err := c.users.GetUser(ctx, connect.NewRequest(&userv2.GetUserRequest{Id: id}))
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return userFromV2(res.Msg.GetUser()), nil
+}
+
+func (c *Client) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	res, err := c.users.GetUserByEmail(ctx, connect.NewRequest(&userv2.GetUserByEmailRequest{Email: email}))
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return userFromV2(res.Msg.GetUser()), nil
+}
+
+// ListUsers returns a page of users and the total number of users.
+func (c *Client) ListUsers(ctx context.Context, offset, limit int) ([]*entity.User, int64, error) {
+	res, err := c.users.ListUsers(ctx, connect.NewRequest(&userv2.ListUsersRequest{
+		Offset: int32(min(offset, math.MaxInt32)), //nolint:gosec // bounded
+		Limit:  int32(min(limit, math.MaxInt32)),  //nolint:gosec // bounded
+	}))
+	if err != nil {
+		return nil, 0, appError(err)
+	}
+
+	users := make([]*entity.User, len(res.Msg.GetUsers()))
+	for i, u := range res.Msg.GetUsers() {
+		users[i] = userFromV2(u)
+	}
+
+	return users, res.Msg.GetTotal(), nil
+}
+
+// UpdateUser applies the fields set in update to the user id, and returns it as stored.
+func (c *Client) UpdateUser(ctx context.Context, id int64, update UserUpdate) (*entity.User, error) {
+	req := &userv2.UpdateUserRequest{Id: id, UpdateMask: &fieldmaskpb.FieldMask{}}
+	if email, ok := update.Email.Get(); ok {
+		req.Email = email
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "email")
+	}
+	if password, ok := update.Password.Get(); ok {
+		req.Password = password
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "password")
+	}
+	if update.FirstName.IsSet() {
+		req.FirstName = update.FirstName.GetOr("")
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "first_name")
+	}
+	if update.LastName.IsSet() {
+		req.LastName = update.LastName.GetOr("")
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "last_name")
+	}
+	if role, ok := update.Role.Get(); ok {
+		req.Role = roleToV2(role)
+		req.UpdateMask.Paths = append(req.UpdateMask.Paths, "role")
+	}
+
+	res, err := c.users.UpdateUser(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return userFromV2(res.Msg.GetUser()), nil
+}
+
+// DeleteUser soft-deletes the user id.
+func (c *Client) DeleteUser(ctx context.Context, id int64) error {
+	if _, err := c.users.DeleteUser(ctx, connect.NewRequest(&userv2.DeleteUserRequest{Id: id})); err != nil {
+		return appError(err)
+	}
+
+	return nil
+}
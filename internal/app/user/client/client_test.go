@@ -0,0 +1,242 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/pivaldi/presence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/client"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/reqid"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+var createTime = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+// fakeUserService fails the first calls with unavailable and records the headers of every call.
+type fakeUserService struct {
+	userv2connect.UnimplementedUserServiceHandler
+
+	mu          sync.Mutex
+	unavailable int
+	headers     []http.Header
+	update      *userv2.UpdateUserRequest
+}
+
+func (s *fakeUserService) call(header http.Header) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.headers = append(s.headers, header.Clone())
+	if s.unavailable > 0 {
+		s.unavailable--
+
+		return connect.NewError(connect.CodeUnavailable, nil)
+	}
+
+	return nil
+}
+
+func (s *fakeUserService) CreateUser(
+	_ context.Context,
+	req *connect.Request[userv2.CreateUserRequest],
+) (*connect.Response[userv2.CreateUserResponse], error) {
+	if err := s.call(req.Header()); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&userv2.CreateUserResponse{User: &userv2.User{
+		Id:         1,
+		Email:      req.Msg.Email,
+		FirstName:  req.Msg.FirstName,
+		Role:       req.Msg.Role,
+		CreateTime: timestamppb.New(createTime),
+	}}), nil
+}
+
+func (s *fakeUserService) GetUser(
+	_ context.Context,
+	req *connect.Request[userv2.GetUserRequest],
+) (*connect.Response[userv2.GetUserResponse], error) {
+	if err := s.call(req.Header()); err != nil {
+		return nil, err
+	}
+
+	return nil, connectx.ToConnectError(apperr.NotFound("user.not_found", "user not found"))
+}
+
+func (s *fakeUserService) ListUsers(
+	_ context.Context,
+	req *connect.Request[userv2.ListUsersRequest],
+) (*connect.Response[userv2.ListUsersResponse], error) {
+	if err := s.call(req.Header()); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&userv2.ListUsersResponse{
+		Users: []*userv2.User{{Id: 1, Email: "john@example.com", Role: userv2.Role_ROLE_ADMIN}},
+		Total: 12,
+	}), nil
+}
+
+func (s *fakeUserService) UpdateUser(
+	_ context.Context,
+	req *connect.Request[userv2.UpdateUserRequest],
+) (*connect.Response[userv2.UpdateUserResponse], error) {
+	if err := s.call(req.Header()); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.update = req.Msg
+	s.mu.Unlock()
+
+	return connect.NewResponse(&userv2.UpdateUserResponse{User: &userv2.User{
+		Id:         req.Msg.Id,
+		Role:       req.Msg.Role,
+		UpdateTime: timestamppb.New(createTime),
+	}}), nil
+}
+
+func newServer(t *testing.T, svc *fakeUserService) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(userv2connect.NewUserServiceHandler(svc,
+		connect.WithInterceptors(connectx.NewRequestIDInterceptor(), connectx.NewErrorHeaderInterceptor())))
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.Protocols = &http.Protocols{}
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+var fastRetry = client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+func TestClient_CreateUser(t *testing.T) {
+	svc := &fakeUserService{unavailable: 1}
+	srv := newServer(t, svc)
+
+	c, err := client.New(
+		client.WithBaseURL(srv.URL),
+		client.WithBearerToken(client.StaticToken("secret")),
+		client.WithRetry(fastRetry),
+	)
+	require.NoError(t, err)
+
+	user := entity.NewUser("john@example.com", "password123", entity.RoleUser)
+	user.SetFirstName("John")
+	created, err := c.CreateUser(reqid.With(context.Background(), "req-1"), user)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), created.ID)
+	assert.Equal(t, "john@example.com", created.Email)
+	assert.Equal(t, "John", created.FirstName.MustGet())
+	assert.True(t, created.LastName.IsNull())
+	assert.Equal(t, entity.RoleUser, created.Role)
+	assert.Equal(t, createTime, created.CreatedAt)
+	assert.True(t, created.UpdatedAt.IsNull())
+
+	// The retry of the unavailable call is the same request.
+	require.Len(t, svc.headers, 2)
+	for _, header := range svc.headers {
+		assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+		assert.Equal(t, "req-1", header.Get(connectx.RequestIDHeader))
+	}
+	key := svc.headers[0].Get(connectx.IdempotencyKeyHeader)
+	assert.NotEmpty(t, key)
+	assert.Equal(t, key, svc.headers[1].Get(connectx.IdempotencyKeyHeader))
+}
+
+func TestClient_GetUser_NotFound(t *testing.T) {
+	svc := &fakeUserService{}
+	srv := newServer(t, svc)
+
+	c, err := client.New(client.WithBaseURL(srv.URL), client.WithAPIKey(client.StaticToken("key")))
+	require.NoError(t, err)
+
+	_, err = c.GetUser(context.Background(), 42)
+	var ae *apperr.AppError
+	require.ErrorAs(t, err, &ae)
+	assert.Equal(t, "user.not_found", ae.Code)
+	assert.Equal(t, http.StatusNotFound, ae.HTTPStatus)
+
+	// Not retried, and without idempotency key since it has no side effects.
+	require.Len(t, svc.headers, 1)
+	assert.Equal(t, "key", svc.headers[0].Get(connectx.APIKeyHeader))
+	assert.Empty(t, svc.headers[0].Get(connectx.IdempotencyKeyHeader))
+	assert.NotEmpty(t, svc.headers[0].Get(connectx.RequestIDHeader))
+}
+
+func TestClient_Unavailable(t *testing.T) {
+	svc := &fakeUserService{unavailable: 5}
+	srv := newServer(t, svc)
+
+	c, err := client.New(client.WithBaseURL(srv.URL), client.WithRetry(fastRetry))
+	require.NoError(t, err)
+
+	_, _, err = c.ListUsers(context.Background(), 0, 10)
+	var ae *apperr.AppError
+	require.ErrorAs(t, err, &ae)
+	assert.Equal(t, connect.CodeUnavailable.String(), ae.Code)
+	assert.Len(t, svc.headers, fastRetry.MaxAttempts)
+}
+
+func TestClient_UpdateUser(t *testing.T) {
+	svc := &fakeUserService{}
+	srv := newServer(t, svc)
+
+	c, err := client.New(client.WithBaseURL(srv.URL))
+	require.NoError(t, err)
+
+	updated, err := c.UpdateUser(context.Background(), 7, client.UserUpdate{
+		FirstName: presence.Null[string](),
+		Role:      presence.FromValue(entity.RoleAdmin),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first_name", "role"}, svc.update.GetUpdateMask().GetPaths())
+	assert.Empty(t, svc.update.GetFirstName())
+	assert.Equal(t, entity.RoleAdmin, updated.Role)
+	assert.Equal(t, createTime, updated.UpdatedAt.MustGet())
+}
+
+func TestClient_Protocols(t *testing.T) {
+	srv := newServer(t, &fakeUserService{})
+
+	for _, protocol := range []client.Protocol{client.ProtocolConnect, client.ProtocolGRPC, client.ProtocolGRPCWeb} {
+		t.Run(string(protocol), func(t *testing.T) {
+			c, err := client.New(client.WithBaseURL(srv.URL), client.WithProtocol(protocol))
+			require.NoError(t, err)
+
+			users, total, err := c.ListUsers(context.Background(), 0, 10)
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.Equal(t, entity.RoleAdmin, users[0].Role)
+			assert.Equal(t, int64(12), total)
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := client.New(client.WithBaseURL("localhost"))
+	require.ErrorContains(t, err, "invalid base URL")
+
+	_, err = client.New(client.WithProtocol("soap"))
+	require.ErrorContains(t, err, `unknown protocol "soap"`)
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/reqid"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// unaryInterceptor adapts a function wrapping the unary client calls to a
+// connect.Interceptor. The handler and streaming calls are left untouched.
+type unaryInterceptor func(next connect.UnaryFunc) connect.UnaryFunc
+
+func (f unaryInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	wrapped := f(next)
+
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if !req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		return wrapped(ctx, req)
+	}
+}
+
+func (f unaryInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (f unaryInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// newTimeoutInterceptor bounds the calls without deadline to timeout.
+func newTimeoutInterceptor(timeout time.Duration) connect.Interceptor {
+	return unaryInterceptor(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+				return next(ctx, req)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return next(ctx, req)
+		}
+	})
+}
+
+// newCredentialInterceptor sets header to prefix followed by the credential of provider.
+func newCredentialInterceptor(header, prefix string, provider TokenProvider) connect.Interceptor {
+	return unaryInterceptor(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			credential, err := provider(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s credential: %w", header, err)
+			}
+			req.Header().Set(header, prefix+credential)
+
+			return next(ctx, req)
+		}
+	})
+}
+
+// newRetryInterceptor retries the calls according to policy, see RetryPolicy.
+func newRetryInterceptor(policy RetryPolicy) connect.Interceptor {
+	return unaryInterceptor(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if policy.MaxAttempts <= 1 {
+				return next(ctx, req)
+			}
+			if req.Spec().IdempotencyLevel != connect.IdempotencyNoSideEffects &&
+				req.Header().Get(connectx.IdempotencyKeyHeader) == "" {
+				// Random 128-bit keys, like the request ids.
+				req.Header().Set(connectx.IdempotencyKeyHeader, reqid.New())
+			}
+
+			for attempt := 0; ; attempt++ {
+				res, err := next(ctx, req)
+				if err == nil || attempt+1 >= policy.MaxAttempts || !retryable(err) {
+					return res, err
+				}
+				if !sleep(ctx, policy.delay(attempt, err)) {
+					return res, err
+				}
+			}
+		}
+	})
+}
+
+func retryable(err error) bool {
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeAborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// delay returns the jittered backoff before the attempt following attempt,
+// or the delay asked by the server with err when longer.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	backoff := p.MaxBackoff
+	if attempt < 32 && p.InitialBackoff<<attempt > 0 {
+		backoff = min(p.InitialBackoff<<attempt, p.MaxBackoff)
+	}
+
+	var d time.Duration
+	if backoff > 0 {
+		d = rand.N(backoff) + 1 //nolint:gosec // jitter does not need a secure random
+	}
+	if ae := connectx.AppErrorFromConnect(err); ae != nil && ae.RetryAfter > d {
+		d = ae.RetryAfter
+	}
+
+	return d
+}
+
+// sleep waits for d, returning false when ctx is done first or would be
+// before the end of the wait.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// appError returns the AppError sent by the server with err, or err when the
+// call failed before reaching it.
+func appError(err error) error {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) {
+		return fmt.Errorf("failed to call user service: %w", err)
+	}
+
+	return connectx.AppErrorFromConnect(err)
+}
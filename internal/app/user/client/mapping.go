@@ -0,0 +1,67 @@
+package client
+
+import (
+	"time"
+
+	"github.com/pivaldi/presence"
+
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+)
+
+// UserUpdate lists the fields to update: the unset ones are left untouched,
+// and a null FirstName or LastName clears the name.
+type UserUpdate struct {
+	Email     presence.Of[string]
+	Password  presence.Of[string] // clear text
+	FirstName presence.Of[string]
+	LastName  presence.Of[string]
+	Role      presence.Of[entity.Role]
+}
+
+// userFromV2 returns the user of the API as an entity, without password.
+func userFromV2(u *userv2.User) *entity.User {
+	user := &entity.User{
+		ID:        u.GetId(),
+		Email:     u.GetEmail(),
+		FirstName: presence.Null[string](),
+		LastName:  presence.Null[string](),
+		Role:      roleFromV2(u.GetRole()),
+		CreatedAt: u.GetCreateTime().AsTime(),
+		UpdatedAt: presence.Null[time.Time](),
+	}
+	if u.FirstName != nil {
+		user.SetFirstName(u.GetFirstName())
+	}
+	if u.LastName != nil {
+		user.SetLastName(u.GetLastName())
+	}
+	if u.UpdateTime != nil {
+		user.UpdatedAt = presence.FromValue(u.GetUpdateTime().AsTime())
+	}
+
+	return user
+}
+
+func roleToV2(role entity.Role) userv2.Role {
+	switch role {
+	case entity.RoleAdmin:
+		return userv2.Role_ROLE_ADMIN
+	case entity.RoleUser:
+		return userv2.Role_ROLE_USER
+	default:
+		return userv2.Role_ROLE_UNSPECIFIED
+	}
+}
+
+// roleFromV2 returns the empty, invalid, role for the unknown roles.
+func roleFromV2(role userv2.Role) entity.Role {
+	switch role {
+	case userv2.Role_ROLE_ADMIN:
+		return entity.RoleAdmin
+	case userv2.Role_ROLE_USER:
+		return entity.RoleUser
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// Protocol is the RPC protocol spoken with the server.
+type Protocol string
+
+const (
+	ProtocolConnect Protocol = "connect"
+	// ProtocolGRPC requires HTTP/2, spoken in cleartext (h2c) with http:// base URLs.
+	ProtocolGRPC    Protocol = "grpc"
+	ProtocolGRPCWeb Protocol = "grpc-web"
+)
+
+const (
+	defaultBaseURL = "http://localhost:4224"
+	defaultTimeout = 30 * time.Second
+)
+
+// TokenProvider returns the credential to send with a call, e.g. a token
+// refreshed when it expires. It is called before every attempt.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// StaticToken returns a provider of a credential that never changes.
+func StaticToken(token string) TokenProvider {
+	return func(context.Context) (string, error) { return token, nil }
+}
+
+// RetryPolicy retries the calls failing with unavailable or aborted, after
+// an exponential backoff with full jitter: the delay before the attempt n+1
+// is random, up to InitialBackoff * 2^n capped to MaxBackoff, or the delay
+// asked by the server when longer. Mutating calls get an Idempotency-Key,
+// so that they are applied once whatever the number of attempts.
+type RetryPolicy struct {
+	MaxAttempts    int // 1 disables the retries
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy makes 3 attempts, waiting up to 100ms then 200ms.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sets the URL of the server, http://localhost:4224 by default.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithProtocol sets the RPC protocol, Connect by default.
+func WithProtocol(protocol Protocol) Option {
+	return func(c *Client) {
+		c.protocol = protocol
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the calls. It must speak HTTP/2 for the gRPC protocol.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken authenticates the calls with an "Authorization: Bearer" header.
+func WithBearerToken(provider TokenProvider) Option {
+	return func(c *Client) {
+		c.bearerToken = provider
+	}
+}
+
+// WithAPIKey authenticates the calls with an X-Api-Key header.
+func WithAPIKey(provider TokenProvider) Option {
+	return func(c *Client) {
+		c.apiKey = provider
+	}
+}
+
+// WithRetry sets the retry policy, DefaultRetryPolicy by default.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithTimeout sets the deadline of the calls whose context has none, 30s by
+// default. It covers every attempt of a call, and is sent to the server.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithClientOptions adds options to the underlying Connect client, e.g.
+// interceptors run on every attempt of the calls.
+func WithClientOptions(opts ...connect.ClientOption) Option {
+	return func(c *Client) {
+		c.clientOptions = append(c.clientOptions, opts...)
+	}
+}
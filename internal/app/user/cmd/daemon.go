@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	appConfig "github.com/pivaldi/go-cleanstack/internal/app/user/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/clierr"
+	"github.com/pivaldi/go-cleanstack/pkg/file"
+)
+
+// daemonEnv marks the process started by serve --detach, which must not detach again.
+const daemonEnv = "CLEANSTACK_DAEMON"
+
+const (
+	pidfilePerm = 0o644
+	// daemonPoll is the interval of the checks of the pidfile by serve --detach and stop.
+	daemonPoll = 100 * time.Millisecond
+	// detachTimeout bounds the wait of serve --detach for the server to hold the pidfile.
+	detachTimeout = 10 * time.Second
+	// pidfileLockRetry bounds the wait of serve for a pidfile locked without pid.
+	pidfileLockRetry = time.Second
+	pidfileLockPoll  = 10 * time.Millisecond
+	// stopGrace is added to the shutdown delays of the server by the default timeout of stop.
+	stopGrace = 10 * time.Second
+)
+
+// States of a server, by its pidfile.
+const (
+	stateRunning = "running"
+	stateStale   = "stale"
+	stateStopped = "stopped"
+)
+
+// Error codes of status, with the exit codes of the LSB init scripts.
+const (
+	errCodeStale   = "daemon.stale"
+	errCodeStopped = "daemon.stopped"
+)
+
+// lockPidfile creates the pidfile at path, or takes over a stale one, locked
+// and holding the pid of the process as long as it runs. It fails when a
+// running server holds it. A pidfile locked without pid is being checked by
+// status or stop, or written by a starting server: it is tried again for
+// pidfileLockRetry.
+func lockPidfile(path string) (*file.LockFile, error) {
+	deadline := time.Now().Add(pidfileLockRetry)
+	for {
+		lock, err := file.CreatePidFile(path, pidfilePerm)
+		if errors.Is(err, file.ErrWouldBlock) {
+			pid, _ := file.ReadPidFile(path)
+			if pid <= 0 && time.Now().Before(deadline) {
+				time.Sleep(pidfileLockPoll)
+
+				continue
+			}
+
+			return nil, fmt.Errorf("the server is already running with pid %d, see %s", pid, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pidfile: %w", err)
+		}
+
+		return lock, nil
+	}
+}
+
+// pidfileState returns the state of the server of the pidfile at path, and its
+// pid. The lock of the pidfile is the only proof of a running server, whose pid
+// may since have been reused by another process: a pidfile not locked is
+// stale, left by a server that did not stop cleanly. A stale pidfile is kept,
+// serve takes it over: removing it could remove the pidfile a starting server
+// just opened. The pidfile is only locked to be checked, not to prevent a
+// server from starting.
+func pidfileState(path string) (string, int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stateStopped, 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open pidfile: %w", err)
+	}
+	lock := file.NewLockFile(f)
+	defer lock.Close()
+
+	if err := lock.Lock(); errors.Is(err, file.ErrWouldBlock) {
+		pid, err := lock.ReadPid()
+		if err != nil || pid <= 0 {
+			return "", 0, fmt.Errorf("invalid pidfile %s", path)
+		}
+
+		return stateRunning, pid, nil
+	} else if err != nil {
+		return "", 0, err //nolint:wrapcheck // already wrapped by the lock
+	}
+
+	// The pid of a stale pidfile is only reported, it may be reused: it is never signaled.
+	pid, _ := lock.ReadPid()
+
+	return stateStale, pid, nil
+}
+
+// detach starts the command again in the background, in a session of its own,
+// and returns once the server holds pidfile. The output of the server is
+// appended to logFile, discarded when empty.
+func detach(cmd *cobra.Command, pidfile, logFile string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	child := exec.Command(executable, os.Args[1:]...) //nolint:gosec // the running executable
+	child.Env = append(os.Environ(), daemonEnv+"=1")
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if logFile != "" {
+		out, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer out.Close()
+		child.Stdout, child.Stderr = out, out
+	}
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- child.Wait() }()
+	timeout := time.After(detachTimeout)
+	ticker := time.NewTicker(daemonPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the server exited on start, see its log: %w", err)
+		case <-timeout:
+			return fmt.Errorf("the server with pid %d did not hold %s after %s",
+				child.Process.Pid, pidfile, detachTimeout)
+		case <-ticker.C:
+		}
+
+		state, pid, err := pidfileState(pidfile)
+		if err == nil && state == stateRunning && pid == child.Process.Pid {
+			fmt.Fprintf(cmd.OutOrStdout(), "started with pid %d\n", pid)
+
+			return nil
+		}
+	}
+}
+
+func NewStopCmd() *cobra.Command {
+	var (
+		pidfile string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the server of a pidfile",
+		Long: `Stop the server of a pidfile, started by serve --pidfile.
+
+The server holding the lock of the pidfile is sent SIGTERM, then stop waits
+for its graceful shutdown to release the pidfile. The pid of a stale pidfile,
+left by a server that did not stop cleanly, which may have been reused by
+another process, is not signaled: the pidfile is kept for serve to take over.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if timeout == 0 {
+				shutdown := appConfig.Get().Platform.Server.Shutdown
+				timeout = shutdown.PreStopDelay + shutdown.DrainTimeout + stopGrace
+			}
+			cmd.SilenceUsage = true
+
+			state, pid, err := pidfileState(pidfile)
+			if err != nil {
+				return err
+			}
+			switch state {
+			case stateStale:
+				fmt.Fprintf(cmd.OutOrStdout(), "not running, stale pidfile of pid %d\n", pid)
+
+				return nil
+			case stateStopped:
+				fmt.Fprintln(cmd.OutOrStdout(), "not running")
+
+				return nil
+			}
+
+			if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+				return fmt.Errorf("failed to stop pid %d: %w", pid, err)
+			}
+			deadline := time.Now().Add(timeout)
+			for state == stateRunning {
+				if time.Now().After(deadline) {
+					return fmt.Errorf("pid %d is still running after %s", pid, timeout)
+				}
+				time.Sleep(daemonPoll)
+				if state, _, err = pidfileState(pidfile); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "stopped pid %d\n", pid)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pidfile, "pidfile", "", "pidfile of the server")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0,
+		"how long to wait for the shutdown (default pre-stop-delay + drain-timeout + 10s)")
+	_ = cmd.MarkFlagRequired("pidfile")
+
+	return cmd
+}
+
+func NewStatusCmd() *cobra.Command {
+	var pidfile string
+
+	clierr.RegisterExitCode(errCodeStale, 1)
+	clierr.RegisterExitCode(errCodeStopped, 3)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report whether the server of a pidfile is running",
+		Long: `Report whether the server of a pidfile, started by serve --pidfile, is
+running. A stale pidfile, left by a server that did not stop cleanly, is
+kept for serve to take over.
+
+Exit codes, as the LSB init scripts:
+  0  running
+  1  stale pidfile
+  3  stopped`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+
+			state, pid, err := pidfileState(pidfile)
+			if err != nil {
+				return err
+			}
+
+			switch state {
+			case stateRunning:
+				fmt.Fprintf(cmd.OutOrStdout(), "running with pid %d\n", pid)
+
+				return nil
+			case stateStale:
+				return apperr.NewPublic(errCodeStale,
+					fmt.Sprintf("not running, stale pidfile of pid %d", pid), 0)
+			default:
+				return apperr.NewPublic(errCodeStopped, "not running", 0)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&pidfile, "pidfile", "", "pidfile of the server")
+	_ = cmd.MarkFlagRequired("pidfile")
+
+	return cmd
+}
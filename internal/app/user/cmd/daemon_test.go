@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/clierr"
+	"github.com/pivaldi/go-cleanstack/pkg/file"
+)
+
+func runDaemonCmd(t *testing.T, newCmd func() *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	cmd := newCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+
+	return stdout.String(), err
+}
+
+// deadPid returns the pid of a process that exited.
+func deadPid(t *testing.T) int {
+	t.Helper()
+
+	proc := exec.Command("true")
+	require.NoError(t, proc.Run())
+
+	return proc.Process.Pid
+}
+
+// pidfileServerEnv is the pidfile held by TestPidfileServer.
+const pidfileServerEnv = "CLEANSTACK_TEST_PIDFILE"
+
+// TestPidfileServer stands for a server in a process of its own, started by
+// startPidfileServer: it holds the pidfile until SIGTERM, then removes it.
+func TestPidfileServer(t *testing.T) {
+	pidfile := os.Getenv(pidfileServerEnv)
+	if pidfile == "" {
+		t.Skip("run by startPidfileServer")
+	}
+
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM)
+	lock, err := lockPidfile(pidfile)
+	require.NoError(t, err)
+	<-term
+	require.NoError(t, lock.Remove())
+}
+
+// startPidfileServer starts TestPidfileServer, returning once it holds pidfile.
+func startPidfileServer(t *testing.T, pidfile string) *exec.Cmd {
+	t.Helper()
+
+	server := exec.Command(os.Args[0], "-test.run=^TestPidfileServer$") //nolint:gosec // the test binary
+	server.Env = append(os.Environ(), pidfileServerEnv+"="+pidfile)
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { _ = server.Process.Kill() })
+
+	require.Eventually(t, func() bool {
+		state, pid, err := pidfileState(pidfile)
+
+		return err == nil && state == stateRunning && pid == server.Process.Pid
+	}, 10*time.Second, daemonPoll)
+
+	return server
+}
+
+func TestPidfile(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+
+	state, _, err := pidfileState(pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, stateStopped, state)
+
+	lock, err := lockPidfile(pidfile)
+	require.NoError(t, err)
+	state, pid, err := pidfileState(pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, stateRunning, state)
+	assert.Equal(t, os.Getpid(), pid)
+
+	_, err = lockPidfile(pidfile)
+	require.ErrorContains(t, err, "already running with pid "+strconv.Itoa(os.Getpid()))
+	assert.FileExists(t, pidfile, "the pidfile of the running server is kept")
+
+	require.NoError(t, lock.Remove())
+	state, _, err = pidfileState(pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, stateStopped, state)
+}
+
+func TestPidfile_Stale(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte(strconv.Itoa(deadPid(t))), pidfilePerm))
+
+	state, _, err := pidfileState(pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, stateStale, state)
+
+	// serve takes the stale pidfile over.
+	lock, err := lockPidfile(pidfile)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lock.Remove() })
+	state, pid, err := pidfileState(pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, stateRunning, state)
+	assert.Equal(t, os.Getpid(), pid)
+}
+
+func TestLockPidfile_LockedWithoutPid(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+
+	// status or stop checking the pidfile while serve starts.
+	check, err := file.OpenLockFile(pidfile, pidfilePerm)
+	require.NoError(t, err)
+	require.NoError(t, check.Lock())
+	go func() {
+		time.Sleep(5 * pidfileLockPoll)
+		_ = check.Close()
+	}()
+
+	lock, err := lockPidfile(pidfile)
+	require.NoError(t, err)
+	require.NoError(t, lock.Remove())
+}
+
+func TestStatusCmd(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+
+	_, err := runDaemonCmd(t, NewStatusCmd, "--pidfile", pidfile)
+	require.Error(t, err)
+	assert.Equal(t, 3, clierr.ExitCode(err))
+
+	lock, err := file.CreatePidFile(pidfile, pidfilePerm)
+	require.NoError(t, err)
+	out, err := runDaemonCmd(t, NewStatusCmd, "--pidfile", pidfile)
+	require.NoError(t, err)
+	assert.Equal(t, "running with pid "+strconv.Itoa(os.Getpid())+"\n", out)
+	require.NoError(t, lock.Remove())
+
+	for name, pid := range map[string]int{"dead pid": deadPid(t), "reused pid": os.Getpid()} {
+		require.NoError(t, os.WriteFile(pidfile, []byte(strconv.Itoa(pid)), pidfilePerm))
+		_, err = runDaemonCmd(t, NewStatusCmd, "--pidfile", pidfile)
+		require.ErrorContains(t, err, "stale pidfile of pid "+strconv.Itoa(pid), name)
+		assert.Equal(t, 1, clierr.ExitCode(err), name)
+		assert.FileExists(t, pidfile, "the stale pidfile is kept for serve to take over")
+	}
+}
+
+func TestStopCmd(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+
+	out, err := runDaemonCmd(t, NewStopCmd, "--pidfile", pidfile, "--timeout", "5s")
+	require.NoError(t, err)
+	assert.Equal(t, "not running\n", out)
+
+	server := startPidfileServer(t, pidfile)
+	exited := make(chan error, 1)
+	go func() { exited <- server.Wait() }()
+
+	out, err = runDaemonCmd(t, NewStopCmd, "--pidfile", pidfile, "--timeout", "5s")
+	require.NoError(t, err)
+	assert.Equal(t, "stopped pid "+strconv.Itoa(server.Process.Pid)+"\n", out)
+	assert.NoFileExists(t, pidfile)
+	require.NoError(t, <-exited, "the server stopped gracefully on SIGTERM")
+}
+
+func TestStopCmd_ReusedPid(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "cleanstack.pid")
+
+	// A process reusing the pid of a server that did not stop cleanly.
+	other := exec.Command("sleep", "30")
+	require.NoError(t, other.Start())
+	t.Cleanup(func() { _ = other.Process.Kill(); _ = other.Wait() })
+	require.NoError(t, os.WriteFile(pidfile, []byte(strconv.Itoa(other.Process.Pid)), pidfilePerm))
+
+	out, err := runDaemonCmd(t, NewStopCmd, "--pidfile", pidfile, "--timeout", "5s")
+	require.NoError(t, err)
+	assert.Equal(t, "not running, stale pidfile of pid "+strconv.Itoa(other.Process.Pid)+"\n", out)
+	assert.NoError(t, other.Process.Signal(syscall.Signal(0)), "the process of the unlocked pid is not signaled")
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/adapters"
+	appConfig "github.com/pivaldi/go-cleanstack/internal/app/user/config"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
+	"github.com/spf13/cobra"
+)
+
+var errImportFailures = errors.New("some rows were not imported")
+
+func NewImportCmd() *cobra.Command {
+	var (
+		filePath   string
+		reportPath string
+		onConflict string
+		dryRun     bool
+		batchSize  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Bulk import users from a CSV or NDJSON file",
+		Long: `Bulk import users from a CSV or NDJSON file.
+
+Columns (CSV header) or keys (NDJSON) are: email, password, password_hash,
+first_name, last_name and role (defaults to "user"). Use password_hash instead
+of password to import a bcrypt or argon2 hash as is.
+
+A per-row report (CSV) is written to --report, or to stdout.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			policy, err := ports.ParseConflictPolicy(onConflict)
+			if err != nil {
+				return fmt.Errorf("invalid --on-conflict: %w", err)
+			}
+
+			rows, err := readImportFile(filePath)
+			if err != nil {
+				return err
+			}
+
+			cfg := appConfig.Get()
+
+			db, err := persistence.NewDB(cfg.Platform.Database.URL)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+
+			logger, err := zap.NewLogger(string(cfg.Platform.AppEnv), cfg.Platform.Log.Level)
+			if err != nil {
+				return fmt.Errorf("failed to create logger: %w", err)
+			}
+
+			infraRepo := persistence.NewUserRepo(db)
+			userRepo := adapters.NewUserRepositoryAdapter(infraRepo)
+			userService := service.NewUserService(userRepo, logger)
+
+			// Ctrl-C stops the import between batches, the committed batches are reported.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			report, importErr := userService.ImportUsers(ctx, rows, service.ImportOptions{
+				OnConflict: policy,
+				DryRun:     dryRun,
+				BatchSize:  batchSize,
+			})
+			if report != nil {
+				if err := writeReport(cmd.OutOrStdout(), reportPath, report); err != nil {
+					return err
+				}
+				printImportSummary(cmd.ErrOrStderr(), report, dryRun)
+			}
+
+			if importErr != nil {
+				return fmt.Errorf("import aborted: %w", importErr)
+			}
+			if report.Count(service.ImportFailed) > 0 {
+				return errImportFailures
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filePath, "file", "", "users file to import (.csv, .ndjson or .jsonl)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write the per-row CSV report to this file instead of stdout")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", ports.ConflictPolicyFail.String(),
+		"what to do when the email already exists (fail, skip, update)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the file without writing to the database")
+	cmd.Flags().IntVar(&batchSize, "batch-size", service.DefaultImportBatchSize,
+		"number of users inserted per transaction")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func writeReport(stdout io.Writer, path string, report *service.ImportReport) error {
+	if path == "" {
+		return writeImportReport(stdout, report)
+	}
+
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer f.Close()
+
+	return writeImportReport(f, report)
+}
+
+func printImportSummary(w io.Writer, report *service.ImportReport, dryRun bool) {
+	if dryRun {
+		fmt.Fprintf(w, "dry run: %d valid, %d invalid\n",
+			report.Count(service.ImportValid),
+			report.Count(service.ImportFailed),
+		)
+
+		return
+	}
+
+	fmt.Fprintf(w, "import: %d created, %d updated, %d skipped, %d failed\n",
+		report.Count(service.ImportCreated),
+		report.Count(service.ImportUpdated),
+		report.Count(service.ImportSkipped),
+		report.Count(service.ImportFailed),
+	)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+const maxNDJSONLineBytes = 1 << 20
+
+var (
+	errBothPasswords    = errors.New("password and password_hash are mutually exclusive")
+	errMissingEmailCol  = errors.New("csv header must contain an email column")
+	errUnsupportedInput = errors.New("unsupported import file, expected .csv, .ndjson or .jsonl")
+)
+
+// importRecord is the flat shape of an imported user, shared by CSV and NDJSON.
+type importRecord struct {
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"password_hash"` //nolint:tagliatelle // import file format
+	FirstName    string `json:"first_name"`    //nolint:tagliatelle // import file format
+	LastName     string `json:"last_name"`     //nolint:tagliatelle // import file format
+	Role         string `json:"role"`
+}
+
+// readImportFile reads users from a CSV or NDJSON file, chosen by extension.
+func readImportFile(path string) ([]service.ImportRow, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readImportCSV(f)
+	case ".ndjson", ".jsonl":
+		return readImportNDJSON(f)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedInput, path)
+	}
+}
+
+// readImportCSV reads a CSV file whose first line is a header naming the columns.
+func readImportCSV(r io.Reader) ([]service.ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errMissingEmailCol
+	}
+
+	var rows []service.ImportRow
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, service.ImportRow{Line: parseErr.Line, Err: err})
+
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+
+			return ""
+		}
+
+		rows = append(rows, newImportRow(line, importRecord{
+			Email:        get("email"),
+			Password:     get("password"),
+			PasswordHash: get("password_hash"),
+			FirstName:    get("first_name"),
+			LastName:     get("last_name"),
+			Role:         get("role"),
+		}))
+	}
+}
+
+// readImportNDJSON reads one JSON object per line, blank lines are ignored.
+func readImportNDJSON(r io.Reader) ([]service.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxNDJSONLineBytes)
+
+	var rows []service.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var rec importRecord
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			rows = append(rows, service.ImportRow{Line: line, Err: fmt.Errorf("invalid json: %w", err)})
+
+			continue
+		}
+
+		rows = append(rows, newImportRow(line, rec))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ndjson: %w", err)
+	}
+
+	return rows, nil
+}
+
+func newImportRow(line int, rec importRecord) service.ImportRow {
+	if rec.Password != "" && rec.PasswordHash != "" {
+		return service.ImportRow{Line: line, Err: errBothPasswords}
+	}
+
+	role := entity.RoleUser
+	if rec.Role != "" {
+		role = entity.Role(strings.ToLower(rec.Role))
+	}
+
+	user := entity.NewUser(rec.Email, rec.Password, role)
+	if rec.PasswordHash != "" {
+		user.SetPasswordHash(rec.PasswordHash)
+	}
+	if rec.FirstName != "" {
+		user.SetFirstName(rec.FirstName)
+	}
+	if rec.LastName != "" {
+		user.SetLastName(rec.LastName)
+	}
+
+	return service.ImportRow{Line: line, User: user}
+}
+
+// writeImportReport writes one CSV line per imported row.
+func writeImportReport(w io.Writer, report *service.ImportReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"line", "email", "status", "user_id", "error"}); err != nil {
+		return fmt.Errorf("failed to write report header: %w", err)
+	}
+
+	for _, res := range report.Results {
+		var userID, errMsg string
+		if res.UserID != 0 {
+			userID = strconv.FormatInt(res.UserID, 10)
+		}
+		if res.Err != nil {
+			errMsg = res.Err.Error()
+		}
+
+		record := []string{strconv.Itoa(res.Line), res.Email, string(res.Status), userID, errMsg}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write report line: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to flush report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+const bcryptHash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+
+func TestReadImportCSV(t *testing.T) {
+	input := "email,password,password_hash,first_name,last_name,role\n" +
+		"john@example.com,password123,,John,Doe,admin\n" +
+		"jane@example.com,," + bcryptHash + ",Jane,,\n" +
+		"both@example.com,password123," + bcryptHash + ",,,\n"
+
+	rows, err := readImportCSV(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "john@example.com", rows[0].User.Email)
+	assert.Equal(t, entity.RoleAdmin, rows[0].User.Role)
+	assert.Equal(t, "Doe", rows[0].User.LastName.MustGet())
+	assert.False(t, rows[0].User.PasswordHashed)
+
+	assert.Equal(t, entity.RoleUser, rows[1].User.Role)
+	assert.True(t, rows[1].User.PasswordHashed)
+	assert.False(t, rows[1].User.LastName.IsSet())
+
+	assert.Equal(t, 4, rows[2].Line)
+	assert.ErrorIs(t, rows[2].Err, errBothPasswords)
+}
+
+func TestReadImportCSV_MissingEmailColumn(t *testing.T) {
+	_, err := readImportCSV(strings.NewReader("password,role\nsecret123,user\n"))
+	assert.ErrorIs(t, err, errMissingEmailCol)
+}
+
+func TestReadImportNDJSON(t *testing.T) {
+	input := `{"email":"john@example.com","password":"password123","role":"user"}
+
+{"email":"jane@example.com","password_hash":"` + bcryptHash + `"}
+{"email":"bad@example.com","nickname":"oops"}
+not json
+`
+
+	rows, err := readImportNDJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "john@example.com", rows[0].User.Email)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.True(t, rows[1].User.PasswordHashed)
+	require.Error(t, rows[2].Err)
+	assert.Equal(t, 5, rows[3].Line)
+	require.Error(t, rows[3].Err)
+}
+
+func TestReadImportFile_UnsupportedExtension(t *testing.T) {
+	_, err := readImportFile("users.xlsx")
+	require.Error(t, err)
+}
+
+func TestWriteImportReport(t *testing.T) {
+	report := &service.ImportReport{Results: []service.ImportResult{
+		{Line: 2, Email: "john@example.com", Status: service.ImportCreated, UserID: 12},
+		{Line: 3, Email: "bad", Status: service.ImportFailed, Err: entity.ErrEmailInvalid},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeImportReport(&buf, report))
+
+	assert.Equal(t, "line,email,status,user_id,error\n"+
+		"2,john@example.com,created,12,\n"+
+		"3,bad,failed,,email format is invalid\n", buf.String())
+}
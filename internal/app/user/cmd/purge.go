@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+)
+
+// purgeInterval is the delay between two purges of the stores.
+const purgeInterval = time.Minute
+
+// purger deletes the stale entries of a store, returning how many were deleted.
+type purger interface {
+	Purge(ctx context.Context) (int64, error)
+}
+
+// startPurges runs the purgers, named after what they delete, every interval
+// until ctx is done, returning the function stopping them. Each purge is
+// bounded by the interval, so that a slow one never delays the next ones.
+// Failures are logged, the next tick retries.
+func startPurges(ctx context.Context, interval time.Duration, purgers map[string]purger, logger logging.Logger) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			for name, p := range purgers {
+				purge(ctx, interval, name, p, logger)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// purge runs p for at most timeout.
+func purge(ctx context.Context, timeout time.Duration, name string, p purger, logger logging.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	deleted, err := p.Purge(ctx)
+	if err != nil {
+		// A purge interrupted by the stop is no failure.
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			logger.Error("failed to purge "+name, logging.String("error", err.Error()))
+		}
+
+		return
+	}
+	logger.Debug("purged "+name, logging.Int64("deleted", deleted))
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
+)
+
+type countingPurger struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (p *countingPurger) Purge(ctx context.Context) (int64, error) {
+	p.calls.Add(1)
+	if _, ok := ctx.Deadline(); !ok {
+		return 0, errors.New("purge without deadline")
+	}
+
+	return 1, p.err
+}
+
+func TestStartPurges(t *testing.T) {
+	logger, err := zap.NewDevelopment("debug")
+	require.NoError(t, err)
+
+	ok := &countingPurger{}
+	failing := &countingPurger{err: errors.New("database down")}
+	stop := startPurges(context.Background(), 10*time.Millisecond, map[string]purger{
+		"ok":      ok,
+		"failing": failing,
+	}, logger)
+
+	// A failing purge stops none of the following ones.
+	assert.Eventually(t, func() bool {
+		return ok.calls.Load() >= 2 && failing.calls.Load() >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	stop()
+	calls := ok.calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, calls, ok.calls.Load(), "no purge after stop")
+}
+
+func TestStartPurgesStopsWithContext(t *testing.T) {
+	logger, err := zap.NewDevelopment("debug")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := startPurges(ctx, time.Hour, map[string]purger{"ok": &countingPurger{}}, logger)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purges not stopped with their context")
+	}
+}
@@ -50,6 +50,10 @@ func GetRootCmd() *cobra.Command {
 
 	rootCmd.AddCommand(NewVersionCmd())
 	rootCmd.AddCommand(NewServeCmd())
+	rootCmd.AddCommand(NewStopCmd())
+	rootCmd.AddCommand(NewStatusCmd())
+	rootCmd.AddCommand(NewImportCmd())
+	rootCmd.AddCommand(NewUsersCmd())
 	// app.cmd.AddCommand(NewMigrateCmd())
 
 	return rootCmd
@@ -1,44 +1,307 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/adapters"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api"
 	appConfig "github.com/pivaldi/go-cleanstack/internal/app/user/config"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/tracing"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/admin"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// tracingShutdownTimeout bounds the export of the pending spans on exit.
+	tracingShutdownTimeout = 5 * time.Second
+	// adminShutdownTimeout bounds the admin requests in flight on exit, e.g. profiles.
+	adminShutdownTimeout = time.Second
+)
+
 func NewServeCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		pidfile  string
+		detached bool
+		logFile  string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Start the HTTP server",
-		RunE: func(_ *cobra.Command, _ []string) error {
+		Long: `Start the HTTP server.
+
+On SIGHUP or when the configuration file changes, the log level, rate limit
+policies, CORS origins and feature flags are reloaded; the other changed
+settings are logged as rejected and require a restart.
+
+On SIGINT or SIGTERM, the server reports itself as not serving to health
+checks, keeps serving for [platform.server.shutdown] pre-stop-delay, then
+drains the in-flight requests for at most drain-timeout before closing the
+database. A second signal stops it immediately.
+
+With --pidfile, the server refuses to start while another one holds the
+pidfile, see the stop and status commands. With --detach, it runs in the
+background, its output appended to --log-file.`,
+		RunE: func(cmd *cobra.Command, _ []string) (err error) {
+			if detached && os.Getenv(daemonEnv) == "" {
+				if pidfile == "" {
+					return errors.New("--detach requires --pidfile, to stop the server")
+				}
+
+				cmd.SilenceUsage = true
+
+				return detach(cmd, pidfile, logFile)
+			}
+			if pidfile != "" {
+				lock, err := lockPidfile(pidfile)
+				if err != nil {
+					return err
+				}
+				// Removed last, once the server is stopped and its resources released.
+				defer func() { _ = lock.Remove() }()
+			}
+
 			cfg := appConfig.Get()
 
 			db, err := persistence.NewDB(cfg.Platform.Database.URL)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
-			defer db.Close()
 
 			logger, err := zap.NewLogger(string(cfg.Platform.AppEnv), cfg.Platform.Log.Level)
 			if err != nil {
+				_ = db.Close()
+
 				return fmt.Errorf("failed to create logger: %w", err)
 			}
 
+			shutdownTracing, err := tracing.Setup(cmd.Context(), cfg.Platform.Tracing)
+			if err != nil {
+				_ = db.Close()
+
+				return fmt.Errorf("failed to set up tracing: %w", err)
+			}
+
+			// The spans and the logger are flushed, then the database closed, once the server is drained.
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+				defer cancel()
+				if traceErr := shutdownTracing(ctx); traceErr != nil {
+					err = errors.Join(err, traceErr)
+				}
+				if syncErr := logger.Sync(); syncErr != nil && !isUnsyncable(syncErr) {
+					err = errors.Join(err, syncErr)
+				}
+				if closeErr := db.Close(); closeErr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to close database: %w", closeErr))
+				}
+			}()
+
 			logger.Info("connected to database")
 
-			infraRepo := persistence.NewUserRepo(db)
+			var (
+				repoOpts    []persistence.UserRepoOption
+				serviceOpts []service.Option
+				opts        []api.Option
+			)
+			if cfg.Platform.Metrics.Enabled {
+				repoOpts, serviceOpts, opts, err = metricsOptions(cfg.Platform.Metrics, db)
+				if err != nil {
+					return err
+				}
+			}
+
+			// The watcher is created first, for the service to read the reloaded feature flags.
+			watcher := config.NewWatcher(cfg.Platform, logger)
+			serviceOpts = append(serviceOpts, service.WithFeatures(watcher))
+
+			infraRepo := persistence.NewUserRepo(db, repoOpts...)
 			userRepo := adapters.NewUserRepositoryAdapter(infraRepo)
-			userService := service.NewUserService(userRepo, logger)
+			userService := service.NewUserService(userRepo, logger, serviceOpts...)
+
+			idempotencyStore := adapters.NewIdempotencyStoreAdapter(persistence.NewIdempotencyRepo(db))
+
+			migrationsCheck, err := persistence.MigrationsCheck(db)
+			if err != nil {
+				return err
+			}
 
-			server := api.NewServer(cfg.Platform.Server.Port, userService, logger)
+			opts = append(opts,
+				api.WithIdempotencyStore(idempotencyStore),
+				api.WithReadinessCheck("pgcrypto", persistence.PgcryptoCheck(db)),
+				api.WithReadinessCheck("migrations", migrationsCheck),
+			)
+			purgers := map[string]purger{"idempotency keys": idempotencyStore}
+			if cfg.Platform.Server.RateLimit.Store == config.RateLimitStorePostgres {
+				rateLimitStore := adapters.NewRateLimitStoreAdapter(persistence.NewRateLimitRepo(db))
+				opts = append(opts, api.WithRateLimitStore(rateLimitStore))
+				purgers["rate limit buckets"] = rateLimitStore
+			}
+
+			server := api.NewServer(cfg.Platform, userService, db, logger, opts...)
+
+			// The stores are purged in the background, away from the requests and their cancellation.
+			stopPurges := startPurges(cmd.Context(), purgeInterval, purgers, logger)
+			defer stopPurges()
+
+			stopWatcher := watchConfig(cmd.Context(), watcher, server, logger, cmd.Flags().Changed("log-level"))
+			defer stopWatcher()
 
-			return server.Start()
+			if cfg.Platform.Admin.Addr != "" {
+				stopAdmin, err := startAdmin(cfg.Platform, db, server, logger)
+				if err != nil {
+					return err
+				}
+				defer stopAdmin()
+			}
+
+			return serve(cmd.Context(), server, logger)
 		},
 	}
+
+	cmd.Flags().StringVar(&pidfile, "pidfile", "", "pidfile held while the server runs, e.g. /run/cleanstack.pid")
+	cmd.Flags().BoolVar(&detached, "detach", false, "run the server in the background, requires --pidfile")
+	cmd.Flags().StringVar(&logFile, "log-file", "", "file the output of the detached server is appended to")
+
+	return cmd
+}
+
+// metricsOptions returns the options measuring the user repository, the user
+// service and the database pool in the metrics registry of the server.
+func metricsOptions(
+	cfg config.MetricsConfig,
+	db *sqlx.DB,
+) ([]persistence.UserRepoOption, []service.Option, []api.Option, error) {
+	reg := metrics.NewRegistry(cfg)
+	if err := reg.RegisterDB(db.DB, "user"); err != nil {
+		return nil, nil, nil, err //nolint:wrapcheck // already wrapped by the registry
+	}
+
+	queryMetrics, err := persistence.NewQueryMetrics(reg, "user")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	userMetrics, err := adapters.NewUserMetricsAdapter(reg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return []persistence.UserRepoOption{persistence.WithQueryObserver(queryMetrics)},
+		[]service.Option{service.WithMetrics(userMetrics)},
+		[]api.Option{api.WithMetrics(reg)},
+		nil
+}
+
+// startAdmin starts the admin listener, returning the function stopping it.
+func startAdmin(cfg config.Platform, db *sqlx.DB, server *api.Server, logger logging.Logger) (func(), error) {
+	opts := []admin.Option{
+		admin.WithBuildInfo(map[string]string{"buildTime": BuildTime, "appEnv": string(cfg.AppEnv)}),
+		admin.WithSettings(config.Settings),
+		admin.WithDB("user", db.DB),
+		admin.WithReadiness(server.ReadinessDetailsHandler()),
+	}
+	if metrics := server.MetricsHandler(); metrics != nil {
+		opts = append(opts, admin.WithMetrics(cfg.Metrics.Path, metrics))
+	}
+	if level := zap.LevelHandler(logger); level != nil {
+		opts = append(opts, admin.WithLogLevel(level))
+	}
+
+	adminServer, err := admin.NewServer(cfg.Admin.Addr, admin.NewHandler(opts...))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped by the admin server
+	}
+	go func() {
+		if err := adminServer.Serve(); err != nil {
+			logger.Error("admin server failed", logging.String("error", err.Error()))
+		}
+	}()
+	logger.Info("starting admin server", logging.String("address", adminServer.Addr()))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+		if err := adminServer.Shutdown(ctx); err != nil {
+			logger.Error("failed to stop admin server", logging.String("error", err.Error()))
+		}
+	}, nil
+}
+
+// watchConfig applies the reloadable settings of the configuration file to
+// the logger and server when the file changes or on SIGHUP, returning the
+// function stopping it. The log level is only applied when it changed in the
+// file, keeping the one set through the admin listener over the other reloads,
+// and never when set by --log-level, which overrides the file.
+func watchConfig(
+	ctx context.Context,
+	watcher *config.Watcher,
+	server *api.Server,
+	logger logging.Logger,
+	levelFromFlag bool,
+) func() {
+	if !levelFromFlag {
+		watcher.Subscribe(func(cfg config.Platform) (func(), error) {
+			return zap.ReloadLevel(logger, cfg.Log.Level) //nolint:wrapcheck // already wrapped by the logger
+		}, "platform.log.level")
+	}
+	watcher.Subscribe(server.Reload)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := watcher.Run(ctx); err != nil {
+			logger.Error("config watcher failed", logging.String("error", err.Error()))
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// serve runs server until it fails, or until SIGINT or SIGTERM and its graceful shutdown.
+func serve(ctx context.Context, server *api.Server, logger logging.Logger) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan error, 1)
+	go func() { started <- server.Start() }()
+
+	select {
+	case err := <-started:
+		return err
+	case <-ctx.Done():
+	}
+	// Restores the default behavior of the signals: a second one kills the process.
+	stop()
+
+	logger.Info("shutting down HTTP server")
+	shutdownErr := server.Shutdown(context.Background())
+	if err := errors.Join(<-started, shutdownErr); err != nil {
+		return err
+	}
+	logger.Info("HTTP server stopped")
+
+	return nil
+}
+
+// isUnsyncable reports whether err is the failure to sync a logger writing to
+// a terminal or a pipe, which has nothing to flush.
+func isUnsyncable(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY)
 }
@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivaldi/presence"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/client"
+	appConfig "github.com/pivaldi/go-cleanstack/internal/app/user/config"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/clierr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// tokenEnv holds the default of --token, to keep the token out of the shell history.
+const tokenEnv = "CLEANSTACK_TOKEN"
+
+// usersExitCodes are the exit codes of the users commands by error code of
+// the API, 1 for the other errors.
+var usersExitCodes = []struct {
+	code     string
+	exitCode int
+}{
+	{handler.ErrCodeInvalidArgument.Code, 10},
+	{connectx.ErrCodeInvalidRequest.Code, 11},
+	{handler.ErrCodeNotFound.Code, 12},
+	{handler.ErrCodeCreateRejected.Code, 13},
+	{connectx.ErrCodeIdempotencyKeyReused.Code, 14},
+	{connectx.ErrCodeIdempotencyInProgress.Code, 15},
+	{connectx.ErrCodeRateLimited.Code, 16},
+	{connectx.ErrCodeDeadlineExceeded.Code, 17},
+	{connectx.ErrCodeCanceled.Code, 18},
+	{handler.ErrCodeInternal.Code, 19},
+	// Errors of the transport, without code of the API.
+	{"unauthenticated", 20},
+	{"permission_denied", 21},
+	{"unavailable", 22},
+	{"deadline_exceeded", 23},
+}
+
+var errPasswordMismatch = errors.New("passwords do not match")
+
+// usersFlags are the flags shared by the users commands.
+type usersFlags struct {
+	server   string
+	token    string
+	protocol string
+	output   string
+	timeout  time.Duration
+}
+
+func NewUsersCmd() *cobra.Command {
+	var flags usersFlags
+
+	var exitCodes strings.Builder
+	for _, c := range usersExitCodes {
+		clierr.RegisterExitCode(c.code, c.exitCode)
+		fmt.Fprintf(&exitCodes, "  %d  %s\n", c.exitCode, c.code)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage the users through the API of a running server",
+		Long: `Manage the users through the API of a running server.
+
+The commands exit with 1 on unexpected errors, or with the exit code of the
+error code returned by the server:
+
+` + exitCodes.String(),
+	}
+
+	cmd.PersistentFlags().StringVar(&flags.server, "server", "",
+		"base URL of the server (default the first TCP address of platform.server.listen)")
+	cmd.PersistentFlags().StringVar(&flags.token, "token", os.Getenv(tokenEnv),
+		"bearer token authenticating the calls, $"+tokenEnv+" by default")
+	cmd.PersistentFlags().StringVar(&flags.protocol, "protocol", "connect", "RPC protocol (connect, grpc, grpcweb)")
+	cmd.PersistentFlags().StringVarP(&flags.output, "output", "o", outputTable, "output format (table, json, yaml)")
+	cmd.PersistentFlags().DurationVar(&flags.timeout, "timeout", 30*time.Second, "timeout of the call to the server")
+
+	cmd.AddCommand(
+		newUsersCreateCmd(&flags),
+		newUsersGetCmd(&flags),
+		newUsersGetByEmailCmd(&flags),
+		newUsersListCmd(&flags),
+		newUsersUpdateCmd(&flags),
+		newUsersDeleteCmd(&flags),
+	)
+
+	return cmd
+}
+
+// defaultServer returns the base URL of the first TCP address the server of
+// cfg listens on, on localhost when it listens on every interface.
+func defaultServer(cfg config.ServerConfig) (string, error) {
+	scheme := "http"
+	if cfg.TLS.Enabled {
+		scheme = "https"
+	}
+
+	for _, addr := range cfg.Listen {
+		parsed, err := connectx.ParseListenAddress(addr)
+		if err != nil || parsed.Scheme != connectx.ListenTCP {
+			continue
+		}
+		host, port, _ := net.SplitHostPort(parsed.Address)
+		if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+			host = "localhost"
+		}
+
+		return scheme + "://" + net.JoinHostPort(host, port), nil
+	}
+
+	return "", errors.New("the server listens on no TCP address, set --server")
+}
+
+// newClient returns the client of the server of flags. Usage is not printed
+// on the errors that follow, returned by the server.
+func (f *usersFlags) newClient(cmd *cobra.Command) (*client.Client, error) {
+	if err := validateOutput(f.output); err != nil {
+		return nil, err
+	}
+
+	protocol, err := parseProtocol(f.protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	server := f.server
+	if server == "" {
+		if server, err = defaultServer(appConfig.Get().Platform.Server); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []client.Option{
+		client.WithBaseURL(server),
+		client.WithProtocol(protocol),
+		client.WithTimeout(f.timeout),
+	}
+	if f.token != "" {
+		opts = append(opts, client.WithBearerToken(client.StaticToken(f.token)))
+	}
+
+	c, err := client.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	cmd.SilenceUsage = true
+
+	return c, nil
+}
+
+func parseProtocol(protocol string) (client.Protocol, error) {
+	switch protocol {
+	case "connect":
+		return client.ProtocolConnect, nil
+	case "grpc":
+		return client.ProtocolGRPC, nil
+	case "grpcweb", "grpc-web":
+		return client.ProtocolGRPCWeb, nil
+	default:
+		return "", fmt.Errorf("invalid --protocol %q: want connect, grpc or grpcweb", protocol)
+	}
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid user id %q", arg)
+	}
+
+	return id, nil
+}
+
+func newUsersCreateCmd(flags *usersFlags) *cobra.Command {
+	var email, firstName, lastName, role string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user, reading its password from the terminal or the first line of stdin",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			password, err := readPassword(cmd.InOrStdin(), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			user := entity.NewUser(email, password, entity.Role(role))
+			if firstName != "" {
+				user.SetFirstName(firstName)
+			}
+			if lastName != "" {
+				user.SetLastName(lastName)
+			}
+
+			created, err := c.CreateUser(cmd.Context(), user)
+			if err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+
+			return writeUsers(cmd.OutOrStdout(), flags.output, []*entity.User{created})
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "email of the user")
+	cmd.Flags().StringVar(&firstName, "first-name", "", "first name of the user")
+	cmd.Flags().StringVar(&lastName, "last-name", "", "last name of the user")
+	cmd.Flags().StringVar(&role, "role", entity.RoleUser.String(), "role of the user (user, admin)")
+	_ = cmd.MarkFlagRequired("email")
+
+	return cmd
+}
+
+func newUsersGetCmd(flags *usersFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Get a user by id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			user, err := c.GetUser(cmd.Context(), id)
+			if err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+
+			return writeUsers(cmd.OutOrStdout(), flags.output, []*entity.User{user})
+		},
+	}
+}
+
+func newUsersGetByEmailCmd(flags *usersFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get-by-email <email>",
+		Short: "Get a user by email",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			user, err := c.GetUserByEmail(cmd.Context(), args[0])
+			if err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+
+			return writeUsers(cmd.OutOrStdout(), flags.output, []*entity.User{user})
+		},
+	}
+}
+
+func newUsersListCmd(flags *usersFlags) *cobra.Command {
+	var offset, limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			users, total, err := c.ListUsers(cmd.Context(), offset, limit)
+			if err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+
+			return writeUserList(cmd.OutOrStdout(), flags.output, users, total)
+		},
+	}
+
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of users to skip")
+	cmd.Flags().IntVar(&limit, "limit", 20, "maximum number of users to return, at most 100")
+
+	return cmd
+}
+
+func newUsersUpdateCmd(flags *usersFlags) *cobra.Command {
+	var (
+		email, firstName, lastName, role string
+		password                         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Update the given fields of a user",
+		Long: `Update the given fields of a user, the others are left untouched.
+An empty --first-name or --last-name clears the name. With --password, the new
+password is read from the terminal or the first line of stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			var update client.UserUpdate
+			changed := cmd.Flags().Changed
+			if changed("email") {
+				update.Email = presence.FromValue(email)
+			}
+			if changed("first-name") {
+				update.FirstName = optionalName(firstName)
+			}
+			if changed("last-name") {
+				update.LastName = optionalName(lastName)
+			}
+			if changed("role") {
+				update.Role = presence.FromValue(entity.Role(role))
+			}
+			if !changed("email") && !changed("first-name") && !changed("last-name") && !changed("role") && !password {
+				return errors.New("nothing to update: set --email, --first-name, --last-name, --role or --password")
+			}
+
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			if password {
+				newPassword, err := readPassword(cmd.InOrStdin(), cmd.ErrOrStderr())
+				if err != nil {
+					return err
+				}
+				update.Password = presence.FromValue(newPassword)
+			}
+
+			user, err := c.UpdateUser(cmd.Context(), id, update)
+			if err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+
+			return writeUsers(cmd.OutOrStdout(), flags.output, []*entity.User{user})
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "new email")
+	cmd.Flags().StringVar(&firstName, "first-name", "", "new first name, empty to clear it")
+	cmd.Flags().StringVar(&lastName, "last-name", "", "new last name, empty to clear it")
+	cmd.Flags().StringVar(&role, "role", "", "new role (user, admin)")
+	cmd.Flags().BoolVar(&password, "password", false, "change the password")
+
+	return cmd
+}
+
+func newUsersDeleteCmd(flags *usersFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := c.DeleteUser(cmd.Context(), id); err != nil {
+				return err //nolint:wrapcheck // the AppError drives the exit code
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "user %d deleted\n", id)
+
+			return nil
+		},
+	}
+}
+
+// optionalName returns a null name for the empty string, which clears it.
+func optionalName(name string) presence.Of[string] {
+	if name == "" {
+		return presence.Null[string]()
+	}
+
+	return presence.FromValue(name)
+}
+
+// readPassword reads a password without echo, twice, when in is a terminal,
+// else reads the first line of in, e.g. piped from a secret manager.
+func readPassword(in io.Reader, prompt io.Writer) (string, error) {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	read := func(label string) (string, error) {
+		fmt.Fprint(prompt, label)
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(prompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+
+		return string(password), nil
+	}
+
+	password, err := read("Password: ")
+	if err != nil {
+		return "", err
+	}
+	confirm, err := read("Confirm password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirm {
+		return "", errPasswordMismatch
+	}
+
+	return password, nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+)
+
+// Output formats of the users commands.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// userOutput is a user as printed by the users commands.
+type userOutput struct {
+	ID        int64      `json:"id"                  yaml:"id"`
+	Email     string     `json:"email"               yaml:"email"`
+	FirstName *string    `json:"firstName,omitempty" yaml:"firstName,omitempty"`
+	LastName  *string    `json:"lastName,omitempty"  yaml:"lastName,omitempty"`
+	Role      string     `json:"role"                yaml:"role"`
+	CreatedAt time.Time  `json:"createdAt"           yaml:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" yaml:"updatedAt,omitempty"`
+}
+
+type userListOutput struct {
+	Users []userOutput `json:"users" yaml:"users"`
+	Total int64        `json:"total" yaml:"total"`
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q: want table, json or yaml", output)
+	}
+}
+
+func outputOf(user *entity.User) userOutput {
+	return userOutput{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName.Ptr(),
+		LastName:  user.LastName.Ptr(),
+		Role:      user.Role.String(),
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt.Ptr(),
+	}
+}
+
+// writeUsers writes users in the output format, a single user being written
+// as an object rather than a list in JSON and YAML.
+func writeUsers(w io.Writer, output string, users []*entity.User) error {
+	outputs := make([]userOutput, len(users))
+	for i, user := range users {
+		outputs[i] = outputOf(user)
+	}
+
+	if output == outputTable {
+		return writeUserTable(w, outputs)
+	}
+	if len(outputs) == 1 {
+		return encode(w, output, outputs[0])
+	}
+
+	return encode(w, output, outputs)
+}
+
+// writeUserList writes a page of users, followed by the total number of users.
+func writeUserList(w io.Writer, output string, users []*entity.User, total int64) error {
+	list := userListOutput{Users: make([]userOutput, len(users)), Total: total}
+	for i, user := range users {
+		list.Users[i] = outputOf(user)
+	}
+
+	if output != outputTable {
+		return encode(w, output, list)
+	}
+
+	if err := writeUserTable(w, list.Users); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "\n%d of %d users\n", len(users), total); err != nil {
+		return fmt.Errorf("failed to write users: %w", err)
+	}
+
+	return nil
+}
+
+func writeUserTable(w io.Writer, users []userOutput) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEMAIL\tFIRST NAME\tLAST NAME\tROLE\tCREATED AT\tUPDATED AT")
+	for _, u := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			u.ID, u.Email, orDash(u.FirstName), orDash(u.LastName), u.Role,
+			u.CreatedAt.Format(time.RFC3339), orDash(formatTime(u.UpdatedAt)))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write users: %w", err)
+	}
+
+	return nil
+}
+
+func encode(w io.Writer, output string, v any) error {
+	var err error
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		err = enc.Encode(v)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write users: %w", err)
+	}
+
+	return nil
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+
+	return &formatted
+}
+
+func orDash(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+
+	return *s
+}
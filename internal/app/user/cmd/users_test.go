@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/clierr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+var createdAt = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+type fakeUserService struct {
+	userv2connect.UnimplementedUserServiceHandler
+
+	created *userv2.CreateUserRequest
+}
+
+func (s *fakeUserService) CreateUser(
+	_ context.Context,
+	req *connect.Request[userv2.CreateUserRequest],
+) (*connect.Response[userv2.CreateUserResponse], error) {
+	s.created = req.Msg
+
+	return connect.NewResponse(&userv2.CreateUserResponse{User: &userv2.User{
+		Id:         3,
+		Email:      req.Msg.Email,
+		Role:       req.Msg.Role,
+		CreateTime: timestamppb.New(createdAt),
+	}}), nil
+}
+
+func (s *fakeUserService) GetUser(
+	_ context.Context,
+	req *connect.Request[userv2.GetUserRequest],
+) (*connect.Response[userv2.GetUserResponse], error) {
+	if req.Msg.Id != 1 {
+		return nil, connectx.ToConnectError(handler.ErrCodeNotFound.New("user not found"))
+	}
+
+	firstName := "John"
+
+	return connect.NewResponse(&userv2.GetUserResponse{User: &userv2.User{
+		Id:         1,
+		Email:      "john@example.com",
+		FirstName:  &firstName,
+		Role:       userv2.Role_ROLE_ADMIN,
+		CreateTime: timestamppb.New(createdAt),
+	}}), nil
+}
+
+func runUsersCmd(t *testing.T, svc *fakeUserService, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(userv2connect.NewUserServiceHandler(svc))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	var stdout bytes.Buffer
+	cmd := NewUsersCmd()
+	cmd.SetArgs(append(args, "--server", srv.URL))
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+
+	return stdout.String(), err
+}
+
+func TestUsersCmd_Get(t *testing.T) {
+	out, err := runUsersCmd(t, &fakeUserService{}, "", "get", "1")
+	require.NoError(t, err)
+	assert.Equal(t,
+		"ID  EMAIL             FIRST NAME  LAST NAME  ROLE   CREATED AT            UPDATED AT\n"+
+			"1   john@example.com  John        -          admin  2026-10-18T12:00:00Z  -\n",
+		out)
+
+	out, err = runUsersCmd(t, &fakeUserService{}, "", "get", "1", "--output", "json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": 1,
+		"email": "john@example.com",
+		"firstName": "John",
+		"role": "admin",
+		"createdAt": "2026-10-18T12:00:00Z"
+	}`, out)
+
+	out, err = runUsersCmd(t, &fakeUserService{}, "", "get", "1", "-o", "yaml")
+	require.NoError(t, err)
+	assert.Contains(t, out, "email: john@example.com\n")
+}
+
+func TestUsersCmd_GetNotFound(t *testing.T) {
+	_, err := runUsersCmd(t, &fakeUserService{}, "", "get", "2")
+	require.Error(t, err)
+	assert.Equal(t, 12, clierr.ExitCode(err))
+}
+
+func TestUsersCmd_Create(t *testing.T) {
+	svc := &fakeUserService{}
+	out, err := runUsersCmd(t, svc, "password123\n",
+		"create", "--email", "jane@example.com", "--role", "admin", "-o", "json")
+	require.NoError(t, err)
+
+	assert.Equal(t, "password123", svc.created.GetPassword())
+	assert.Equal(t, userv2.Role_ROLE_ADMIN, svc.created.GetRole())
+	assert.Contains(t, out, `"email": "jane@example.com"`)
+}
+
+func TestUsersCmd_InvalidFlags(t *testing.T) {
+	_, err := runUsersCmd(t, &fakeUserService{}, "", "get", "1", "--protocol", "soap")
+	require.ErrorContains(t, err, `invalid --protocol "soap"`)
+
+	_, err = runUsersCmd(t, &fakeUserService{}, "", "get", "1", "-o", "xml")
+	require.ErrorContains(t, err, `invalid --output "xml"`)
+
+	_, err = runUsersCmd(t, &fakeUserService{}, "", "update", "1")
+	require.ErrorContains(t, err, "nothing to update")
+}
+
+func TestDefaultServer(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  config.ServerConfig
+		want string
+	}{
+		{config.ServerConfig{Listen: []string{"tcp://:4224"}}, "http://localhost:4224"},
+		{config.ServerConfig{Listen: []string{"tcp://0.0.0.0:4224"}}, "http://localhost:4224"},
+		{
+			config.ServerConfig{Listen: []string{"unix:///run/cleanstack.sock", "tcp://127.0.0.1:8080"}},
+			"http://127.0.0.1:8080",
+		},
+		{
+			config.ServerConfig{Listen: []string{"tcp://[::1]:4224"}, TLS: config.TLSConfig{Enabled: true}},
+			"https://[::1]:4224",
+		},
+	} {
+		got, err := defaultServer(tc.cfg)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+
+	_, err := defaultServer(config.ServerConfig{Listen: []string{"systemd:"}})
+	require.Error(t, err)
+}
+
+func TestWriteUserList(t *testing.T) {
+	var out bytes.Buffer
+	users := []*entity.User{{ID: 1, Email: "john@example.com", Role: entity.RoleUser, CreatedAt: createdAt}}
+
+	require.NoError(t, writeUserList(&out, outputYAML, users, 12))
+	assert.Equal(t, "users:\n"+
+		"  - id: 1\n"+
+		"    email: john@example.com\n"+
+		"    role: user\n"+
+		"    createdAt: 2026-10-18T12:00:00Z\n"+
+		"total: 12\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeUserList(&out, outputTable, users, 12))
+	assert.True(t, strings.HasSuffix(out.String(), "\n1 of 12 users\n"))
+}
@@ -0,0 +1,51 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appConfig "github.com/pivaldi/go-cleanstack/internal/app/user/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/admin"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// examplesDir holds the configuration examples shipped with the repository.
+const examplesDir = "../../../../configs"
+
+// TestExamples loads every configuration example, as the file of its
+// environment, and parses its addresses as serve does.
+func TestExamples(t *testing.T) {
+	examples, err := filepath.Glob(filepath.Join(examplesDir, "config_*.toml.example"))
+	require.NoError(t, err)
+	require.NotEmpty(t, examples)
+
+	for _, example := range examples {
+		name := strings.TrimSuffix(filepath.Base(example), ".example")
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(example)
+			require.NoError(t, err)
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+			t.Setenv("APP_ENV", strings.TrimSuffix(strings.TrimPrefix(name, "config_"), ".toml"))
+
+			cfg := &appConfig.Config{}
+			require.NoError(t, config.Load(dir, cfg))
+
+			assert.NotEmpty(t, cfg.Platform.Server.Listen)
+			for _, addr := range cfg.Platform.Server.Listen {
+				_, err := connectx.ParseListenAddress(addr)
+				assert.NoError(t, err)
+			}
+			if cfg.Platform.Admin.Addr != "" {
+				_, err := admin.ParseAddress(cfg.Platform.Admin.Addr)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,18 +3,27 @@ package entity
 import (
 	"errors"
 	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/pivaldi/presence"
 )
 
-const minPasswordLength = 8
+const (
+	minPasswordLength = 8
+	bcryptHashLength  = 60
+)
+
+// passwordHashPrefixes lists the modular crypt prefixes accepted as pre-hashed
+// passwords (bcrypt and argon2 variants).
+var passwordHashPrefixes = []string{"$2a$", "$2b$", "$2y$", "$argon2id$", "$argon2i$", "$argon2d$"}
 
 var (
 	ErrEmailRequired    = errors.New("email is required")
 	ErrEmailInvalid     = errors.New("email format is invalid")
 	ErrPasswordRequired = errors.New("password is required")
 	ErrPasswordTooShort = errors.New("password must be at least 8 characters")
+	ErrPasswordHash     = errors.New("password hash must be a bcrypt or argon2 hash")
 	ErrRoleInvalid      = errors.New("role is invalid")
 )
 
@@ -28,6 +37,10 @@ type User struct {
 	CreatedAt time.Time              `db:"created_at"`
 	UpdatedAt presence.Of[time.Time] `db:"updated_at"`
 	DeletedAt presence.Of[time.Time] `db:"deleted_at"` // soft delete
+
+	// PasswordHashed is true when Password already holds a bcrypt or argon2
+	// hash (e.g. users migrated from another system) and must be stored as is.
+	PasswordHashed bool `db:"-"`
 }
 
 // NewUser creates a new User with required fields.
@@ -54,7 +67,11 @@ func (u *User) Validate() error {
 		return ErrPasswordRequired
 	}
 
-	if len(u.Password) < minPasswordLength {
+	if u.PasswordHashed {
+		if !IsPasswordHash(u.Password) {
+			return ErrPasswordHash
+		}
+	} else if len(u.Password) < minPasswordLength {
 		return ErrPasswordTooShort
 	}
 
@@ -65,6 +82,30 @@ func (u *User) Validate() error {
 	return nil
 }
 
+// SetPasswordHash sets an already hashed password (bcrypt or argon2).
+func (u *User) SetPasswordHash(hash string) {
+	u.Password = hash
+	u.PasswordHashed = true
+}
+
+// IsPasswordHash reports whether s looks like a bcrypt or argon2 hash
+// in modular crypt format.
+func IsPasswordHash(s string) bool {
+	for _, prefix := range passwordHashPrefixes {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+
+		if strings.HasPrefix(prefix, "$2") {
+			return len(s) == bcryptHashLength
+		}
+
+		return true
+	}
+
+	return false
+}
+
 // SetFirstName sets the first name.
 func (u *User) SetFirstName(name string) {
 	u.FirstName = presence.FromValue(name)
@@ -60,6 +60,21 @@ func TestUser_Validate(t *testing.T) {
 			user:    NewUser("test@example.com", "password123", Role("invalid")),
 			wantErr: ErrRoleInvalid,
 		},
+		{
+			name:    "valid bcrypt hash",
+			user:    newHashedUser("$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"),
+			wantErr: nil,
+		},
+		{
+			name:    "valid argon2id hash",
+			user:    newHashedUser("$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$aGFzaGhhc2hoYXNo"),
+			wantErr: nil,
+		},
+		{
+			name:    "plaintext given as hash",
+			user:    newHashedUser("password123"),
+			wantErr: ErrPasswordHash,
+		},
 	}
 
 	for _, tt := range tests {
@@ -74,6 +89,44 @@ func TestUser_Validate(t *testing.T) {
 	}
 }
 
+func newHashedUser(hash string) *User {
+	user := NewUser("test@example.com", "", RoleUser)
+	user.SetPasswordHash(hash)
+
+	return user
+}
+
+func TestIsPasswordHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"bcrypt 2a", "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", true},
+		{"bcrypt 2b", "$2b$12$KIXQJ8sL0Q4b7b3u5Y1y2O0rO3M9o0jz8Xw1Jc6Y3vVf2m8lq1P2a", true},
+		{"truncated bcrypt", "$2a$10$N9qo8uLOickgx2ZMRZoMye", false},
+		{"argon2id", "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$aGFzaGhhc2hoYXNo", true},
+		{"plaintext", "password123", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPasswordHash(tt.input))
+		})
+	}
+}
+
+func TestUser_SetPasswordHash(t *testing.T) {
+	user := NewUser("test@example.com", "", RoleUser)
+	assert.False(t, user.PasswordHashed)
+
+	user.SetPasswordHash("$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$aGFzaGhhc2hoYXNo")
+
+	assert.True(t, user.PasswordHashed)
+	assert.Equal(t, "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHQ$aGFzaGhhc2hoYXNo", user.Password)
+}
+
 func TestUser_SetFirstName(t *testing.T) {
 	user := NewUser("test@example.com", "password123", RoleUser)
 	assert.False(t, user.FirstName.IsSet())
@@ -0,0 +1,28 @@
+package ports
+
+import "github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+
+//go:generate go-enum --marshal --names --values
+
+// ConflictPolicy tells a batch insert what to do with a user whose email already exists.
+// ENUM(fail, skip, update)
+type ConflictPolicy string
+
+// BatchOutcome is the outcome of a single user of a batch insert.
+type BatchOutcome string
+
+const (
+	BatchCreated  BatchOutcome = "created"
+	BatchUpdated  BatchOutcome = "updated"
+	BatchSkipped  BatchOutcome = "skipped"
+	BatchConflict BatchOutcome = "conflict"
+	BatchFailed   BatchOutcome = "failed"
+)
+
+// BatchResult reports what happened to the user at the same index of a batch.
+// User is the stored user for created and updated outcomes, Err is set for failed ones.
+type BatchResult struct {
+	Outcome BatchOutcome
+	User    *entity.User
+	Err     error
+}
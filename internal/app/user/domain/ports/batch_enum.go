@@ -0,0 +1,93 @@
+// Code generated by go-enum DO NOT EDIT.
+// Version: v0.9.2
+
+// Built By: go install
+
+package ports
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ConflictPolicyFail is a ConflictPolicy of type fail.
+	ConflictPolicyFail ConflictPolicy = "fail"
+	// ConflictPolicySkip is a ConflictPolicy of type skip.
+	ConflictPolicySkip ConflictPolicy = "skip"
+	// ConflictPolicyUpdate is a ConflictPolicy of type update.
+	ConflictPolicyUpdate ConflictPolicy = "update"
+)
+
+var ErrInvalidConflictPolicy = fmt.Errorf("not a valid ConflictPolicy, try [%s]", strings.Join(_ConflictPolicyNames, ", "))
+
+var _ConflictPolicyNames = []string{
+	string(ConflictPolicyFail),
+	string(ConflictPolicySkip),
+	string(ConflictPolicyUpdate),
+}
+
+// ConflictPolicyNames returns a list of possible string values of ConflictPolicy.
+func ConflictPolicyNames() []string {
+	tmp := make([]string, len(_ConflictPolicyNames))
+	copy(tmp, _ConflictPolicyNames)
+	return tmp
+}
+
+// ConflictPolicyValues returns a list of the values for ConflictPolicy
+func ConflictPolicyValues() []ConflictPolicy {
+	return []ConflictPolicy{
+		ConflictPolicyFail,
+		ConflictPolicySkip,
+		ConflictPolicyUpdate,
+	}
+}
+
+// String implements the Stringer interface.
+func (x ConflictPolicy) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x ConflictPolicy) IsValid() bool {
+	_, err := ParseConflictPolicy(string(x))
+	return err == nil
+}
+
+var _ConflictPolicyValue = map[string]ConflictPolicy{
+	"fail":   ConflictPolicyFail,
+	"skip":   ConflictPolicySkip,
+	"update": ConflictPolicyUpdate,
+}
+
+// ParseConflictPolicy attempts to convert a string to a ConflictPolicy.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	if x, ok := _ConflictPolicyValue[name]; ok {
+		return x, nil
+	}
+	return ConflictPolicy(""), fmt.Errorf("%s is %w", name, ErrInvalidConflictPolicy)
+}
+
+// MarshalText implements the text marshaller method.
+func (x ConflictPolicy) MarshalText() ([]byte, error) {
+	return []byte(string(x)), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *ConflictPolicy) UnmarshalText(text []byte) error {
+	tmp, err := ParseConflictPolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
+
+// AppendText appends the textual representation of itself to the end of b
+// (allocating a larger slice if necessary) and returns the updated slice.
+//
+// Implementations must not retain b, nor mutate any bytes within b[:len(b)].
+func (x *ConflictPolicy) AppendText(b []byte) ([]byte, error) {
+	return append(b, x.String()...), nil
+}
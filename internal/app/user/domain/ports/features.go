@@ -0,0 +1,7 @@
+package ports
+
+// FeatureFlags tells whether the feature flags are enabled. It is asked at
+// each use, for the flags reloaded without restart to be seen.
+type FeatureFlags interface {
+	Enabled(name string) bool
+}
@@ -0,0 +1,10 @@
+package ports
+
+import "github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+
+// UserFilter narrows a user search. Zero fields do not filter.
+type UserFilter struct {
+	Role  entity.Role // exact role
+	Email string      // case-insensitive substring of the email
+	Name  string      // case-insensitive substring of the first or last name
+}
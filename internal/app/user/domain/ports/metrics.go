@@ -0,0 +1,10 @@
+package ports
+
+import "github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+
+// UserMetrics counts the changes of the users.
+type UserMetrics interface {
+	UserCreated(role entity.Role)
+	UserUpdated()
+	UserDeleted()
+}
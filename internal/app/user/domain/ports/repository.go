@@ -14,6 +14,13 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id int64) (*entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	List(ctx context.Context, offset, limit int) ([]*entity.User, int64, error)
+	// Search is List restricted to the users matching filter.
+	Search(ctx context.Context, filter UserFilter, offset, limit int) ([]*entity.User, int64, error)
+	// GetByIDs returns the existing users among ids, in no particular order.
+	GetByIDs(ctx context.Context, ids []int64) ([]*entity.User, error)
 	Update(ctx context.Context, user *entity.User) (*entity.User, error)
 	Delete(ctx context.Context, id int64) error
+	// CreateBatch inserts users in a single transaction, resolving email
+	// conflicts according to policy. Results are returned in input order.
+	CreateBatch(ctx context.Context, users []*entity.User, policy ConflictPolicy) ([]BatchResult, error)
 }
@@ -1,10 +1,15 @@
 package persistence
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const (
@@ -12,6 +17,9 @@ const (
 	maxIdleConns = 5
 )
 
+// pgQueryCanceled is the SQLSTATE of statements stopped by statement_timeout or a cancel request.
+const pgQueryCanceled = "57014"
+
 func NewDB(databaseURL string) (*sqlx.DB, error) {
 	db, err := sqlx.Connect("postgres", databaseURL)
 	if err != nil {
@@ -23,3 +31,89 @@ func NewDB(databaseURL string) (*sqlx.DB, error) {
 
 	return db, nil
 }
+
+// querier is the part of *sqlx.DB and *sqlx.Tx used by the repositories.
+type querier interface {
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// withStatementTimeout runs fn in a transaction whose statements are limited
+// to the time left before the deadline of ctx, so that the queries of a call
+// stop in the database when the caller gives up. Without deadline, fn runs
+// on db directly.
+// The limit is enforced by the database itself, at the cost of three round
+// trips (BEGIN, SET LOCAL and COMMIT): single-statement reads use
+// withCancelRequest instead.
+// Canceled statements are reported as context.DeadlineExceeded or
+// context.Canceled errors.
+func withStatementTimeout(ctx context.Context, db *sqlx.DB, fn func(q querier) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return canceledStatementError(ctx, fn(db))
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := setStatementTimeout(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return canceledStatementError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// withCancelRequest runs the single read statement of fn on db, in one round
+// trip. When ctx is done, lib/pq stops the statement with a cancel request
+// over another connection, which is best effort: a statement whose cancel
+// request fails runs to completion, unlike with withStatementTimeout. This is
+// acceptable for the reads by key, which are short and lock nothing.
+// Canceled statements are reported as context.DeadlineExceeded or
+// context.Canceled errors.
+func withCancelRequest(ctx context.Context, db *sqlx.DB, fn func(q querier) error) error {
+	return canceledStatementError(ctx, fn(db))
+}
+
+// setStatementTimeout sets the statement_timeout of tx to the time left before
+// the deadline of ctx, when it has one.
+func setStatementTimeout(ctx context.Context, tx *sqlx.Tx) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	// statement_timeout = 0 disables the limit, an expired deadline keeps the smallest one.
+	ms := max(time.Until(deadline).Milliseconds(), 1)
+
+	// SET takes no parameters, ms is an integer.
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = "+strconv.FormatInt(ms, 10)); err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	return nil
+}
+
+// canceledStatementError wraps the errors of canceled statements with the
+// matching context error.
+func canceledStatementError(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pgQueryCanceled {
+		return err
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return fmt.Errorf("%w: %w", context.Canceled, err)
+	}
+
+	return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+}
@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence/migrations"
+)
+
+var errPgcryptoMissing = errors.New("the pgcrypto extension is not installed")
+
+// PgcryptoCheck returns a check failing when the pgcrypto extension, used by
+// the users table, is not installed.
+func PgcryptoCheck(db *sqlx.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var installed bool
+		if err := db.GetContext(ctx, &installed,
+			"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pgcrypto')"); err != nil {
+			return fmt.Errorf("failed to look up pgcrypto: %w", err)
+		}
+		if !installed {
+			return errPgcryptoMissing
+		}
+
+		return nil
+	}
+}
+
+// MigrationsCheck returns a check failing when the goose version of the
+// database is not the latest embedded migration.
+func MigrationsCheck(db *sqlx.DB) (func(ctx context.Context) error, error) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db.DB, migrations.FS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	var latest int64
+	for _, source := range provider.ListSources() {
+		latest = max(latest, source.Version)
+	}
+
+	return func(ctx context.Context) error {
+		version, err := provider.GetDBVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get database version: %w", err)
+		}
+		if version != latest {
+			return fmt.Errorf("database version is %d, want %d", version, latest)
+		}
+
+		return nil
+	}, nil
+}
@@ -0,0 +1,156 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrIdempotencyLeaseLost is returned when a reservation expired and the key
+// was reserved again, or purged, before the call holding it was done.
+var ErrIdempotencyLeaseLost = errors.New("idempotency key lease lost")
+
+// IdempotencyKey is a row of the idempotency_keys table.
+type IdempotencyKey struct {
+	Client      string       `db:"client"`
+	Key         string       `db:"key"`
+	Procedure   string       `db:"procedure"`
+	Reservation string       `db:"reservation"`
+	Fingerprint []byte       `db:"fingerprint"`
+	Response    []byte       `db:"response"`
+	CreatedAt   time.Time    `db:"created_at"`
+	CompletedAt sql.NullTime `db:"completed_at"`
+	ExpiresAt   time.Time    `db:"expires_at"`
+}
+
+// IdempotencyRepo stores the idempotency keys of the mutating procedures.
+type IdempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepo(db *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Reserve inserts the key for lease under the reservation token, replacing an
+// expired one, and returns nil. When a live row already exists for client, key
+// and procedure, it is returned instead.
+func (r *IdempotencyRepo) Reserve(
+	ctx context.Context,
+	client, key, procedure, reservation string,
+	fingerprint []byte,
+	lease time.Duration,
+) (*IdempotencyKey, error) {
+	query := `
+		INSERT INTO idempotency_keys (client, key, procedure, reservation, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW() + make_interval(secs => $6))
+		ON CONFLICT (client, key, procedure) DO UPDATE
+		SET reservation = EXCLUDED.reservation, fingerprint = EXCLUDED.fingerprint, response = NULL,
+			created_at = EXCLUDED.created_at, completed_at = NULL, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query, client, key, procedure, reservation, fingerprint, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute insert query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 1 {
+		return nil, nil
+	}
+
+	query = `
+		SELECT client, key, procedure, reservation, fingerprint, response, created_at, completed_at, expires_at
+		FROM idempotency_keys
+		WHERE client = $1 AND key = $2 AND procedure = $3
+	`
+
+	var existing IdempotencyKey
+	err = r.db.GetContext(ctx, &existing, query, client, key, procedure)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between both queries.
+		return r.Reserve(ctx, client, key, procedure, reservation, fingerprint, lease)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute select query: %w", err)
+	}
+
+	return &existing, nil
+}
+
+// Complete stores the response of the key reserved by reservation, and keeps
+// it for ttl. It fails with ErrIdempotencyLeaseLost when the key is no longer
+// held by reservation.
+func (r *IdempotencyRepo) Complete(
+	ctx context.Context,
+	client, key, procedure, reservation string,
+	response []byte,
+	ttl time.Duration,
+) error {
+	query := `
+		UPDATE idempotency_keys
+		SET response = $5, completed_at = NOW(), expires_at = NOW() + make_interval(secs => $6)
+		WHERE client = $1 AND key = $2 AND procedure = $3 AND reservation = $4 AND completed_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, client, key, procedure, reservation, response, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to execute update query: %w", err)
+	}
+
+	return leaseRowAffected(result)
+}
+
+// Delete removes the key reserved by reservation, not completed. It fails with
+// ErrIdempotencyLeaseLost when the key is no longer held by reservation.
+func (r *IdempotencyRepo) Delete(ctx context.Context, client, key, procedure, reservation string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE client = $1 AND key = $2 AND procedure = $3 AND reservation = $4 AND completed_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, client, key, procedure, reservation)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete query: %w", err)
+	}
+
+	return leaseRowAffected(result)
+}
+
+// leaseRowAffected fails with ErrIdempotencyLeaseLost when result affected no row.
+func leaseRowAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrIdempotencyLeaseLost
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the expired keys and returns how many were removed.
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute delete query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
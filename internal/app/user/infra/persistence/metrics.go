@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+)
+
+// Outcomes of the queries in the metrics.
+const (
+	queryOK     = "ok"
+	queryFailed = "error"
+)
+
+// NewQueryMetrics returns an observer exposing the durations of the queries of
+// repository in the db_query_duration_seconds histogram, by repository, query
+// and outcome. The repositories share the histogram.
+func NewQueryMetrics(reg *metrics.Registry, repository string) (QueryObserver, error) {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of the database queries, by repository, query and outcome.",
+		Buckets: reg.Buckets(),
+	}, []string{"repository", "query", "outcome"})
+
+	if err := reg.Register(duration); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			return nil, fmt.Errorf("failed to register query metrics: %w", err)
+		}
+		existing, ok := registered.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register query metrics: %w", err)
+		}
+		duration = existing
+	}
+
+	return func(query string, d time.Duration, failed bool) {
+		outcome := queryOK
+		if failed {
+			outcome = queryFailed
+		}
+		duration.WithLabelValues(repository, query, outcome).Observe(d.Seconds())
+	}, nil
+}
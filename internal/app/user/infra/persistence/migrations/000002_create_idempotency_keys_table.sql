@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE idempotency_keys (
+    client       VARCHAR(512) NOT NULL,
+    key          VARCHAR(255) NOT NULL,
+    procedure    VARCHAR(255) NOT NULL,
+    reservation  VARCHAR(64) NOT NULL,
+    fingerprint  BYTEA NOT NULL,
+    response     BYTEA,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    expires_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (client, key, procedure)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
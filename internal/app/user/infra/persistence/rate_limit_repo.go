@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitRepo stores token buckets, shared by the replicas of the server.
+type RateLimitRepo struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitRepo(db *sqlx.DB) *RateLimitRepo {
+	return &RateLimitRepo{db: db}
+}
+
+// Take refills the bucket of key, created with burst tokens, at rate tokens
+// per second up to burst, then takes a token when one is available.
+// It returns the tokens available before the take.
+// Both statements run in a transaction holding the lock of the bucket row, so
+// that DeleteFull, which sees a new bucket as full, cannot remove it in between.
+func (r *RateLimitRepo) Take(ctx context.Context, key string, burst, rate float64) (float64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The no-op update of an existing bucket locks its row until the commit.
+	query := `
+		INSERT INTO rate_limit_buckets (key, tokens, burst, rate, updated_at)
+		VALUES ($1, $2::float8, $2::float8, $3::float8, NOW())
+		ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+	`
+
+	if _, err := tx.ExecContext(ctx, query, key, burst, rate); err != nil {
+		return 0, fmt.Errorf("failed to execute insert query: %w", err)
+	}
+
+	// The bucket is refilled up to the time of the take, once the lock is held:
+	// NOW(), the start of the transaction, may precede the take it waited for.
+	query = `
+		UPDATE rate_limit_buckets b
+		SET tokens = CASE WHEN s.refilled >= 1 THEN s.refilled - 1 ELSE s.refilled END,
+			burst = $2::float8, rate = $3::float8, updated_at = s.taken_at
+		FROM (
+			SELECT key, taken_at,
+				LEAST($2::float8, tokens + EXTRACT(EPOCH FROM taken_at - updated_at)::float8 * $3::float8) AS refilled
+			FROM (
+				SELECT key, tokens, updated_at, clock_timestamp()::timestamp AS taken_at
+				FROM rate_limit_buckets
+				WHERE key = $1
+			) c
+		) s
+		WHERE b.key = s.key
+		RETURNING s.refilled
+	`
+
+	var tokens float64
+	if err := tx.GetContext(ctx, &tokens, query, key, burst, rate); err != nil {
+		return 0, fmt.Errorf("failed to execute update query: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteFull removes the buckets refilled up to their burst, which are the
+// same as new ones, and returns how many were removed.
+func (r *RateLimitRepo) DeleteFull(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM rate_limit_buckets
+		WHERE tokens + EXTRACT(EPOCH FROM NOW() - updated_at)::float8 * rate >= burst
+	`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute delete query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
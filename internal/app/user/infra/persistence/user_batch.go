@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OnConflict selects how CreateBatch handles an email that already exists.
+type OnConflict string
+
+const (
+	OnConflictNothing OnConflict = "nothing" // leave the existing row untouched
+	OnConflictUpdate  OnConflict = "update"  // overwrite the existing row
+)
+
+// BatchStatus is the status of a single row of a batch insert.
+type BatchStatus string
+
+const (
+	BatchInserted BatchStatus = "inserted"
+	BatchUpdated  BatchStatus = "updated"
+	BatchConflict BatchStatus = "conflict"
+	BatchError    BatchStatus = "error"
+)
+
+// BatchRow is the result of inserting one user of a batch.
+type BatchRow struct {
+	Status BatchStatus
+	User   *User
+	Err    error
+}
+
+const batchInsertQuery = `
+	INSERT INTO users (email, password, first_name, last_name, role, created_at)
+	VALUES ($1, CASE WHEN $6 THEN $2 ELSE crypt($2, gen_salt('bf')) END, $3, $4, $5, NOW())
+`
+
+const batchReturning = `
+	RETURNING id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at,
+		(xmax = 0) AS inserted
+`
+
+var batchConflictClauses = map[OnConflict]string{
+	OnConflictNothing: `ON CONFLICT (email) DO NOTHING`,
+	// Soft-deleted users are not resurrected: they are reported as conflicts.
+	OnConflictUpdate: `
+	ON CONFLICT (email) DO UPDATE SET
+		password = EXCLUDED.password,
+		first_name = EXCLUDED.first_name,
+		last_name = EXCLUDED.last_name,
+		role = EXCLUDED.role,
+		updated_at = NOW()
+	WHERE users.deleted_at IS NULL`,
+}
+
+type batchResult struct {
+	User
+	Inserted bool `db:"inserted"`
+}
+
+// CreateBatch inserts users in one transaction. Each row runs inside its own
+// savepoint so that a failing row does not abort the rest of the batch.
+func (r *UserRepo) CreateBatch(ctx context.Context, users []*User, onConflict OnConflict) (_ []BatchRow, err error) {
+	ctx, end := r.startQuery(ctx, "create_batch")
+	defer end(&err)
+
+	conflictClause, ok := batchConflictClauses[onConflict]
+	if !ok {
+		return nil, fmt.Errorf("unsupported conflict action %q", onConflict)
+	}
+	query := batchInsertQuery + conflictClause + batchReturning
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin batch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := setStatementTimeout(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	rows := make([]BatchRow, len(users))
+	for i, user := range users {
+		rows[i], err = insertBatchRow(ctx, tx, query, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch transaction: %w", err)
+	}
+
+	return rows, nil
+}
+
+// insertBatchRow inserts a single user. Row-level failures are reported in the
+// returned BatchRow; the error is only set when the transaction itself is broken.
+func insertBatchRow(ctx context.Context, tx *sqlx.Tx, query string, user *User) (BatchRow, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_row"); err != nil {
+		return BatchRow{}, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	var result batchResult
+	err := tx.GetContext(ctx, &result, query,
+		user.Email,
+		user.Password,
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		user.PasswordHashed,
+	)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return BatchRow{Status: BatchConflict}, releaseBatchRow(ctx, tx)
+	case err != nil:
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_row"); rbErr != nil {
+			return BatchRow{}, fmt.Errorf("failed to rollback to savepoint: %w", rbErr)
+		}
+
+		return BatchRow{Status: BatchError, Err: fmt.Errorf("failed to execute insert query: %w", err)}, nil
+	}
+
+	status := BatchUpdated
+	if result.Inserted {
+		status = BatchInserted
+	}
+
+	return BatchRow{Status: status, User: &result.User}, releaseBatchRow(ctx, tx)
+}
+
+func releaseBatchRow(ctx context.Context, tx *sqlx.Tx) error {
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_row"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
@@ -9,20 +9,77 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pivaldi/presence"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrUserNotFound = errors.New("user not found")
 
+var tracer = otel.Tracer("github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence")
+
+// QueryObserver is notified of the duration of every query of a repository,
+// named after its method, failed when it returned an unexpected error.
+type QueryObserver func(query string, duration time.Duration, failed bool)
+
+// UserRepoOption configures a UserRepo.
+type UserRepoOption func(*UserRepo)
+
+// WithQueryObserver sets the observer of the queries of the repository.
+func WithQueryObserver(observer QueryObserver) UserRepoOption {
+	return func(r *UserRepo) {
+		r.observer = observer
+	}
+}
+
 // UserRepo is the infrastructure implementation.
 type UserRepo struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	observer QueryObserver
+}
+
+func NewUserRepo(db *sqlx.DB, opts ...UserRepoOption) *UserRepo {
+	r := &UserRepo{db: db}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
-func NewUserRepo(db *sqlx.DB) *UserRepo {
-	return &UserRepo{db: db}
+// startQuery starts the span of the query of a repository method, returning
+// its context and a function ending it, which must be deferred with the error
+// of the method. The query is reported to the observer, if any; a missing
+// user is not a failure.
+func (r *UserRepo) startQuery(ctx context.Context, query string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "UserRepo."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", query),
+		),
+	)
+
+	return ctx, func(err *error) {
+		failed := *err != nil && !errors.Is(*err, ErrUserNotFound)
+		if failed {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+
+		if r.observer != nil {
+			r.observer(query, time.Since(start), failed)
+		}
+	}
 }
 
-func (r *UserRepo) Create(ctx context.Context, user *User) (*User, error) {
+func (r *UserRepo) Create(ctx context.Context, user *User) (_ *User, err error) {
+	ctx, end := r.startQuery(ctx, "create")
+	defer end(&err)
+
 	query := `
 		INSERT INTO users (email, password, first_name, last_name, role, created_at)
 		VALUES ($1, crypt($2, gen_salt('bf')), $3, $4, $5, NOW())
@@ -30,13 +87,15 @@ func (r *UserRepo) Create(ctx context.Context, user *User) (*User, error) {
 	`
 
 	var result User
-	err := r.db.GetContext(ctx, &result, query,
-		user.Email,
-		user.Password,
-		user.FirstName,
-		user.LastName,
-		user.Role,
-	)
+	err = withStatementTimeout(ctx, r.db, func(q querier) error {
+		return q.GetContext(ctx, &result, query,
+			user.Email,
+			user.Password,
+			user.FirstName,
+			user.LastName,
+			user.Role,
+		)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute insert query: %w", err)
 	}
@@ -44,7 +103,10 @@ func (r *UserRepo) Create(ctx context.Context, user *User) (*User, error) {
 	return &result, nil
 }
 
-func (r *UserRepo) GetByID(ctx context.Context, id int64) (*User, error) {
+func (r *UserRepo) GetByID(ctx context.Context, id int64) (_ *User, err error) {
+	ctx, end := r.startQuery(ctx, "get_by_id")
+	defer end(&err)
+
 	query := `
 		SELECT id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at
 		FROM users
@@ -52,7 +114,9 @@ func (r *UserRepo) GetByID(ctx context.Context, id int64) (*User, error) {
 	`
 
 	var user User
-	err := r.db.GetContext(ctx, &user, query, id)
+	err = withCancelRequest(ctx, r.db, func(q querier) error {
+		return q.GetContext(ctx, &user, query, id)
+	})
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
@@ -63,7 +127,10 @@ func (r *UserRepo) GetByID(ctx context.Context, id int64) (*User, error) {
 	return &user, nil
 }
 
-func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*User, error) {
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, end := r.startQuery(ctx, "get_by_email")
+	defer end(&err)
+
 	query := `
 		SELECT id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at
 		FROM users
@@ -71,7 +138,9 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*User, error)
 	`
 
 	var user User
-	err := r.db.GetContext(ctx, &user, query, email)
+	err = withCancelRequest(ctx, r.db, func(q querier) error {
+		return q.GetContext(ctx, &user, query, email)
+	})
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
@@ -82,15 +151,11 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*User, error)
 	return &user, nil
 }
 
-func (r *UserRepo) List(ctx context.Context, offset, limit int) ([]*User, int64, error) {
-	// Get total count
-	countQuery := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
-	var total int64
-	if err := r.db.GetContext(ctx, &total, countQuery); err != nil {
-		return nil, 0, fmt.Errorf("failed to count users: %w", err)
-	}
+func (r *UserRepo) List(ctx context.Context, offset, limit int) (_ []*User, _ int64, err error) {
+	ctx, end := r.startQuery(ctx, "list")
+	defer end(&err)
 
-	// Get paginated results
+	countQuery := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 	query := `
 		SELECT id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at
 		FROM users
@@ -99,9 +164,23 @@ func (r *UserRepo) List(ctx context.Context, offset, limit int) ([]*User, int64,
 		LIMIT $1 OFFSET $2
 	`
 
+	var total int64
 	var users []User
-	if err := r.db.SelectContext(ctx, &users, query, limit, offset); err != nil {
-		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	err = withStatementTimeout(ctx, r.db, func(q querier) error {
+		// Get total count
+		if err := q.GetContext(ctx, &total, countQuery); err != nil {
+			return fmt.Errorf("failed to count users: %w", err)
+		}
+
+		// Get paginated results
+		if err := q.SelectContext(ctx, &users, query, limit, offset); err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
 	result := make([]*User, len(users))
@@ -112,7 +191,10 @@ func (r *UserRepo) List(ctx context.Context, offset, limit int) ([]*User, int64,
 	return result, total, nil
 }
 
-func (r *UserRepo) Update(ctx context.Context, user *User) (*User, error) {
+func (r *UserRepo) Update(ctx context.Context, user *User) (_ *User, err error) {
+	ctx, end := r.startQuery(ctx, "update")
+	defer end(&err)
+
 	query := `
 		UPDATE users SET
 			email = COALESCE(NULLIF($2, ''), email),
@@ -126,14 +208,16 @@ func (r *UserRepo) Update(ctx context.Context, user *User) (*User, error) {
 	`
 
 	var result User
-	err := r.db.GetContext(ctx, &result, query,
-		user.ID,
-		user.Email,
-		user.Password,
-		user.FirstName,
-		user.LastName,
-		user.Role,
-	)
+	err = withStatementTimeout(ctx, r.db, func(q querier) error {
+		return q.GetContext(ctx, &result, query,
+			user.ID,
+			user.Email,
+			user.Password,
+			user.FirstName,
+			user.LastName,
+			user.Role,
+		)
+	})
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
 	}
@@ -144,17 +228,27 @@ func (r *UserRepo) Update(ctx context.Context, user *User) (*User, error) {
 	return &result, nil
 }
 
-func (r *UserRepo) Delete(ctx context.Context, id int64) error {
+func (r *UserRepo) Delete(ctx context.Context, id int64) (err error) {
+	ctx, end := r.startQuery(ctx, "delete")
+	defer end(&err)
+
 	query := `UPDATE users SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query, id, presence.FromValue(time.Now()))
-	if err != nil {
-		return fmt.Errorf("failed to execute soft delete: %w", err)
-	}
+	var rows int64
+	err = withStatementTimeout(ctx, r.db, func(q querier) error {
+		result, err := q.ExecContext(ctx, query, id, presence.FromValue(time.Now()))
+		if err != nil {
+			return fmt.Errorf("failed to execute soft delete: %w", err)
+		}
+
+		if rows, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
 
-	rows, err := result.RowsAffected()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
 
 	if rows == 0 {
@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// SearchFilter narrows Search. Empty fields do not filter.
+type SearchFilter struct {
+	Role  string
+	Email string
+	Name  string
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// where returns the WHERE clause of the filter and its arguments.
+func (f SearchFilter) where() (string, []any) {
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+
+	param := func(v any) string {
+		args = append(args, v)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.Role != "" {
+		conds = append(conds, "role = "+param(f.Role))
+	}
+	if f.Email != "" {
+		conds = append(conds, "email ILIKE "+param("%"+likeEscaper.Replace(f.Email)+"%"))
+	}
+	if f.Name != "" {
+		p := param("%" + likeEscaper.Replace(f.Name) + "%")
+		conds = append(conds, "(first_name ILIKE "+p+" OR last_name ILIKE "+p+")")
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+func (r *UserRepo) Search(ctx context.Context, filter SearchFilter, offset, limit int) (_ []*User, _ int64, err error) {
+	ctx, end := r.startQuery(ctx, "search")
+	defer end(&err)
+
+	where, args := filter.where()
+
+	query := fmt.Sprintf(`
+		SELECT id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at
+		FROM users
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2) //nolint:gosec // where only holds placeholders
+
+	var total int64
+	var users []User
+	err = withStatementTimeout(ctx, r.db, func(q querier) error {
+		if err := q.GetContext(ctx, &total, `SELECT COUNT(*) FROM users WHERE `+where, args...); err != nil {
+			return fmt.Errorf("failed to count users: %w", err)
+		}
+
+		if err := q.SelectContext(ctx, &users, query, append(args, limit, offset)...); err != nil {
+			return fmt.Errorf("failed to search users: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]*User, len(users))
+	for i := range users {
+		result[i] = &users[i]
+	}
+
+	return result, total, nil
+}
+
+func (r *UserRepo) GetByIDs(ctx context.Context, ids []int64) (_ []*User, err error) {
+	ctx, end := r.startQuery(ctx, "get_by_ids")
+	defer end(&err)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, email, password, first_name, last_name, role, created_at, updated_at, deleted_at
+		FROM users
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	var users []User
+	err = withCancelRequest(ctx, r.db, func(q querier) error {
+		return q.SelectContext(ctx, &users, query, pq.Array(ids))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by ids: %w", err)
+	}
+
+	result := make([]*User, len(users))
+	for i := range users {
+		result[i] = &users[i]
+	}
+
+	return result, nil
+}
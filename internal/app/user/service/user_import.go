@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+)
+
+const DefaultImportBatchSize = 500
+
+var (
+	ErrDuplicateImportEmail = errors.New("email appears more than once in the import")
+	ErrImportConflict       = errors.New("a user with this email already exists")
+)
+
+// ImportStatus is the outcome of one imported row.
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	ImportUpdated ImportStatus = "updated"
+	ImportSkipped ImportStatus = "skipped"
+	ImportValid   ImportStatus = "valid" // dry-run only: the row would be imported
+	ImportFailed  ImportStatus = "failed"
+)
+
+// ImportRow is a user read from an import source.
+// Err carries a parse error, in which case User may be nil.
+type ImportRow struct {
+	Line int
+	User *entity.User
+	Err  error
+}
+
+// ImportOptions tunes ImportUsers.
+type ImportOptions struct {
+	OnConflict ports.ConflictPolicy
+	DryRun     bool
+	BatchSize  int
+}
+
+// ImportResult is the per-row report entry of an import.
+type ImportResult struct {
+	Line   int
+	Email  string
+	Status ImportStatus
+	UserID int64
+	Err    error
+}
+
+// ImportReport lists the outcome of every imported row, in input order.
+type ImportReport struct {
+	Results []ImportResult
+}
+
+// Count returns the number of rows with the given status.
+func (r *ImportReport) Count(status ImportStatus) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+// ImportUsers validates every row and inserts the valid ones in batches.
+// Invalid rows never reach the repository. With DryRun, rows are only validated.
+// On a repository error the report holds the rows processed so far.
+func (s *UserService) ImportUsers(
+	ctx context.Context,
+	rows []ImportRow,
+	opts ImportOptions,
+) (_ *ImportReport, err error) {
+	ctx, end := startSpan(ctx, "ImportUsers")
+	defer end(&err)
+
+	if opts.OnConflict == "" {
+		opts.OnConflict = ports.ConflictPolicyFail
+	}
+	if !opts.OnConflict.IsValid() {
+		return nil, fmt.Errorf("invalid conflict policy %q", opts.OnConflict)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultImportBatchSize
+	}
+
+	report := &ImportReport{Results: make([]ImportResult, len(rows))}
+	pending := s.validateImportRows(rows, report)
+
+	s.logger.InfoContext(ctx, "importing users",
+		logging.Int("rows", len(rows)),
+		logging.Int("valid", len(pending)),
+		logging.Bool("dry_run", opts.DryRun),
+		logging.String("on_conflict", opts.OnConflict.String()),
+	)
+
+	if opts.DryRun {
+		for _, i := range pending {
+			report.Results[i].Status = ImportValid
+		}
+
+		return report, nil
+	}
+
+	for start := 0; start < len(pending); start += opts.BatchSize {
+		batch := pending[start:min(start+opts.BatchSize, len(pending))]
+		if err := s.importBatch(ctx, rows, batch, opts.OnConflict, report); err != nil {
+			report.Results = report.Results[:batch[0]]
+
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// validateImportRows fills the report for invalid rows and returns the indexes
+// of the rows to insert.
+func (s *UserService) validateImportRows(rows []ImportRow, report *ImportReport) []int {
+	pending := make([]int, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+
+	for i, row := range rows {
+		res := &report.Results[i]
+		res.Line = row.Line
+
+		if row.Err != nil {
+			res.Status, res.Err = ImportFailed, row.Err
+
+			continue
+		}
+
+		res.Email = row.User.Email
+		if err := row.User.Validate(); err != nil {
+			res.Status, res.Err = ImportFailed, fmt.Errorf("user validation failed: %w", err)
+
+			continue
+		}
+
+		// Compared as the UNIQUE constraint of users.email does: case-sensitively.
+		key := row.User.Email
+		if line, ok := seen[key]; ok {
+			res.Status, res.Err = ImportFailed, fmt.Errorf("%w (first seen on line %d)", ErrDuplicateImportEmail, line)
+
+			continue
+		}
+		seen[key] = row.Line
+
+		pending = append(pending, i)
+	}
+
+	return pending
+}
+
+func (s *UserService) importBatch(
+	ctx context.Context,
+	rows []ImportRow,
+	batch []int,
+	policy ports.ConflictPolicy,
+	report *ImportReport,
+) error {
+	users := make([]*entity.User, len(batch))
+	for j, i := range batch {
+		users[j] = rows[i].User
+	}
+
+	results, err := s.repo.CreateBatch(ctx, users, policy)
+	if err != nil {
+		return fmt.Errorf("failed to import user batch in repository: %w", err)
+	}
+	if len(results) != len(batch) {
+		return fmt.Errorf("repository returned %d results for a batch of %d users", len(results), len(batch))
+	}
+
+	for j, i := range batch {
+		res := &report.Results[i]
+		switch results[j].Outcome {
+		case ports.BatchCreated:
+			res.Status, res.UserID = ImportCreated, results[j].User.ID
+			s.metrics.UserCreated(results[j].User.Role)
+		case ports.BatchUpdated:
+			res.Status, res.UserID = ImportUpdated, results[j].User.ID
+			s.metrics.UserUpdated()
+		case ports.BatchSkipped:
+			res.Status = ImportSkipped
+		case ports.BatchConflict:
+			res.Status, res.Err = ImportFailed, ErrImportConflict
+		default:
+			res.Status, res.Err = ImportFailed, results[j].Err
+		}
+	}
+
+	return nil
+}
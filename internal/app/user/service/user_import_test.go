@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+)
+
+func importRows(users ...*entity.User) []service.ImportRow {
+	rows := make([]service.ImportRow, len(users))
+	for i, user := range users {
+		rows[i] = service.ImportRow{Line: i + 2, User: user}
+	}
+
+	return rows
+}
+
+func TestUserService_ImportUsers(t *testing.T) {
+	t.Run("invalid rows never reach the repository", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		valid := entity.NewUser("ok@example.com", "password123", entity.RoleUser)
+		rows := importRows(
+			valid,
+			entity.NewUser("not-an-email", "password123", entity.RoleUser),
+			entity.NewUser("ok@example.com", "password123", entity.RoleUser),
+		)
+		rows = append(rows, service.ImportRow{Line: 5, Err: errors.New("bad line")})
+
+		mockRepo.On("CreateBatch", mock.Anything, []*entity.User{valid}, ports.ConflictPolicySkip).
+			Return([]ports.BatchResult{{Outcome: ports.BatchCreated, User: &entity.User{ID: 7}}}, nil)
+
+		report, err := svc.ImportUsers(context.Background(), rows, service.ImportOptions{
+			OnConflict: ports.ConflictPolicySkip,
+		})
+
+		require.NoError(t, err)
+		require.Len(t, report.Results, 4)
+		assert.Equal(t, service.ImportCreated, report.Results[0].Status)
+		assert.Equal(t, int64(7), report.Results[0].UserID)
+		assert.ErrorIs(t, report.Results[1].Err, entity.ErrEmailInvalid)
+		assert.ErrorIs(t, report.Results[2].Err, service.ErrDuplicateImportEmail)
+		assert.Equal(t, 5, report.Results[3].Line)
+		assert.Equal(t, 3, report.Count(service.ImportFailed))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("emails differing by case are distinct, as in the database", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		lower := entity.NewUser("ok@example.com", "password123", entity.RoleUser)
+		upper := entity.NewUser("OK@example.com", "password123", entity.RoleUser)
+		mockRepo.On("CreateBatch", mock.Anything, []*entity.User{lower, upper}, ports.ConflictPolicyFail).
+			Return([]ports.BatchResult{
+				{Outcome: ports.BatchCreated, User: &entity.User{ID: 1}},
+				{Outcome: ports.BatchCreated, User: &entity.User{ID: 2}},
+			}, nil)
+
+		report, err := svc.ImportUsers(context.Background(), importRows(lower, upper), service.ImportOptions{
+			OnConflict: ports.ConflictPolicyFail,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Count(service.ImportCreated))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("dry run only validates", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		rows := importRows(entity.NewUser("ok@example.com", "password123", entity.RoleUser))
+
+		report, err := svc.ImportUsers(context.Background(), rows, service.ImportOptions{DryRun: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, service.ImportValid, report.Results[0].Status)
+		mockRepo.AssertNotCalled(t, "CreateBatch")
+	})
+
+	t.Run("rows are inserted in batches", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		rows := importRows(
+			entity.NewUser("a@example.com", "password123", entity.RoleUser),
+			entity.NewUser("b@example.com", "password123", entity.RoleUser),
+			entity.NewUser("c@example.com", "password123", entity.RoleUser),
+		)
+
+		mockRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(u []*entity.User) bool { return len(u) == 2 }),
+			ports.ConflictPolicyFail).
+			Return([]ports.BatchResult{
+				{Outcome: ports.BatchCreated, User: &entity.User{ID: 1}},
+				{Outcome: ports.BatchConflict},
+			}, nil).Once()
+		mockRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(u []*entity.User) bool { return len(u) == 1 }),
+			ports.ConflictPolicyFail).
+			Return([]ports.BatchResult{{Outcome: ports.BatchCreated, User: &entity.User{ID: 3}}}, nil).Once()
+
+		report, err := svc.ImportUsers(context.Background(), rows, service.ImportOptions{BatchSize: 2})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Count(service.ImportCreated))
+		assert.ErrorIs(t, report.Results[1].Err, service.ErrImportConflict)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository error stops the import", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		rows := importRows(entity.NewUser("a@example.com", "password123", entity.RoleUser))
+		mockRepo.On("CreateBatch", mock.Anything, mock.Anything, ports.ConflictPolicyFail).
+			Return(nil, errors.New("connection refused"))
+
+		report, err := svc.ImportUsers(context.Background(), rows, service.ImportOptions{})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to import user batch in repository")
+		assert.Empty(t, report.Results)
+	})
+
+	t.Run("invalid conflict policy", func(t *testing.T) {
+		svc := service.NewUserService(new(MockUserRepository), l)
+
+		_, err := svc.ImportUsers(context.Background(), nil, service.ImportOptions{OnConflict: "merge"})
+
+		require.Error(t, err)
+	})
+}
@@ -2,38 +2,108 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
 	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
 )
 
+var tracer = otel.Tracer("github.com/pivaldi/go-cleanstack/internal/app/user/service")
+
 type UserService struct {
-	repo   ports.UserRepository
-	logger logging.Logger
+	repo     ports.UserRepository
+	logger   logging.Logger
+	metrics  ports.UserMetrics
+	features ports.FeatureFlags
+}
+
+// Option configures a UserService.
+type Option func(*UserService)
+
+// WithMetrics counts the users created, updated and deleted in metrics.
+func WithMetrics(metrics ports.UserMetrics) Option {
+	return func(s *UserService) {
+		s.metrics = metrics
+	}
 }
 
-func NewUserService(repo ports.UserRepository, logger logging.Logger) *UserService {
-	return &UserService{repo: repo, logger: logger}
+// WithFeatures reads the feature flags from features, e.g. the config.Watcher
+// reloading them, all disabled otherwise.
+func WithFeatures(features ports.FeatureFlags) Option {
+	return func(s *UserService) {
+		s.features = features
+	}
+}
+
+func NewUserService(repo ports.UserRepository, logger logging.Logger, opts ...Option) *UserService {
+	s := &UserService{repo: repo, logger: logger, metrics: noMetrics{}, features: noFeatures{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func (s *UserService) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+// noMetrics is the UserMetrics of the services without metrics.
+type noMetrics struct{}
+
+func (noMetrics) UserCreated(entity.Role) {}
+func (noMetrics) UserUpdated()            {}
+func (noMetrics) UserDeleted()            {}
+
+// noFeatures are the FeatureFlags of the services without feature flags.
+type noFeatures struct{}
+
+func (noFeatures) Enabled(string) bool { return false }
+
+// FeatureEnabled reports whether the feature name is enabled now, for the use
+// cases and their handlers to gate the features in progress.
+func (s *UserService) FeatureEnabled(name string) bool {
+	return s.features.Enabled(name)
+}
+
+// startSpan starts the span of a use case, returning its context and a
+// function ending it, which must be deferred with the error of the use case.
+func startSpan(ctx context.Context, name string) (context.Context, func(err *error)) {
+	ctx, span := tracer.Start(ctx, "UserService."+name)
+
+	return ctx, func(err *error) {
+		if *err != nil && !errors.Is(*err, ports.ErrUserNotFound) {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
+
+func (s *UserService) CreateUser(ctx context.Context, user *entity.User) (_ *entity.User, err error) {
+	ctx, end := startSpan(ctx, "CreateUser")
+	defer end(&err)
+
 	if err := user.Validate(); err != nil {
 		return nil, fmt.Errorf("user validation failed: %w", err)
 	}
 
-	s.logger.Info("creating user", logging.String("email", user.Email))
+	s.logger.InfoContext(ctx, "creating user", logging.String("email", user.Email))
 
 	created, err := s.repo.Create(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user in repository: %w", err)
 	}
+	s.metrics.UserCreated(created.Role)
 
 	return created, nil
 }
 
-func (s *UserService) GetUserByID(ctx context.Context, id int64) (*entity.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id int64) (_ *entity.User, err error) {
+	ctx, end := startSpan(ctx, "GetUserByID")
+	defer end(&err)
+
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user from repository: %w", err)
@@ -42,7 +112,10 @@ func (s *UserService) GetUserByID(ctx context.Context, id int64) (*entity.User,
 	return user, nil
 }
 
-func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (_ *entity.User, err error) {
+	ctx, end := startSpan(ctx, "GetUserByEmail")
+	defer end(&err)
+
 	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by email from repository: %w", err)
@@ -51,7 +124,10 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*entity
 	return user, nil
 }
 
-func (s *UserService) ListUsers(ctx context.Context, offset, limit int) ([]*entity.User, int64, error) {
+func (s *UserService) ListUsers(ctx context.Context, offset, limit int) (_ []*entity.User, _ int64, err error) {
+	ctx, end := startSpan(ctx, "ListUsers")
+	defer end(&err)
+
 	users, total, err := s.repo.List(ctx, offset, limit)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list users from repository: %w", err)
@@ -60,23 +136,64 @@ func (s *UserService) ListUsers(ctx context.Context, offset, limit int) ([]*enti
 	return users, total, nil
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
-	s.logger.Info("updating user", logging.Int64("id", user.ID))
+func (s *UserService) SearchUsers(
+	ctx context.Context,
+	filter ports.UserFilter,
+	offset, limit int,
+) (_ []*entity.User, _ int64, err error) {
+	ctx, end := startSpan(ctx, "SearchUsers")
+	defer end(&err)
+
+	if filter.Role != "" && !filter.Role.IsValid() {
+		return nil, 0, fmt.Errorf("invalid role filter: %w", entity.ErrRoleInvalid)
+	}
+
+	users, total, err := s.repo.Search(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users in repository: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// GetUsersByIDs returns the users with the given ids, missing ones are omitted.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []int64) (_ []*entity.User, err error) {
+	ctx, end := startSpan(ctx, "GetUsersByIDs")
+	defer end(&err)
+
+	users, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by ids from repository: %w", err)
+	}
+
+	return users, nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, user *entity.User) (_ *entity.User, err error) {
+	ctx, end := startSpan(ctx, "UpdateUser")
+	defer end(&err)
+
+	s.logger.InfoContext(ctx, "updating user", logging.Int64("id", user.ID))
 
 	updated, err := s.repo.Update(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user in repository: %w", err)
 	}
+	s.metrics.UserUpdated()
 
 	return updated, nil
 }
 
-func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
-	s.logger.Info("deleting user", logging.Int64("id", id))
+func (s *UserService) DeleteUser(ctx context.Context, id int64) (err error) {
+	ctx, end := startSpan(ctx, "DeleteUser")
+	defer end(&err)
+
+	s.logger.InfoContext(ctx, "deleting user", logging.Int64("id", id))
 
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete user from repository: %w", err)
 	}
+	s.metrics.UserDeleted()
 
 	return nil
 }
@@ -3,12 +3,15 @@ package service_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/entity"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/domain/ports"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
 	"github.com/pivaldi/presence"
@@ -68,6 +71,26 @@ func (m *MockUserRepository) List(
 	return args.Get(0).([]*entity.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) Search(
+	ctx context.Context,
+	filter ports.UserFilter,
+	offset, limit int,
+) ([]*entity.User, int64, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*entity.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []int64) ([]*entity.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.User), args.Error(1)
+}
+
 func (m *MockUserRepository) Update(ctx context.Context, user *entity.User) (*entity.User, error) {
 	args := m.Called(ctx, user)
 	if args.Get(0) == nil {
@@ -81,6 +104,18 @@ func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) CreateBatch(
+	ctx context.Context,
+	users []*entity.User,
+	policy ports.ConflictPolicy,
+) ([]ports.BatchResult, error) {
+	args := m.Called(ctx, users, policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ports.BatchResult), args.Error(1)
+}
+
 // Ensure MockUserRepository implements ports.UserRepository.
 var _ ports.UserRepository = (*MockUserRepository)(nil)
 
@@ -337,6 +372,48 @@ func TestUserService_ListUsers(t *testing.T) {
 	})
 }
 
+func TestUserService_SearchUsers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		filter := ports.UserFilter{Role: entity.RoleAdmin, Email: "example"}
+		users := []*entity.User{{ID: 2, Email: "admin@example.com", Role: entity.RoleAdmin}}
+		mockRepo.On("Search", mock.Anything, filter, 0, 10).Return(users, int64(1), nil)
+
+		result, total, err := svc.SearchUsers(context.Background(), filter, 0, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, users, result)
+		assert.Equal(t, int64(1), total)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		svc := service.NewUserService(mockRepo, l)
+
+		_, _, err := svc.SearchUsers(context.Background(), ports.UserFilter{Role: "superuser"}, 0, 10)
+
+		require.ErrorIs(t, err, entity.ErrRoleInvalid)
+		mockRepo.AssertNotCalled(t, "Search")
+	})
+}
+
+func TestUserService_GetUsersByIDs(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	svc := service.NewUserService(mockRepo, l)
+
+	users := []*entity.User{{ID: 1, Email: "user1@example.com", Role: entity.RoleUser}}
+	mockRepo.On("GetByIDs", mock.Anything, []int64{1, 2}).Return(users, nil)
+
+	result, err := svc.GetUsersByIDs(context.Background(), []int64{1, 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, users, result)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUserService_UpdateUser(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(MockUserRepository)
@@ -454,3 +531,58 @@ func TestUserService_DeleteUser(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+// MockUserMetrics is a mock implementation of ports.UserMetrics.
+type MockUserMetrics struct {
+	mock.Mock
+}
+
+func (m *MockUserMetrics) UserCreated(role entity.Role) { m.Called(role) }
+func (m *MockUserMetrics) UserUpdated()                 { m.Called() }
+func (m *MockUserMetrics) UserDeleted()                 { m.Called() }
+
+func TestUserService_Metrics(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	metrics := new(MockUserMetrics)
+	svc := service.NewUserService(mockRepo, l, service.WithMetrics(metrics))
+
+	input := entity.NewUser("test@example.com", "password123", entity.RoleAdmin)
+	created := &entity.User{ID: 1, Email: input.Email, Role: entity.RoleAdmin}
+	mockRepo.On("Create", mock.Anything, input).Return(created, nil)
+	mockRepo.On("Update", mock.Anything, created).Return(created, nil)
+	mockRepo.On("Delete", mock.Anything, int64(1)).Return(nil).Once()
+	mockRepo.On("Delete", mock.Anything, int64(1)).Return(ports.ErrUserNotFound)
+	metrics.On("UserCreated", entity.RoleAdmin).Once()
+	metrics.On("UserUpdated").Once()
+	metrics.On("UserDeleted").Once()
+
+	_, err := svc.CreateUser(context.Background(), input)
+	require.NoError(t, err)
+	_, err = svc.UpdateUser(context.Background(), created)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteUser(context.Background(), 1))
+	// Failed changes are not counted.
+	require.Error(t, svc.DeleteUser(context.Background(), 1))
+
+	metrics.AssertExpectations(t)
+}
+
+func TestUserService_FeatureEnabled(t *testing.T) {
+	assert.False(t, service.NewUserService(new(MockUserRepository), l).FeatureEnabled("new-search"),
+		"the features are disabled without feature flags")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config_development.toml")
+	require.NoError(t, os.WriteFile(file, []byte("[platform.features]\nnew-search = false\n"), 0o600))
+	t.Setenv("APP_ENV", "development")
+	cfg := &config.Config{}
+	require.NoError(t, config.Load(dir, cfg))
+	watcher := config.NewWatcher(cfg.Platform, l)
+
+	svc := service.NewUserService(new(MockUserRepository), l, service.WithFeatures(watcher))
+	assert.False(t, svc.FeatureEnabled("new-search"))
+
+	require.NoError(t, os.WriteFile(file, []byte("[platform.features]\nnew-search = true\n"), 0o600))
+	require.NoError(t, watcher.Reload())
+	assert.True(t, svc.FeatureEnabled("new-search"), "the service sees the reloaded flag")
+}
@@ -4,24 +4,34 @@ package e2e
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/pivaldi/go-cleanstack/internal/app/user/adapters"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api"
 	userv1 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v1/userv1connect"
+	userv2 "github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/gen/user/v2/userv2connect"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/api/graphql"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/api/handler"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/service"
 	"github.com/pivaldi/go-cleanstack/internal/app/user/tests/testutil"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
 )
 
 var l logging.Logger
@@ -49,13 +59,30 @@ func TestUserAPI_E2E(t *testing.T) {
 	infraRepo := persistence.NewUserRepo(db)
 	userRepo := adapters.NewUserRepositoryAdapter(infraRepo)
 	userService := service.NewUserService(userRepo, l)
-	userHandler := handler.NewUserHandler(userService)
 
-	// Create test server
-	mux := http.NewServeMux()
-	path, h := userv1connect.NewUserServiceHandler(userHandler)
-	mux.Handle(path, h)
-	server := httptest.NewServer(mux)
+	// Create test server, with the routes and interceptors of the real one
+	cfg := config.Platform{Server: config.ServerConfig{
+		GraphQL: config.GraphQLConfig{Enabled: true},
+		Interceptors: config.InterceptorsConfig{
+			RequestID:    true,
+			Logging:      true,
+			ErrorDetails: true,
+			Deadline:     true,
+			Validation:   true,
+		},
+		Deadlines:   config.DeadlinesConfig{Default: 10 * time.Second, Max: 30 * time.Second},
+		Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour, Lease: time.Minute},
+	}}
+	idempotencyStore := adapters.NewIdempotencyStoreAdapter(persistence.NewIdempotencyRepo(db))
+	migrationsCheck, err := persistence.MigrationsCheck(db)
+	require.NoError(t, err)
+	apiHandler, err := api.NewServer(cfg, userService, db, l,
+		api.WithIdempotencyStore(idempotencyStore),
+		api.WithReadinessCheck("pgcrypto", persistence.PgcryptoCheck(db)),
+		api.WithReadinessCheck("migrations", migrationsCheck),
+	).Handler()
+	require.NoError(t, err)
+	server := httptest.NewServer(apiHandler)
 	defer server.Close()
 
 	// Create client
@@ -63,6 +90,7 @@ func TestUserAPI_E2E(t *testing.T) {
 		http.DefaultClient,
 		server.URL,
 	)
+	clientV2 := userv2connect.NewUserServiceClient(http.DefaultClient, server.URL)
 
 	t.Run("CreateUser", func(t *testing.T) {
 		testutil.CleanupTestDB(db)
@@ -98,6 +126,51 @@ func TestUserAPI_E2E(t *testing.T) {
 		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
 	})
 
+	t.Run("CreateUser with an idempotency key", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		newRequest := func(email string) *connect.Request[userv1.CreateUserRequest] {
+			req := connect.NewRequest(&userv1.CreateUserRequest{Email: email, Password: "password123", Role: "user"})
+			req.Header().Set(connectx.IdempotencyKeyHeader, "e2e-key")
+
+			return req
+		}
+
+		first, err := client.CreateUser(ctx, newRequest("idem@example.com"))
+		require.NoError(t, err)
+
+		replayed, err := client.CreateUser(ctx, newRequest("idem@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, first.Msg.User.Id, replayed.Msg.User.Id)
+		assert.Equal(t, "true", replayed.Header().Get(connectx.IdempotentReplayedHeader))
+
+		_, err = client.CreateUser(ctx, newRequest("other@example.com"))
+		require.Error(t, err)
+		ae := connectx.AppErrorFromConnect(err)
+		require.NotNil(t, ae)
+		assert.Equal(t, connectx.ErrCodeIdempotencyKeyReused.Code, ae.Code)
+	})
+
+	t.Run("ListUsers with invalid pagination", func(t *testing.T) {
+		_, err := client.ListUsers(ctx, connect.NewRequest(&userv1.ListUsersRequest{
+			Offset: -1,
+			Limit:  1_000_000,
+		}))
+
+		require.Error(t, err)
+		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+		ae := connectx.AppErrorFromConnect(err)
+		require.NotNil(t, ae)
+		assert.Equal(t, connectx.ErrCodeInvalidRequest.Code, ae.Code)
+		assert.NotEmpty(t, ae.Fields["request_id"])
+		fields := make([]string, 0, len(ae.Violations))
+		for _, v := range ae.Violations {
+			fields = append(fields, v.Field)
+		}
+		assert.ElementsMatch(t, []string{"offset", "limit"}, fields)
+	})
+
 	t.Run("GetUser", func(t *testing.T) {
 		testutil.CleanupTestDB(db)
 
@@ -129,6 +202,20 @@ func TestUserAPI_E2E(t *testing.T) {
 		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
 	})
 
+	t.Run("GetUser not found error details", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		req := connect.NewRequest(&userv1.GetUserRequest{Id: 9999})
+		req.Header().Set(connectx.RequestIDHeader, "e2e-request")
+		_, err := client.GetUser(ctx, req)
+
+		ae := connectx.AppErrorFromConnect(err)
+		require.NotNil(t, ae)
+		assert.Equal(t, handler.ErrCodeNotFound.Code, ae.Code)
+		assert.Equal(t, http.StatusNotFound, ae.HTTPStatus)
+		assert.Equal(t, "e2e-request", ae.Fields["request_id"])
+	})
+
 	t.Run("GetUserByEmail", func(t *testing.T) {
 		testutil.CleanupTestDB(db)
 
@@ -277,4 +364,159 @@ func TestUserAPI_E2E(t *testing.T) {
 		require.Error(t, err)
 		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
 	})
+
+	t.Run("V2 CreateUser and UpdateUser with a field mask", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		firstName := "John"
+		created, err := clientV2.CreateUser(ctx, connect.NewRequest(&userv2.CreateUserRequest{
+			Email:     "v2@example.com",
+			Password:  "password123",
+			FirstName: &firstName,
+			Role:      userv2.Role_ROLE_USER,
+		}))
+		require.NoError(t, err)
+		assert.Empty(t, created.Header().Get(connectx.DeprecationHeader))
+		assert.Equal(t, userv2.Role_ROLE_USER, created.Msg.User.Role)
+		assert.NotNil(t, created.Msg.User.CreateTime)
+		assert.Nil(t, created.Msg.User.UpdateTime)
+
+		updated, err := clientV2.UpdateUser(ctx, connect.NewRequest(&userv2.UpdateUserRequest{
+			Id:         created.Msg.User.Id,
+			LastName:   "Doe",
+			Role:       userv2.Role_ROLE_ADMIN,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"last_name"}},
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "John", updated.Msg.User.GetFirstName())
+		assert.Equal(t, "Doe", updated.Msg.User.GetLastName())
+		assert.Equal(t, userv2.Role_ROLE_USER, updated.Msg.User.Role, "role is not in the mask")
+		assert.NotNil(t, updated.Msg.User.UpdateTime)
+
+		// The same user through v1, which is deprecated.
+		got, err := client.GetUser(ctx, connect.NewRequest(&userv1.GetUserRequest{Id: created.Msg.User.Id}))
+		require.NoError(t, err)
+		assert.Equal(t, "user", got.Msg.User.Role)
+		assert.NotEmpty(t, got.Header().Get(connectx.DeprecationHeader))
+		assert.Contains(t, got.Header().Get(connectx.LinkHeader), `rel="successor-version"`)
+	})
+
+	t.Run("REST CreateUser and GetUser", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		body := `{"email":"rest@example.com","password":"password123","role":"user"}`
+		resp, err := http.Post(server.URL+"/v1/users", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var created struct {
+			User struct {
+				ID    string `json:"id"`
+				Email string `json:"email"`
+			} `json:"user"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		assert.Equal(t, "rest@example.com", created.User.Email)
+
+		getResp, err := http.Get(server.URL + "/v1/users/" + created.User.ID)
+		require.NoError(t, err)
+		defer getResp.Body.Close()
+		assert.Equal(t, http.StatusOK, getResp.StatusCode)
+
+		listResp, err := http.Get(server.URL + "/v1/users?limit=10&offset=0")
+		require.NoError(t, err)
+		defer listResp.Body.Close()
+		assert.Equal(t, http.StatusOK, listResp.StatusCode)
+	})
+
+	t.Run("REST errors", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		resp, err := http.Get(server.URL + "/v1/users/9999")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var errBody struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errBody))
+		assert.Equal(t, "not_found", errBody.Code)
+		assert.Equal(t, handler.ErrCodeNotFound.Code, resp.Header.Get(connectx.ErrorCodeHeader))
+
+		resp, err = http.Get(server.URL + "/v1/users/not-a-number")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Post(server.URL+"/v1/users", "application/json",
+			strings.NewReader(`{"email":"invalid-email","password":"password123","role":"user"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("GraphQL", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		type gqlResponse struct {
+			Data   map[string]json.RawMessage `json:"data"`
+			Errors []struct {
+				Message    string         `json:"message"`
+				Extensions map[string]any `json:"extensions"`
+			} `json:"errors"`
+		}
+		query := func(t *testing.T, q string) gqlResponse {
+			t.Helper()
+
+			body, err := json.Marshal(map[string]string{"query": q})
+			require.NoError(t, err)
+			resp, err := http.Post(server.URL+graphql.Path, "application/json", strings.NewReader(string(body)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var out gqlResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+
+			return out
+		}
+
+		created := query(t, `mutation { createUser(input: {email: "gql@example.com", password: "password123", role: ADMIN}) { id } }`)
+		require.Empty(t, created.Errors)
+
+		var user struct {
+			ID int64 `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(created.Data["createUser"], &user))
+
+		res := query(t, fmt.Sprintf(`{
+			a: user(id: %d) { email role }
+			b: user(id: 999999) { email }
+			users(filter: {role: ADMIN}) { total items { email } }
+		}`, user.ID))
+		require.Empty(t, res.Errors)
+		assert.JSONEq(t, `{"email": "gql@example.com", "role": "ADMIN"}`, string(res.Data["a"]))
+		assert.JSONEq(t, `null`, string(res.Data["b"]))
+		assert.JSONEq(t, `{"total": 1, "items": [{"email": "gql@example.com"}]}`, string(res.Data["users"]))
+
+		res = query(t, `mutation { deleteUser(id: 999999) }`)
+		require.Len(t, res.Errors, 1)
+		assert.Equal(t, handler.ErrCodeNotFound.Code, res.Errors[0].Extensions["code"])
+	})
+
+	t.Run("Readiness", func(t *testing.T) {
+		resp, err := http.Get(server.URL + connectx.ReadinessPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var report connectx.ProbeReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, connectx.ProbeStatusOK, report.Status)
+		for _, name := range []string{"database", "pgcrypto", "migrations", "shutdown"} {
+			assert.Equal(t, connectx.ProbeStatusOK, report.Checks[name].Status, name)
+		}
+	})
 }
@@ -0,0 +1,119 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/tests/testutil"
+)
+
+func TestIdempotencyRepo(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := testutil.StartPostgresContainer(ctx)
+	require.NoError(t, err)
+	defer pgContainer.Terminate(ctx)
+
+	db, err := testutil.SetupTestDB(pgContainer.URI)
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := persistence.NewIdempotencyRepo(db)
+	const (
+		client    = "ip:192.0.2.1"
+		procedure = "/user.v1.UserService/CreateUser"
+	)
+
+	t.Run("Reserve, Complete and replay", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		existing, err := repo.Reserve(ctx, client, "key-1", procedure, "r1", []byte("fp"), time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		existing, err = repo.Reserve(ctx, client, "key-1", procedure, "r2", []byte("other"), time.Hour)
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, []byte("fp"), existing.Fingerprint)
+		assert.False(t, existing.CompletedAt.Valid)
+
+		require.NoError(t, repo.Complete(ctx, client, "key-1", procedure, "r1", []byte("response"), time.Hour))
+		require.ErrorIs(t, repo.Complete(ctx, client, "key-1", procedure, "r1", []byte("other"), time.Hour),
+			persistence.ErrIdempotencyLeaseLost, "a completed key is not completed again")
+		require.ErrorIs(t, repo.Delete(ctx, client, "key-1", procedure, "r1"), persistence.ErrIdempotencyLeaseLost,
+			"a completed key is not released")
+
+		existing, err = repo.Reserve(ctx, client, "key-1", procedure, "r3", []byte("fp"), time.Hour)
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.True(t, existing.CompletedAt.Valid)
+		assert.Equal(t, []byte("response"), existing.Response)
+
+		// Keys are scoped by procedure.
+		existing, err = repo.Reserve(ctx, client, "key-1", "/user.v1.UserService/UpdateUser", "r4", []byte("fp"), time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		// And by client.
+		existing, err = repo.Reserve(ctx, "ip:192.0.2.2", "key-1", procedure, "r5", []byte("fp"), time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Delete releases the key", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		_, err := repo.Reserve(ctx, client, "key-1", procedure, "r1", []byte("fp"), time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, client, "key-1", procedure, "r1"))
+
+		existing, err := repo.Reserve(ctx, client, "key-1", procedure, "r2", []byte("other"), time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Complete extends the lease to the ttl", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		// The lease of the first call expired, a retry takes the key over.
+		_, err := repo.Reserve(ctx, client, "leased", procedure, "r1", []byte("fp"), -time.Second)
+		require.NoError(t, err)
+		existing, err := repo.Reserve(ctx, client, "leased", procedure, "r2", []byte("fp"), -time.Second)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		// The first call neither completes nor releases the key of the retry.
+		require.ErrorIs(t, repo.Complete(ctx, client, "leased", procedure, "r1", []byte("first"), time.Hour),
+			persistence.ErrIdempotencyLeaseLost)
+		require.ErrorIs(t, repo.Delete(ctx, client, "leased", procedure, "r1"), persistence.ErrIdempotencyLeaseLost)
+
+		require.NoError(t, repo.Complete(ctx, client, "leased", procedure, "r2", []byte("response"), time.Hour))
+		existing, err = repo.Reserve(ctx, client, "leased", procedure, "r3", []byte("fp"), -time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.True(t, existing.CompletedAt.Valid)
+		assert.Equal(t, []byte("response"), existing.Response)
+	})
+
+	t.Run("Expired keys are replaced and purged", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		_, err := repo.Reserve(ctx, client, "expired", procedure, "r1", []byte("fp"), -time.Second)
+		require.NoError(t, err)
+
+		existing, err := repo.Reserve(ctx, client, "expired", procedure, "r2", []byte("other"), -time.Second)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		deleted, err := repo.DeleteExpired(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/app/user/infra/persistence"
+	"github.com/pivaldi/go-cleanstack/internal/app/user/tests/testutil"
+)
+
+func TestRateLimitRepo(t *testing.T) {
+	ctx := context.Background()
+
+	pgContainer, err := testutil.StartPostgresContainer(ctx)
+	require.NoError(t, err)
+	defer pgContainer.Terminate(ctx)
+
+	db, err := testutil.SetupTestDB(pgContainer.URI)
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := persistence.NewRateLimitRepo(db)
+
+	t.Run("Take until the bucket is empty", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		// A token per hour: no noticeable refill during the test.
+		const rate = 1.0 / 3600
+		for _, want := range []float64{2, 1} {
+			tokens, err := repo.Take(ctx, "ip:1.2.3.4", 2, rate)
+			require.NoError(t, err)
+			assert.InDelta(t, want, tokens, 0.01)
+		}
+
+		tokens, err := repo.Take(ctx, "ip:1.2.3.4", 2, rate)
+		require.NoError(t, err)
+		assert.Less(t, tokens, 1.0)
+
+		// Other keys have their own bucket.
+		tokens, err = repo.Take(ctx, "ip:5.6.7.8", 2, rate)
+		require.NoError(t, err)
+		assert.InDelta(t, 2, tokens, 0.01)
+	})
+
+	t.Run("Concurrent takes", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tokens, err := repo.Take(ctx, "ip:1.2.3.4", 5, 1.0/3600)
+				assert.NoError(t, err)
+				if tokens >= 1 {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 5, allowed)
+	})
+
+	t.Run("DeleteFull", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		_, err := repo.Take(ctx, "full", 1, 1000)
+		require.NoError(t, err)
+		_, err = repo.Take(ctx, "empty", 1, 1.0/3600)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, "UPDATE rate_limit_buckets SET updated_at = updated_at - INTERVAL '1 second'")
+		require.NoError(t, err)
+
+		deleted, err := repo.DeleteFull(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("Take and DeleteFull concurrently", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		// Refilled in a millisecond, every bucket is full, hence purged, right after its creation.
+		const rate = 1000.0
+		purgeCtx, stopPurge := context.WithCancel(ctx)
+		purged := make(chan struct{})
+		go func() {
+			defer close(purged)
+			for purgeCtx.Err() == nil {
+				_, _ = repo.DeleteFull(purgeCtx)
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for i := range 200 {
+			wg.Go(func() {
+				key := "ip:" + strconv.Itoa(i%20)
+				tokens, err := repo.Take(ctx, key, 1, rate)
+				assert.NoError(t, err, "a purged bucket is created again")
+				assert.GreaterOrEqual(t, tokens, 0.0)
+			})
+		}
+		wg.Wait()
+		stopPurge()
+		<-purged
+	})
+}
@@ -4,7 +4,9 @@ package integration
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -190,4 +192,112 @@ func TestUserRepo_CRUD(t *testing.T) {
 		err := repo.Delete(ctx, 9999)
 		assert.ErrorIs(t, err, persistence.ErrUserNotFound)
 	})
+
+	t.Run("CreateBatch", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		existing, err := repo.Create(ctx, entity.NewUser("existing@example.com", "password123", entity.RoleUser))
+		require.NoError(t, err)
+
+		hashed := entity.NewUser("hashed@example.com", "", entity.RoleUser)
+		hashed.SetPasswordHash("$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy")
+		tooLong := entity.NewUser(strings.Repeat("x", 300)+"@example.com", "password123", entity.RoleUser)
+		conflicting := entity.NewUser("existing@example.com", "password456", entity.RoleAdmin)
+
+		rows, err := repo.CreateBatch(ctx, []*entity.User{hashed, tooLong, conflicting}, persistence.OnConflictNothing)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, persistence.BatchInserted, rows[0].Status)
+		assert.Equal(t, hashed.Password, rows[0].User.Password) // stored as is
+		assert.Equal(t, persistence.BatchError, rows[1].Status)
+		assert.Equal(t, persistence.BatchConflict, rows[2].Status)
+
+		rows, err = repo.CreateBatch(ctx, []*entity.User{conflicting}, persistence.OnConflictUpdate)
+		require.NoError(t, err)
+		assert.Equal(t, persistence.BatchUpdated, rows[0].Status)
+		assert.Equal(t, existing.ID, rows[0].User.ID)
+		assert.Equal(t, entity.RoleAdmin, rows[0].User.Role)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		alice := entity.NewUser("alice@example.com", "password123", entity.RoleAdmin)
+		alice.SetFirstName("Alice")
+		bob := entity.NewUser("bob@test.org", "password123", entity.RoleUser)
+		bob.SetLastName("Alison")
+		carol := entity.NewUser("carol_100%@example.com", "password123", entity.RoleUser)
+		for _, u := range []*entity.User{alice, bob, carol} {
+			_, err := repo.Create(ctx, u)
+			require.NoError(t, err)
+		}
+
+		users, total, err := repo.Search(ctx, persistence.SearchFilter{Role: "admin"}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, users, 1)
+		assert.Equal(t, "alice@example.com", users[0].Email)
+
+		_, total, err = repo.Search(ctx, persistence.SearchFilter{Email: "EXAMPLE.COM"}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+
+		_, total, err = repo.Search(ctx, persistence.SearchFilter{Name: "ali"}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+
+		// LIKE wildcards are matched literally.
+		users, total, err = repo.Search(ctx, persistence.SearchFilter{Email: "_100%"}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, users, 1)
+
+		users, total, err = repo.Search(ctx, persistence.SearchFilter{Role: "user", Email: "test"}, 0, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("GetByIDs", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		var ids []int64
+		for _, email := range []string{"ids1@example.com", "ids2@example.com", "ids3@example.com"} {
+			created, err := repo.Create(ctx, entity.NewUser(email, "password123", entity.RoleUser))
+			require.NoError(t, err)
+			ids = append(ids, created.ID)
+		}
+		require.NoError(t, repo.Delete(ctx, ids[2]))
+
+		users, err := repo.GetByIDs(ctx, append(ids, 999999))
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+
+		users, err = repo.GetByIDs(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+	t.Run("Statements stop at the deadline", func(t *testing.T) {
+		testutil.CleanupTestDB(db)
+
+		created, err := repo.Create(ctx, entity.NewUser("locked@example.com", "password123", entity.RoleUser))
+		require.NoError(t, err)
+
+		// Another transaction locks the row: the update waits until its statement timeout.
+		lock, err := db.BeginTxx(ctx, nil)
+		require.NoError(t, err)
+		defer lock.Rollback()
+		_, err = lock.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", created.ID)
+		require.NoError(t, err)
+
+		deadlineCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		created.Email = "unlocked@example.com"
+		created.Password = ""
+		_, err = repo.Update(deadlineCtx, created)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
 }
@@ -37,5 +37,5 @@ func SetupTestDB(databaseURL string) (*sqlx.DB, error) {
 
 // CleanupTestDB truncates all tables to reset state between tests.
 func CleanupTestDB(db *sqlx.DB) {
-	_, _ = db.Exec("TRUNCATE TABLE users CASCADE")
+	_, _ = db.Exec("TRUNCATE TABLE users, idempotency_keys, rate_limit_buckets CASCADE")
 }
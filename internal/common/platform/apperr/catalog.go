@@ -0,0 +1,65 @@
+package apperr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Definition documents a stable error code: the HTTP status it maps to and
+// what it means for clients. Definitions are published in the API docs.
+type Definition struct {
+	Code        string
+	HTTPStatus  int
+	Description string
+}
+
+// New returns a public error with the code and status of the definition.
+func (d Definition) New(msg string) *AppError { return NewPublic(d.Code, msg, d.HTTPStatus) }
+
+// Wrap returns a private error with the code and status of the definition.
+func (d Definition) Wrap(cause error) *AppError { return WrapPrivate(d.Code, d.HTTPStatus, cause) }
+
+var catalog = struct {
+	sync.RWMutex
+	defs map[string]Definition
+}{defs: map[string]Definition{}}
+
+// Register adds a definition to the catalog and returns it, so that error
+// codes can be declared as package level vars.
+// Registering the same code twice with a different definition panics.
+func Register(d Definition) Definition {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	if prev, ok := catalog.defs[d.Code]; ok && prev != d {
+		panic(fmt.Sprintf("apperr: error code %q registered twice", d.Code))
+	}
+	catalog.defs[d.Code] = d
+
+	return d
+}
+
+// Lookup returns the definition of a registered error code.
+func Lookup(code string) (Definition, bool) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	d, ok := catalog.defs[code]
+
+	return d, ok
+}
+
+// Catalog returns all registered definitions, sorted by code.
+func Catalog() []Definition {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	defs := make([]Definition, 0, len(catalog.defs))
+	for _, d := range catalog.defs {
+		defs = append(defs, d)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Code < defs[j].Code })
+
+	return defs
+}
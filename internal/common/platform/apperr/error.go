@@ -25,7 +25,17 @@ type AppError struct {
 	Cause  error          // wrapped error (private)
 	Stack  string         // stacktrace for private error
 	Req    map[string]any // decoded request summary (sanitized)
-	Fields map[string]any // arbitrary structured fields
+	Fields map[string]any // arbitrary structured fields, sent to clients when Public
+
+	Violations []FieldViolation // invalid request fields
+	RetryAfter time.Duration    // how long clients should wait before retrying, 0 when unknown
+}
+
+// FieldViolation describes why a field of a request is invalid.
+type FieldViolation struct {
+	Field       string // path of the field, e.g. "email" or "address.city"
+	Description string // human readable reason
+	Reason      string // stable rule identifier, e.g. "string.email"
 }
 
 func (e *AppError) Error() string   { return e.Code + ": " + e.Message }
@@ -92,6 +102,22 @@ func WithField(err error, k string, v any) error {
 	return err
 }
 
+func WithViolations(err error, violations ...FieldViolation) error {
+	if ae := As(err); ae != nil {
+		ae.Violations = append(ae.Violations, violations...)
+	}
+
+	return err
+}
+
+func WithRetryAfter(err error, d time.Duration) error {
+	if ae := As(err); ae != nil {
+		ae.RetryAfter = d
+	}
+
+	return err
+}
+
 func StatusOrDefault(ae *AppError, def int) int {
 	if ae == nil || ae.HTTPStatus <= 0 {
 		return def
@@ -3,10 +3,43 @@ package clierr
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
 )
 
+var (
+	mu        sync.RWMutex
+	exitCodes = map[string]int{} // by AppError code
+)
+
+// RegisterExitCode makes ExitOnError exit with exitCode on the AppErrors of
+// code, so that scripts can tell the errors apart.
+func RegisterExitCode(code string, exitCode int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	exitCodes[code] = exitCode
+}
+
+// ExitCode returns the exit code registered for the code of the AppError of
+// err, or 1.
+func ExitCode(err error) int {
+	ae := apperr.As(err)
+	if ae == nil {
+		return 1
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if exitCode, ok := exitCodes[ae.Code]; ok {
+		return exitCode
+	}
+
+	return 1
+}
+
 func ExitOnError(err error, debug bool) {
 	if err == nil {
 		return
@@ -39,5 +72,5 @@ func ExitOnError(err error, debug bool) {
 		}
 	}
 
-	os.Exit(1)
+	os.Exit(ExitCode(ae))
 }
@@ -0,0 +1,20 @@
+package clierr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+func TestExitCode(t *testing.T) {
+	RegisterExitCode("test.not_found", 4)
+
+	assert.Equal(t, 4, ExitCode(apperr.NotFound("test.not_found", "not found")))
+	assert.Equal(t, 4, ExitCode(fmt.Errorf("failed to get: %w", apperr.NotFound("test.not_found", "not found"))))
+	assert.Equal(t, 1, ExitCode(apperr.BadRequest("test.invalid", "invalid")))
+	assert.Equal(t, 1, ExitCode(errors.New("boom")))
+}
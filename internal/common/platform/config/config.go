@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pivaldi/go-cleanstack/pkg/file"
 	"github.com/spf13/viper"
@@ -17,13 +19,28 @@ var defaultConfig []byte
 
 type AppEnv string
 
+const AppEnvDevelopment AppEnv = "development"
+
+func (e AppEnv) IsDevelopment() bool { return e == AppEnvDevelopment }
+
 type Platform struct {
 	AppEnv   AppEnv
 	Server   ServerConfig
 	Database DatabaseConfig
 	Log      LogConfig
+	Metrics  MetricsConfig
+	Tracing  TracingConfig
+	Admin    AdminConfig
+	Features Features
 }
 
+// Features are the feature flags of the application, by name, reloaded
+// without restart, see Watcher.
+type Features map[string]bool
+
+// Enabled reports whether the feature name is enabled, features are disabled by default.
+func (f Features) Enabled(name string) bool { return f[name] }
+
 func (p *Platform) SetAppEnv(appEnv AppEnv) {
 	if p == nil {
 		return
@@ -49,7 +66,254 @@ type configI interface {
 }
 
 type ServerConfig struct {
-	Port int
+	// Listen are the addresses the server listens on: "tcp://host:port",
+	// "unix:///path/to.sock", or "systemd:" for the sockets passed by systemd
+	// socket activation, "systemd:name" for those of a FileDescriptorName.
+	Listen       []string
+	UnixSocket   UnixSocketConfig `mapstructure:"unix-socket"`
+	GraphQL      GraphQLConfig
+	Interceptors InterceptorsConfig
+	Deadlines    DeadlinesConfig
+	Idempotency  IdempotencyConfig
+	RateLimit    RateLimitConfig `mapstructure:"rate-limit"`
+	CORS         CORSConfig
+	HTTP         HTTPConfig
+	Shutdown     ShutdownConfig
+	Health       HealthConfig
+	TLS          TLSConfig
+}
+
+// UnixSocketConfig are the permissions of the unix sockets the server listens on.
+type UnixSocketConfig struct {
+	// Mode is the octal file mode of the sockets, "0660" by default.
+	Mode string
+	// Group owns the sockets when set, e.g. the group of a local reverse proxy.
+	Group string
+}
+
+// Client certificate policies of TLSConfig.
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// TLSConfig terminates TLS on the server. Without TLS, the server serves
+// cleartext HTTP/1.1 and HTTP/2 (h2c), in development only.
+type TLSConfig struct {
+	Enabled bool
+	// CertFile and KeyFile are the PEM certificate chain and private key of the server.
+	CertFile string `mapstructure:"cert-file"`
+	KeyFile  string `mapstructure:"key-file"`
+	// ClientAuth is "none", "optional" (verified when given) or "require" (mutual TLS).
+	ClientAuth string `mapstructure:"client-auth"`
+	// ClientCAFile is the PEM bundle of the CAs verifying the client certificates.
+	ClientCAFile string `mapstructure:"client-ca-file"`
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string `mapstructure:"min-version"`
+	// ReloadInterval is the period of the checks of the files, which are
+	// reloaded once they change, 0 not to reload them.
+	ReloadInterval time.Duration `mapstructure:"reload-interval"`
+}
+
+// HealthConfig tunes the checks of the /readyz probe.
+type HealthConfig struct {
+	// Timeout bounds every check.
+	Timeout time.Duration
+	// CacheTTL is how long the results of the checks are reused, to not hit the
+	// database on every probe.
+	CacheTTL time.Duration `mapstructure:"cache-ttl"`
+}
+
+// ShutdownConfig drives the graceful shutdown of the server on SIGINT or SIGTERM.
+type ShutdownConfig struct {
+	// PreStopDelay is how long the server keeps serving once reported as not
+	// ready, for the load balancers to stop routing new requests to it.
+	PreStopDelay time.Duration `mapstructure:"pre-stop-delay"`
+	// DrainTimeout bounds the wait for the in-flight requests, which are
+	// canceled past it.
+	DrainTimeout time.Duration `mapstructure:"drain-timeout"`
+}
+
+// HTTPConfig tunes the HTTP server. Zero values keep the defaults of net/http.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration `mapstructure:"read-header-timeout"`
+	ReadTimeout       time.Duration `mapstructure:"read-timeout"`
+	// WriteTimeout defaults to the longest maximum deadline plus a grace period.
+	WriteTimeout time.Duration `mapstructure:"write-timeout"`
+	IdleTimeout  time.Duration `mapstructure:"idle-timeout"`
+	// MaxHeaderBytes bounds the size of the request headers.
+	MaxHeaderBytes int `mapstructure:"max-header-bytes"`
+	// MaxRequestBytes and MaxResponseBytes bound the size of the messages, after decompression.
+	MaxRequestBytes  int `mapstructure:"max-request-bytes"`
+	MaxResponseBytes int `mapstructure:"max-response-bytes"`
+	HTTP2            HTTP2Config
+	Compression      CompressionConfig
+}
+
+// HTTP2Config tunes the HTTP/2 connections, cleartext (h2c) included.
+type HTTP2Config struct {
+	MaxConcurrentStreams uint32 `mapstructure:"max-concurrent-streams"`
+	// MaxReadFrameSize is between 16KiB and 16MiB.
+	MaxReadFrameSize             uint32 `mapstructure:"max-read-frame-size"`
+	MaxUploadBufferPerConnection int32  `mapstructure:"max-upload-buffer-per-connection"`
+	MaxUploadBufferPerStream     int32  `mapstructure:"max-upload-buffer-per-stream"`
+}
+
+// CompressionConfig sets the compression algorithms of the Connect, gRPC and REST calls:
+// "gzip", "zstd" or "br" (brotli).
+type CompressionConfig struct {
+	// Accept are the algorithms of the requests the server decompresses.
+	Accept []string
+	// Send are the algorithms the server compresses responses with, among Accept.
+	Send []string
+	// MinBytes is the size below which responses are not compressed.
+	MinBytes int `mapstructure:"min-bytes"`
+}
+
+// CORSConfig lets browser applications served from other origins call the API.
+type CORSConfig struct {
+	Enabled bool
+	// AllowedOrigins are the origins allowed to call the API, e.g. https://app.example.com.
+	// "*" allows any origin, and "https://*.example.com" any subdomain.
+	AllowedOrigins []string `mapstructure:"allowed-origins"`
+	// AllowCredentials lets browsers send cookies and HTTP authentication,
+	// forbidden with the "*" origin.
+	AllowCredentials bool `mapstructure:"allow-credentials"`
+	// MaxAge is how long browsers cache the preflight responses.
+	MaxAge time.Duration `mapstructure:"max-age"`
+}
+
+// InterceptorsConfig toggles the interceptors of the default Connect chain.
+type InterceptorsConfig struct {
+	// RequestID reads or generates the X-Request-Id of every call.
+	RequestID bool `mapstructure:"request-id"`
+	Logging   bool
+	// ErrorDetails sends the apperr code, field violations and request id as error details.
+	ErrorDetails bool `mapstructure:"error-details"`
+	// Validation enforces the protovalidate rules of the request messages.
+	Validation bool
+	// Deadline applies the [platform.server.deadlines] to the calls.
+	Deadline bool
+}
+
+// DeadlinesConfig bounds the duration of the calls. Clients set their own
+// deadline with the Connect-Timeout-Ms or grpc-timeout header.
+type DeadlinesConfig struct {
+	// Default is the deadline of the calls without client deadline, none when zero.
+	Default time.Duration
+	// Max caps the client deadlines, none when zero.
+	Max time.Duration
+	// Overrides replace Default and Max for some procedures.
+	Overrides []DeadlineOverride
+}
+
+// DeadlineOverride sets the deadlines of Procedures, their zero values keep the global ones.
+type DeadlineOverride struct {
+	// Procedures are full procedure names, e.g. /user.v1.UserService/ListUsers.
+	Procedures []string
+	Default    time.Duration
+	Max        time.Duration
+}
+
+// IdempotencyConfig enables the Idempotency-Key header on the mutating procedures.
+type IdempotencyConfig struct {
+	Enabled bool
+	// TTL is how long a key and its response are kept for replays.
+	TTL time.Duration
+	// Lease is how long the key of a call in progress is kept, at most TTL and
+	// at least the longest deadline. It must exceed the longest call, which
+	// could otherwise run twice.
+	Lease time.Duration
+}
+
+// Rate limit stores.
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// RateLimitConfig limits the calls of every client with token buckets.
+type RateLimitConfig struct {
+	Enabled bool
+	// Store keeps the buckets: "memory", or "postgres" to share them between replicas.
+	Store string
+	// ClientIPHeader is the header where a trusted proxy puts the client IP,
+	// e.g. X-Forwarded-For, whose last address is used. Empty uses the peer address.
+	ClientIPHeader string `mapstructure:"client-ip-header"`
+	Policies       []RateLimitPolicy
+}
+
+// RateLimitPolicy is a token bucket of Burst tokens refilled with Requests
+// tokens per Period, shared by Procedures for every client.
+type RateLimitPolicy struct {
+	// Name identifies the buckets of the policy.
+	Name string
+	// Procedures are full procedure names, "*" for the ones without a policy of their own.
+	Procedures []string
+	// Key identifies the clients: "ip", "principal" or "api-key", the IP when
+	// no principal or API key was verified by an authentication layer.
+	// Calls without principal or API key are limited by IP.
+	Key      string
+	Requests int
+	Period   time.Duration
+	// Burst is the bucket size, Requests when zero.
+	Burst int
+}
+
+type GraphQLConfig struct {
+	// Enabled serves GraphQL, whose resolvers call the service without the
+	// Connect interceptors.
+	Enabled bool
+	// Playground serves the GraphQL playground, in development only.
+	Playground bool
+}
+
+// MetricsConfig exposes Prometheus metrics on the server.
+type MetricsConfig struct {
+	Enabled bool
+	// Path serves the metrics, e.g. /metrics.
+	Path string
+	// Namespace prefixes the names of the metrics of the application.
+	Namespace string
+	// Buckets are the upper bounds, in seconds, of the duration histograms.
+	Buckets []float64
+	// IgnoredProcedures are not measured, e.g. the health checks of the orchestrators.
+	IgnoredProcedures []string `mapstructure:"ignored-procedures"`
+}
+
+// Tracing exporters.
+const (
+	TracingExporterStdout   = "stdout"
+	TracingExporterOTLPFile = "otlp-file"
+	TracingExporterOTLP     = "otlp"
+)
+
+// TracingConfig exports OpenTelemetry traces of the calls, services and queries.
+type TracingConfig struct {
+	Enabled bool
+	// ServiceName is the service.name of the traces.
+	ServiceName string `mapstructure:"service-name"`
+	// Exporter is "stdout" (human-readable JSON), "otlp-file" (OTLP JSON lines
+	// written to File) or "otlp" (OTLP/HTTP sent to Endpoint).
+	Exporter string
+	File     string
+	// Endpoint is the URL of the OTLP/HTTP collector, e.g. http://localhost:4318.
+	Endpoint string
+	// Headers are sent to the collector, e.g. for its authentication.
+	Headers map[string]string
+	// SampleRatio is the ratio of the traces started by the server which are
+	// sampled, between 0 and 1. Traces started by the clients keep their decision.
+	SampleRatio float64 `mapstructure:"sample-ratio"`
+}
+
+// AdminConfig serves the admin endpoints (pprof, runtime info, log level...)
+// on a listener of their own, never on the public port.
+type AdminConfig struct {
+	// Addr is a loopback TCP address, e.g. "tcp://localhost:4230", or a unix
+	// socket, e.g. "unix:///run/cleanstack/admin.sock", in the format of
+	// ServerConfig.Listen. Empty, the admin listener is disabled.
+	Addr string
 }
 
 type DatabaseConfig struct {
@@ -60,32 +324,59 @@ type LogConfig struct {
 	Level string
 }
 
+// loaded is the configuration read by the last Load, from the directory loadedDir.
+var (
+	loadedMu  sync.RWMutex
+	loaded    *viper.Viper
+	loadedDir string
+)
+
 func Load[T configI](configDir string, dest T) error {
+	v, env, err := read(configDir)
+	if err != nil {
+		return err
+	}
+
+	if err := v.Unmarshal(&dest); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	dest.SetAppEnv(AppEnv(env))
+
+	loadedMu.Lock()
+	loaded, loadedDir = v, configDir
+	loadedMu.Unlock()
+
+	return nil
+}
+
+// read reads the default configuration merged with the file of the
+// environment in configDir, if any.
+func read(configDir string) (*viper.Viper, string, error) {
 	env := os.Getenv("APP_ENV")
 	if env == "" {
-		return errors.New("APP_ENV environment variable is not set")
+		return nil, "", errors.New("APP_ENV environment variable is not set")
 	}
 
-	viper.SetConfigType("toml")
-	viper.ReadConfig(bytes.NewBuffer(defaultConfig))
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewBuffer(defaultConfig)); err != nil {
+		return nil, "", fmt.Errorf("failed to read default config: %w", err)
+	}
 
-	baseFile := "config_" + env
-	fileName := baseFile + ".toml"
-	confPath := filepath.Join(configDir, fileName)
+	confPath := filepath.Join(configDir, fileName(env))
 	if file.Exists(confPath) {
-		viper.AddConfigPath(configDir)
-		viper.SetConfigName(baseFile)
+		v.SetConfigFile(confPath)
 
-		if err := viper.MergeInConfig(); err != nil {
-			return fmt.Errorf("failed to merge config file: %w", err)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, "", fmt.Errorf("failed to merge config file: %w", err)
 		}
 	}
 
-	if err := viper.Unmarshal(&dest); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
-	}
-
-	dest.SetAppEnv(AppEnv(env))
+	return v, env, nil
+}
 
-	return nil
+// fileName returns the name of the configuration file of env.
+func fileName(env string) string {
+	return "config_" + env + ".toml"
 }
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the secrets in the settings returned by Settings.
+const Redacted = "[redacted]"
+
+// secretKeys are the parts of the keys whose values are secrets.
+var secretKeys = []string{"password", "secret", "token", "credential", "api-key", "private-key"}
+
+// secretMaps are the keys of the tables whose values are all secrets, e.g.
+// the headers authenticating to a collector.
+var secretMaps = []string{"headers"}
+
+// redactedValue replaces the secrets in the strings, as url.URL.Redacted does.
+const redactedValue = "xxxxx"
+
+// dsnParam matches the key=value parameters of the DSNs, e.g.
+// "host=localhost password='a b'", the value quoted or up to a space.
+var dsnParam = regexp.MustCompile(`([\w.-]+)(\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s']*)`)
+
+// Settings returns the effective settings loaded by Load, the defaults merged
+// with the file of the environment, keyed as in the files. The secrets are
+// redacted: the values of the keys naming a secret, of the secret tables, the
+// passwords of the URLs, and the parameters naming a secret in the URL queries,
+// e.g. "?password=", and the key=value DSNs, e.g. "host=db password=".
+func Settings() map[string]any {
+	loadedMu.RLock()
+	defer loadedMu.RUnlock()
+
+	if loaded == nil {
+		return map[string]any{}
+	}
+	redacted, _ := redact(loaded.AllSettings(), false).(map[string]any)
+
+	return redacted
+}
+
+func redact(value any, secret bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			out[key] = redact(val, secret || isSecretKey(key) || isSecretMap(key))
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = redact(val, secret)
+		}
+
+		return out
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = redact(val, secret)
+		}
+
+		return out
+	case string:
+		if secret {
+			return Redacted
+		}
+
+		return redactString(v)
+	default:
+		if secret {
+			return Redacted
+		}
+
+		return v
+	}
+}
+
+// redactString redacts the passwords and the secret query parameters of the
+// URLs, and the secret parameters of the key=value DSNs.
+func redactString(v string) string {
+	if u, err := url.Parse(v); err == nil && u.Scheme != "" && strings.Contains(v, "://") {
+		redacted := false
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+			redacted = true
+		}
+		if query := u.Query(); len(query) > 0 {
+			for key := range query {
+				if isSecretKey(key) {
+					query.Set(key, redactedValue)
+					redacted = true
+				}
+			}
+			if redacted {
+				u.RawQuery = query.Encode()
+			}
+		}
+		if redacted {
+			return u.String()
+		}
+
+		return v
+	}
+
+	return dsnParam.ReplaceAllStringFunc(v, func(param string) string {
+		m := dsnParam.FindStringSubmatch(param)
+		if !isSecretKey(m[1]) {
+			return param
+		}
+
+		return m[1] + m[2] + redactedValue
+	})
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeys {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecretMap(key string) bool {
+	for _, m := range secretMaps {
+		if strings.EqualFold(key, m) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+)
+
+// ReloadFunc validates the configuration cfg reloaded from the files and
+// returns the function applying it. When a subscriber fails, the reload is
+// rejected and none applies it.
+type ReloadFunc func(cfg Platform) (apply func(), err error)
+
+type subscriber struct {
+	fn   ReloadFunc
+	keys []string // the subscriber is called when one of them changed, on every reload when empty
+}
+
+// reloadable are the settings applied without restart, by key.
+var reloadable = []struct {
+	key  string
+	copy func(dst, src *Platform)
+}{
+	{"platform.log.level", func(dst, src *Platform) { dst.Log.Level = src.Log.Level }},
+	{"platform.server.rate-limit.policies", func(dst, src *Platform) {
+		dst.Server.RateLimit.Policies = src.Server.RateLimit.Policies
+	}},
+	{"platform.server.rate-limit.client-ip-header", func(dst, src *Platform) {
+		dst.Server.RateLimit.ClientIPHeader = src.Server.RateLimit.ClientIPHeader
+	}},
+	{"platform.server.cors.allowed-origins", func(dst, src *Platform) {
+		dst.Server.CORS.AllowedOrigins = src.Server.CORS.AllowedOrigins
+	}},
+	{"platform.features", func(dst, src *Platform) { dst.Features = src.Features }},
+}
+
+// reloadDebounce groups the events of a file being written in a single reload.
+const reloadDebounce = 100 * time.Millisecond
+
+// Watcher reloads the configuration loaded by Load when its file changes or
+// on SIGHUP. The changed reloadable settings (log level, rate limit policies,
+// CORS origins and feature flags) are applied through the subscribers, the
+// other changed settings are logged as rejected: they require a restart.
+type Watcher struct {
+	logger logging.Logger
+
+	mu          sync.Mutex // serializes the reloads
+	subscribers []subscriber
+	current     atomic.Pointer[Platform]
+	initial     map[string]any // settings at start, by key
+	applied     map[string]any // settings of the last reload, by key
+}
+
+// NewWatcher returns the watcher of the configuration cfg, loaded by Load.
+func NewWatcher(cfg Platform, logger logging.Logger) *Watcher {
+	settings := map[string]any{}
+	loadedMu.RLock()
+	if loaded != nil {
+		flatten("", loaded.AllSettings(), settings)
+	}
+	loadedMu.RUnlock()
+
+	w := &Watcher{logger: logger, initial: settings, applied: settings}
+	w.current.Store(&cfg)
+
+	return w
+}
+
+// Subscribe adds fn to the functions validating and applying the reloaded
+// configurations. Given keys, e.g. "platform.log.level", fn is only called by
+// the reloads changing one of them or a setting under them, for a setting also
+// changed by other means, e.g. the log level set through the admin listener,
+// to be kept over the reloads of the other settings.
+func (w *Watcher) Subscribe(fn ReloadFunc, keys ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, subscriber{fn: fn, keys: keys})
+}
+
+// Current returns the configuration applied last.
+func (w *Watcher) Current() Platform {
+	return *w.current.Load()
+}
+
+// Enabled reports whether the feature name is enabled in the configuration
+// applied last: the feature flags are read from the watcher, not from the
+// configuration at start, for their reloads to be seen.
+func (w *Watcher) Enabled(name string) bool {
+	return w.current.Load().Features.Enabled(name)
+}
+
+// Reload reads the configuration files again and applies their changed
+// reloadable settings. It fails, keeping the current configuration, when the
+// files are invalid or a subscriber rejects the new configuration.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	loadedMu.RLock()
+	dir := loadedDir
+	loadedMu.RUnlock()
+
+	v, env, err := read(dir)
+	if err != nil {
+		return err
+	}
+	var next Config
+	if err := v.Unmarshal(&next); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	next.SetAppEnv(AppEnv(env))
+
+	settings := map[string]any{}
+	flatten("", v.AllSettings(), settings)
+	changed, rejected := w.diff(settings)
+	for _, key := range rejected {
+		// The effective settings keep the values of the restart.
+		v.Set(key, w.initial[key])
+	}
+	if len(rejected) > 0 {
+		w.logger.Warn("config reload rejected settings requiring a restart",
+			logging.String("keys", strings.Join(rejected, ", ")))
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	cfg := w.Current()
+	for _, r := range reloadable {
+		r.copy(&cfg, &next.Platform)
+	}
+	applies := make([]func(), 0, len(w.subscribers))
+	for _, sub := range w.subscribers {
+		if !sub.watches(changed) {
+			continue
+		}
+		apply, err := sub.fn(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid reloaded config: %w", err)
+		}
+		applies = append(applies, apply)
+	}
+	for _, apply := range applies {
+		apply()
+	}
+
+	w.current.Store(&cfg)
+	w.applied = settings
+	loadedMu.Lock()
+	loaded = v
+	loadedMu.Unlock()
+
+	w.logger.Info("config reloaded", logging.String("keys", strings.Join(changed, ", ")))
+
+	return nil
+}
+
+// watches reports whether the subscriber is called for the changed keys.
+func (s subscriber) watches(changed []string) bool {
+	if len(s.keys) == 0 {
+		return true
+	}
+	for _, key := range changed {
+		for _, k := range s.keys {
+			if key == k || strings.HasPrefix(key, k+".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// diff returns the sorted keys of the reloadable settings changed since the
+// last reload, and of the other settings changed since the start.
+func (w *Watcher) diff(settings map[string]any) (changed, rejected []string) {
+	keys := map[string]struct{}{}
+	for _, m := range []map[string]any{settings, w.initial, w.applied} {
+		for key := range m {
+			keys[key] = struct{}{}
+		}
+	}
+
+	for key := range keys {
+		switch {
+		case !isReloadable(key):
+			if !reflect.DeepEqual(settings[key], w.initial[key]) {
+				rejected = append(rejected, key)
+			}
+		case !reflect.DeepEqual(settings[key], w.applied[key]):
+			changed = append(changed, key)
+		}
+	}
+	slices.Sort(changed)
+	slices.Sort(rejected)
+
+	return changed, rejected
+}
+
+func isReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r.key || strings.HasPrefix(key, r.key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flatten puts the values of the nested settings in flat, by dotted key.
+// Arrays, e.g. of rate limit policies, are values.
+func flatten(prefix string, settings map[string]any, flat map[string]any) {
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, flat)
+
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// Run reloads the configuration on SIGHUP and when its file changes, until
+// ctx is done. The directory of the file is watched, to also catch the files
+// replaced by editors or by the symlink swaps of Kubernetes ConfigMaps.
+func (w *Watcher) Run(ctx context.Context) error {
+	loadedMu.RLock()
+	dir := loadedDir
+	loadedMu.RUnlock()
+	if dir == "" {
+		dir = "."
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	defer fsw.Close()
+	if err := fsw.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	name := fileName(string(w.Current().AppEnv))
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			w.reload("SIGHUP")
+		case event := <-fsw.Events:
+			base := filepath.Base(event.Name)
+			if event.Op != fsnotify.Chmod && (base == name || strings.HasPrefix(base, "..")) {
+				debounce.Reset(reloadDebounce)
+			}
+		case err := <-fsw.Errors:
+			w.logger.Error("config watcher failed", logging.String("error", err.Error()))
+		case <-debounce.C:
+			w.reload("file change")
+		}
+	}
+}
+
+func (w *Watcher) reload(trigger string) {
+	if err := w.Reload(); err != nil {
+		w.logger.Error("failed to reload config, keeping the current one",
+			logging.String("trigger", trigger), logging.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
+)
+
+const watchedConfig = `
+[platform.server]
+listen = ["tcp://:8080"]
+
+[platform.server.cors]
+allowed-origins = ["https://app.example.com"]
+
+[platform.log]
+level = "info"
+`
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config_development.toml"), []byte(content), 0o600))
+}
+
+func loadWatched(t *testing.T) (string, *Watcher) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeConfig(t, dir, watchedConfig)
+	os.Setenv("APP_ENV", "development")
+	t.Cleanup(func() { os.Unsetenv("APP_ENV") })
+
+	cfg := &Config{}
+	require.NoError(t, Load(dir, cfg))
+
+	return dir, NewWatcher(cfg.Platform, zap.NewNop())
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	dir, w := loadWatched(t)
+	var reloaded []Platform
+	w.Subscribe(func(cfg Platform) (func(), error) {
+		return func() { reloaded = append(reloaded, cfg) }, nil
+	})
+
+	t.Run("Unchanged file", func(t *testing.T) {
+		require.NoError(t, w.Reload())
+		assert.Empty(t, reloaded)
+	})
+
+	t.Run("Reloadable and rejected settings", func(t *testing.T) {
+		writeConfig(t, dir, `
+[platform.server]
+listen = ["tcp://:9090"]
+
+[platform.server.cors]
+allowed-origins = ["https://admin.example.com"]
+
+[platform.database]
+url = "postgres://localhost/other"
+
+[platform.features]
+new-search = true
+
+[platform.log]
+level = "debug"
+`)
+		require.NoError(t, w.Reload())
+		require.Len(t, reloaded, 1)
+
+		cfg := w.Current()
+		assert.Equal(t, reloaded[0], cfg)
+		assert.Equal(t, "debug", cfg.Log.Level)
+		assert.Equal(t, []string{"https://admin.example.com"}, cfg.Server.CORS.AllowedOrigins)
+		assert.True(t, cfg.Features.Enabled("new-search"))
+		assert.False(t, cfg.Features.Enabled("other"))
+		assert.Equal(t, []string{"tcp://:8080"}, cfg.Server.Listen, "the listen addresses require a restart")
+		assert.NotEqual(t, "postgres://localhost/other", cfg.Database.URL, "the database requires a restart")
+
+		server, ok := Settings()["platform"].(map[string]any)["server"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, []any{"tcp://:8080"}, server["listen"], "the effective settings keep the rejected values")
+	})
+
+	t.Run("Only rejected settings changed", func(t *testing.T) {
+		require.NoError(t, w.Reload())
+		assert.Len(t, reloaded, 1)
+	})
+}
+
+func TestWatcher_SubscribeKeys(t *testing.T) {
+	dir, w := loadWatched(t)
+	var levels []string
+	w.Subscribe(func(cfg Platform) (func(), error) {
+		return func() { levels = append(levels, cfg.Log.Level) }, nil
+	}, "platform.log.level")
+
+	writeConfig(t, dir, watchedConfig+`
+[platform.features]
+new-search = true
+`)
+	require.NoError(t, w.Reload())
+	assert.Empty(t, levels, "the subscriber is not called by the reloads of other keys")
+	assert.True(t, w.Enabled("new-search"))
+
+	writeConfig(t, dir, `
+[platform.server]
+listen = ["tcp://:8080"]
+
+[platform.server.cors]
+allowed-origins = ["https://app.example.com"]
+
+[platform.log]
+level = "debug"
+`)
+	require.NoError(t, w.Reload())
+	assert.Equal(t, []string{"debug"}, levels)
+	assert.False(t, w.Enabled("new-search"))
+}
+
+func TestWatcher_ReloadInvalid(t *testing.T) {
+	dir, w := loadWatched(t)
+	errInvalid := errors.New("invalid level")
+	applied := false
+	w.Subscribe(func(Platform) (func(), error) { return func() { applied = true }, nil })
+	w.Subscribe(func(cfg Platform) (func(), error) {
+		if cfg.Log.Level == "verbose" {
+			return nil, errInvalid
+		}
+
+		return func() {}, nil
+	})
+
+	writeConfig(t, dir, `[platform.log`)
+	require.Error(t, w.Reload())
+
+	writeConfig(t, dir, `
+[platform.log]
+level = "verbose"
+`)
+	require.ErrorIs(t, w.Reload(), errInvalid)
+	assert.False(t, applied, "a rejected config is applied by no subscriber")
+	assert.Equal(t, "info", w.Current().Log.Level)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	dir, w := loadWatched(t)
+	reloaded := make(chan string, 2)
+	w.Subscribe(func(cfg Platform) (func(), error) {
+		return func() { reloaded <- cfg.Log.Level }, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	// Run watches the directory once started.
+	require.Eventually(t, func() bool {
+		writeConfig(t, dir, `
+[platform.log]
+level = "warn"
+`)
+		select {
+		case level := <-reloaded:
+			return level == "warn"
+		case <-time.After(200 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeConfig(t, dir, watchedConfig)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case level := <-reloaded:
+		assert.Equal(t, "info", level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload")
+	}
+}
@@ -2,6 +2,7 @@ package zap
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
 	"go.uber.org/zap"
@@ -28,6 +29,7 @@ func NewProduction(level string) (logging.Logger, error) {
 	return &zapLogger{
 		logger:        logger,
 		sugaredLogger: logger.Sugar(),
+		level:         &cfg.Level,
 	}, nil
 }
 
@@ -49,6 +51,7 @@ func NewDevelopment(level string) (logging.Logger, error) {
 	return &zapLogger{
 		logger:        logger,
 		sugaredLogger: logger.Sugar(),
+		level:         &cfg.Level,
 	}, nil
 }
 
@@ -72,6 +75,35 @@ func NewNop() logging.Logger {
 	}
 }
 
+// LevelHandler returns the handler of the level of logger, created by
+// NewProduction or NewDevelopment: GET responds {"level":"info"} and PUT with
+// such a body changes the level of the running logger and its derived ones.
+// It returns nil for the other loggers.
+func LevelHandler(logger logging.Logger) http.Handler {
+	l, ok := logger.(*zapLogger)
+	if !ok || l.level == nil {
+		return nil
+	}
+
+	return l.level
+}
+
+// ReloadLevel validates level and returns the function setting it as the level
+// of logger and its derived ones, see config.Watcher. The function does nothing
+// for the loggers without a level handler, see LevelHandler.
+func ReloadLevel(logger logging.Logger, level string) (func(), error) {
+	zapLevel, err := parseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if l, ok := logger.(*zapLogger); ok && l.level != nil {
+			l.level.SetLevel(zapLevel)
+		}
+	}, nil
+}
+
 // Must panics if logger creation fails
 func Must(logger logging.Logger, err error) logging.Logger {
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -15,6 +16,8 @@ import (
 type zapLogger struct {
 	logger        *zap.Logger
 	sugaredLogger *zap.SugaredLogger
+	// level is shared by the loggers derived with With and Named, nil for the no-op logger.
+	level *zap.AtomicLevel
 }
 
 // Compile-time interface check
@@ -71,53 +74,77 @@ func (l *zapLogger) Panicf(template string, args ...any) {
 }
 
 // Context-aware structured logging
-func (l *zapLogger) DebugContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Debug(msg, toZapFields(fields)...)
+func (l *zapLogger) DebugContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Debug(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
-func (l *zapLogger) InfoContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Info(msg, toZapFields(fields)...)
+func (l *zapLogger) InfoContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Info(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
-func (l *zapLogger) WarnContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Warn(msg, toZapFields(fields)...)
+func (l *zapLogger) WarnContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Warn(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
-func (l *zapLogger) ErrorContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Error(msg, toZapFields(fields)...)
+func (l *zapLogger) ErrorContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Error(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
-func (l *zapLogger) FatalContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Fatal(msg, toZapFields(fields)...)
+func (l *zapLogger) FatalContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Fatal(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
-func (l *zapLogger) PanicContext(_ context.Context, msg string, fields ...logging.Field) {
-	l.logger.Panic(msg, toZapFields(fields)...)
+func (l *zapLogger) PanicContext(ctx context.Context, msg string, fields ...logging.Field) {
+	l.logger.Panic(msg, append(toZapFields(fields), traceFields(ctx)...)...)
 }
 
 // Context-aware sugared logging
-func (l *zapLogger) DebugfContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Debugf(template, args...)
+func (l *zapLogger) DebugfContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Debugf(template, args...)
 }
 
-func (l *zapLogger) InfofContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Infof(template, args...)
+func (l *zapLogger) InfofContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Infof(template, args...)
 }
 
-func (l *zapLogger) WarnfContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Warnf(template, args...)
+func (l *zapLogger) WarnfContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Warnf(template, args...)
 }
 
-func (l *zapLogger) ErrorfContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Errorf(template, args...)
+func (l *zapLogger) ErrorfContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Errorf(template, args...)
 }
 
-func (l *zapLogger) FatalfContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Fatalf(template, args...)
+func (l *zapLogger) FatalfContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Fatalf(template, args...)
 }
 
-func (l *zapLogger) PanicfContext(_ context.Context, template string, args ...any) {
-	l.sugaredLogger.Panicf(template, args...)
+func (l *zapLogger) PanicfContext(ctx context.Context, template string, args ...any) {
+	l.withTrace(ctx).Panicf(template, args...)
+}
+
+// traceFields returns the trace_id and span_id fields of the span of ctx, if
+// any, to correlate the logs with the traces.
+func traceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+// withTrace returns the sugared logger with the trace fields of ctx.
+func (l *zapLogger) withTrace(ctx context.Context) *zap.SugaredLogger {
+	fields := traceFields(ctx)
+	if fields == nil {
+		return l.sugaredLogger
+	}
+
+	return l.sugaredLogger.Desugar().With(fields...).Sugar()
 }
 
 // Logger manipulation
@@ -128,6 +155,7 @@ func (l *zapLogger) With(fields ...logging.Field) logging.Logger {
 	return &zapLogger{
 		logger:        newLogger,
 		sugaredLogger: newLogger.Sugar(),
+		level:         l.level,
 	}
 }
 
@@ -135,6 +163,7 @@ func (l *zapLogger) Named(name string) logging.Logger {
 	return &zapLogger{
 		logger:        l.logger.Named(name),
 		sugaredLogger: l.sugaredLogger.Named(name),
+		level:         l.level,
 	}
 }
 
@@ -1,12 +1,19 @@
 package zap
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // mockObjectMarshaler is a test implementation of ObjectMarshaler
@@ -655,3 +662,99 @@ func TestLoggerIntegration_ErrorHandling(t *testing.T) {
 		t.Log("Nil error logging executed successfully")
 	})
 }
+
+func TestZapLogger_TraceFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	base := zap.New(core)
+	l := &zapLogger{logger: base, sugaredLogger: base.Sugar()}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	l.InfoContext(ctx, "structured")
+	l.InfofContext(ctx, "sugared %d", 1)
+	l.InfoContext(context.Background(), "untraced")
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for _, e := range entries[:2] {
+		fields := e.ContextMap()
+		if fields["trace_id"] != traceID.String() || fields["span_id"] != spanID.String() {
+			t.Errorf("%q: expected trace fields, got %v", e.Message, fields)
+		}
+	}
+	if _, ok := entries[2].ContextMap()["trace_id"]; ok {
+		t.Errorf("expected no trace fields without a span")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger, err := NewProduction("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived := logger.With(logging.String("component", "test")).(*zapLogger)
+
+	handler := LevelHandler(logger)
+	if handler == nil {
+		t.Fatal("expected a level handler")
+	}
+	if derived.logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("expected debug to be disabled")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if !derived.logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("expected debug to be enabled on the derived logger")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	if !strings.Contains(rec.Body.String(), `"level":"debug"`) {
+		t.Errorf("expected the debug level, got %s", rec.Body)
+	}
+
+	if LevelHandler(NewNop()) != nil {
+		t.Error("expected no level handler for the no-op logger")
+	}
+}
+
+func TestReloadLevel(t *testing.T) {
+	logger, err := NewProduction("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived := logger.Named("test").(*zapLogger)
+
+	if _, err := ReloadLevel(logger, "verbose"); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+
+	apply, err := ReloadLevel(logger, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("expected debug to be disabled before apply")
+	}
+	apply()
+	if !derived.logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Error("expected debug to be enabled on the derived logger")
+	}
+
+	apply, err = ReloadLevel(NewNop(), "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply()
+}
@@ -0,0 +1,87 @@
+// Package metrics exposes the Prometheus metrics of the application.
+//
+// The label values of the metrics are bounded: procedures registered on the
+// server, status codes, query and repository names, roles... never ids,
+// emails or other values coming from the requests.
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Registry holds the metrics of the application, along with the ones of the
+// Go runtime and of the process.
+type Registry struct {
+	registry *prometheus.Registry
+	prefixed prometheus.Registerer
+	buckets  []float64
+}
+
+// NewRegistry returns a registry prefixing the names of the metrics of the
+// application with the configured namespace.
+func NewRegistry(cfg config.MetricsConfig) *Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	prefixed := prometheus.Registerer(registry)
+	if cfg.Namespace != "" {
+		prefixed = prometheus.WrapRegistererWithPrefix(cfg.Namespace+"_", registry)
+	}
+
+	buckets := cfg.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	return &Registry{registry: registry, prefixed: prefixed, buckets: buckets}
+}
+
+// Registerer registers the metrics of the application, under the namespace.
+func (r *Registry) Registerer() prometheus.Registerer {
+	return r.prefixed
+}
+
+// Buckets returns the upper bounds, in seconds, of the duration histograms.
+func (r *Registry) Buckets() []float64 {
+	return r.buckets
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{
+		Registry:      r.registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// RegisterDB exposes the statistics of the connection pool of db, labeled
+// with name, as go_sql_* metrics.
+func (r *Registry) RegisterDB(db *sql.DB, name string) error {
+	if err := r.registry.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		return fmt.Errorf("failed to register %s database metrics: %w", name, err)
+	}
+
+	return nil
+}
+
+// Register registers collectors under the namespace.
+func (r *Registry) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := r.prefixed.Register(c); err != nil {
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+// Package principal carries the identity of the authenticated caller in the
+// context. Authentication interceptors set it, rate limiting and auditing read it.
+package principal
+
+import "context"
+
+type (
+	ctxKey       struct{}
+	apiKeyCtxKey struct{}
+)
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// Get returns the principal of ctx, or "" for anonymous calls.
+func Get(ctx context.Context) string {
+	v := ctx.Value(ctxKey{})
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return ""
+}
+
+// WithAPIKey returns ctx carrying the API key of the caller, once verified by
+// an authentication layer. Keys merely sent by the client must not be set.
+func WithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey{}, key)
+}
+
+// APIKey returns the verified API key of ctx, or "" for the calls without.
+func APIKey(ctx context.Context) string {
+	v := ctx.Value(apiKeyCtxKey{})
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return ""
+}
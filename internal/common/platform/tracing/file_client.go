@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fileClient is an OTLP client writing every export request as a line of
+// JSON, the format of the OpenTelemetry collector file receiver.
+type fileClient struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+var _ otlptrace.Client = (*fileClient)(nil)
+
+func newFileClient(w io.WriteCloser) *fileClient {
+	return &fileClient{w: w}
+}
+
+func (c *fileClient) Start(context.Context) error {
+	return nil
+}
+
+func (c *fileClient) Stop(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.w.Close(); err != nil {
+		return fmt.Errorf("failed to close trace file: %w", err)
+	}
+
+	return nil
+}
+
+func (c *fileClient) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := protojson.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return fmt.Errorf("failed to encode traces: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write traces: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package tracing sets up the OpenTelemetry traces of the application.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Setup installs the global tracer provider exporting the traces as configured,
+// and the W3C trace context and baggage propagators. The returned function
+// flushes the pending spans and stops the exporter, it must be called on exit.
+// When tracing is disabled, the global no-op provider is kept.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider, err := NewTracerProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to shut down tracing: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+// NewTracerProvider returns a provider sampling the configured ratio of the
+// new traces and batching the spans to the configured exporter.
+func NewTracerProvider(ctx context.Context, cfg config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample-ratio must be between 0 and 1, got %v", cfg.SampleRatio)
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tracing resource: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	), nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+
+		return exporter, nil
+	case config.TracingExporterOTLPFile:
+		if cfg.File == "" {
+			return nil, errors.New("the otlp-file trace exporter requires a file")
+		}
+		f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+
+		return newOTLPExporter(ctx, newFileClient(f))
+	case config.TracingExporterOTLP:
+		if cfg.Endpoint == "" {
+			return nil, errors.New("the otlp trace exporter requires an endpoint")
+		}
+
+		return newOTLPExporter(ctx, otlptracehttp.NewClient(
+			otlptracehttp.WithEndpointURL(cfg.Endpoint),
+			otlptracehttp.WithHeaders(cfg.Headers),
+		))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q: want stdout, otlp-file or otlp", cfg.Exporter)
+	}
+}
+
+func newOTLPExporter(ctx context.Context, client otlptrace.Client) (sdktrace.SpanExporter, error) {
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	return exporter, nil
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestNewTracerProvider_OTLPFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	provider, err := NewTracerProvider(context.Background(), config.TracingConfig{
+		ServiceName: "test",
+		Exporter:    config.TracingExporterOTLPFile,
+		File:        file,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	_, span := provider.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID string `json:"traceId"`
+					Name    string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "work", spans[0].Name)
+	assert.NotEmpty(t, spans[0].TraceID)
+	assert.Contains(t, lines[0], `"stringValue":"test"`)
+}
+
+func TestNewTracerProvider_Sampling(t *testing.T) {
+	provider, err := NewTracerProvider(context.Background(), config.TracingConfig{
+		Exporter: config.TracingExporterOTLPFile,
+		File:     filepath.Join(t.TempDir(), "traces.jsonl"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	_, span := provider.Tracer("test").Start(context.Background(), "work")
+	defer span.End()
+	assert.False(t, span.SpanContext().IsSampled())
+	assert.True(t, span.SpanContext().IsValid())
+}
+
+func TestNewTracerProvider_Invalid(t *testing.T) {
+	for name, cfg := range map[string]config.TracingConfig{
+		"ratio above one":     {Exporter: config.TracingExporterStdout, SampleRatio: 1.5},
+		"negative ratio":      {Exporter: config.TracingExporterStdout, SampleRatio: -0.1},
+		"unknown exporter":    {Exporter: "jaeger"},
+		"file without path":   {Exporter: config.TracingExporterOTLPFile},
+		"otlp without target": {Exporter: config.TracingExporterOTLP},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTracerProvider(context.Background(), cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "jaeger"})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
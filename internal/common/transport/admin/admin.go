@@ -0,0 +1,240 @@
+// Package admin serves the debugging and operation endpoints of the
+// application on a listener of its own, bound to localhost or a unix socket,
+// never on the public port.
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Paths of the admin endpoints.
+const (
+	PprofPath     = "/debug/pprof/"
+	BuildInfoPath = "/buildinfo"
+	ConfigPath    = "/config"
+	RuntimePath   = "/runtime"
+	LogLevelPath  = "/loglevel"
+	ReadinessPath = "/readyz"
+)
+
+// Option configures the admin handler.
+type Option func(*handler)
+
+// WithBuildInfo adds fields to the build info, e.g. the build time set at link time.
+func WithBuildInfo(fields map[string]string) Option {
+	return func(h *handler) {
+		for k, v := range fields {
+			h.buildFields[k] = v
+		}
+	}
+}
+
+// WithSettings serves the settings returned by settings, whose secrets must be
+// redacted, see config.Settings.
+func WithSettings(settings func() map[string]any) Option {
+	return func(h *handler) {
+		h.settings = settings
+	}
+}
+
+// WithDB adds the connection pool statistics of db, under name, to the runtime info.
+func WithDB(name string, db *sql.DB) Option {
+	return func(h *handler) {
+		h.dbs[name] = db
+	}
+}
+
+// WithLogLevel serves the level handler of the running logger, see zap.LevelHandler.
+func WithLogLevel(level http.Handler) Option {
+	return func(h *handler) {
+		h.logLevel = level
+	}
+}
+
+// WithReadiness serves the readiness report with the errors of the checks,
+// left out of the public one, see connectx.Probes.ReadinessDetailsHandler.
+func WithReadiness(readiness http.Handler) Option {
+	return func(h *handler) {
+		h.readiness = readiness
+	}
+}
+
+// WithMetrics serves the metrics handler at path, e.g. the Prometheus
+// registry of the application, kept off the public port.
+func WithMetrics(path string, metrics http.Handler) Option {
+	return func(h *handler) {
+		h.metricsPath = path
+		h.metrics = metrics
+	}
+}
+
+type handler struct {
+	start       time.Time
+	buildFields map[string]string
+	settings    func() map[string]any
+	dbs         map[string]*sql.DB
+	logLevel    http.Handler
+	readiness   http.Handler
+	metricsPath string
+	metrics     http.Handler
+}
+
+// NewHandler returns the handler of the admin endpoints:
+//   - /debug/pprof/: the net/http/pprof profiles;
+//   - GET /buildinfo: the Go version, module and VCS info of the binary;
+//   - GET /config: the effective settings, WithSettings;
+//   - GET /runtime: goroutines, memory and database pool statistics;
+//   - GET and PUT /loglevel: the level of the running logger, WithLogLevel;
+//   - GET /readyz: the readiness report with the errors of the checks, WithReadiness;
+//   - GET of the metrics path: the metrics, WithMetrics.
+func NewHandler(opts ...Option) http.Handler {
+	h := &handler{
+		start:       time.Now(),
+		buildFields: map[string]string{},
+		dbs:         map[string]*sql.DB{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	mux.HandleFunc("GET "+BuildInfoPath, h.buildInfo)
+	mux.HandleFunc("GET "+RuntimePath, h.runtime)
+	if h.settings != nil {
+		mux.HandleFunc("GET "+ConfigPath, func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, h.settings())
+		})
+	}
+	if h.logLevel != nil {
+		mux.Handle("GET "+LogLevelPath, h.logLevel)
+		mux.Handle("PUT "+LogLevelPath, h.logLevel)
+	}
+	if h.readiness != nil {
+		mux.Handle("GET "+ReadinessPath, h.readiness)
+	}
+	if h.metrics != nil {
+		mux.Handle("GET "+h.metricsPath, h.metrics)
+	}
+
+	return mux
+}
+
+// BuildInfo describes the binary.
+type BuildInfo struct {
+	GoVersion string            `json:"goVersion"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+func (h *handler) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	info := BuildInfo{GoVersion: runtime.Version(), Fields: h.buildFields}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Main.Path
+		info.Version = bi.Main.Version
+		info.Settings = make(map[string]string, len(bi.Settings))
+		for _, s := range bi.Settings {
+			info.Settings[s.Key] = s.Value
+		}
+	}
+
+	writeJSON(w, info)
+}
+
+// RuntimeInfo is a snapshot of the state of the process.
+type RuntimeInfo struct {
+	Uptime     string                 `json:"uptime"`
+	Goroutines int                    `json:"goroutines"`
+	GOMAXPROCS int                    `json:"gomaxprocs"`
+	NumCPU     int                    `json:"numCpu"`
+	Memory     MemoryInfo             `json:"memory"`
+	DB         map[string]DBPoolStats `json:"db,omitempty"`
+}
+
+// MemoryInfo are the main memory statistics of the Go runtime.
+type MemoryInfo struct {
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapInuseBytes uint64 `json:"heapInuseBytes"`
+	SysBytes       uint64 `json:"sysBytes"`
+	NumGC          uint32 `json:"numGc"`
+	LastGC         string `json:"lastGc,omitempty"`
+}
+
+// DBPoolStats are the statistics of a database connection pool.
+type DBPoolStats struct {
+	MaxOpen           int    `json:"maxOpen"`
+	Open              int    `json:"open"`
+	InUse             int    `json:"inUse"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"waitCount"`
+	WaitDuration      string `json:"waitDuration"`
+	MaxIdleClosed     int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64  `json:"maxLifetimeClosed"`
+}
+
+func (h *handler) runtime(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	info := RuntimeInfo{
+		Uptime:     time.Since(h.start).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Memory: MemoryInfo{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapInuseBytes: mem.HeapInuse,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+		},
+	}
+	if mem.LastGC > 0 {
+		info.Memory.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339) //nolint:gosec // nanoseconds since 1970
+	}
+
+	if len(h.dbs) > 0 {
+		info.DB = make(map[string]DBPoolStats, len(h.dbs))
+	}
+	for name, db := range h.dbs {
+		s := db.Stats()
+		info.DB[name] = DBPoolStats{
+			MaxOpen:           s.MaxOpenConnections,
+			Open:              s.OpenConnections,
+			InUse:             s.InUse,
+			Idle:              s.Idle,
+			WaitCount:         s.WaitCount,
+			WaitDuration:      s.WaitDuration.String(),
+			MaxIdleClosed:     s.MaxIdleClosed,
+			MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+			MaxLifetimeClosed: s.MaxLifetimeClosed,
+		}
+	}
+
+	writeJSON(w, info)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, h http.Handler, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+	return rec
+}
+
+func TestNewHandler(t *testing.T) {
+	level := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Method+" level")
+	})
+	h := NewHandler(
+		WithBuildInfo(map[string]string{"buildTime": "2026-10-18"}),
+		WithSettings(func() map[string]any { return map[string]any{"platform": map[string]any{"admin": "on"}} }),
+		WithLogLevel(level),
+		WithReadiness(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "readiness details")
+		})),
+		WithMetrics("/metrics", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "metrics")
+		})),
+	)
+
+	t.Run("build info", func(t *testing.T) {
+		rec := get(t, h, http.MethodGet, BuildInfoPath)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var info BuildInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+		assert.True(t, strings.HasPrefix(info.GoVersion, "go"))
+		assert.Equal(t, "2026-10-18", info.Fields["buildTime"])
+	})
+
+	t.Run("runtime", func(t *testing.T) {
+		rec := get(t, h, http.MethodGet, RuntimePath)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var info RuntimeInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+		assert.Positive(t, info.Goroutines)
+		assert.Positive(t, info.Memory.SysBytes)
+		assert.Empty(t, info.DB)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		rec := get(t, h, http.MethodGet, ConfigPath)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"platform": {"admin": "on"}}`, rec.Body.String())
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("log level", func(t *testing.T) {
+		assert.Equal(t, "GET level", get(t, h, http.MethodGet, LogLevelPath).Body.String())
+		assert.Equal(t, "PUT level", get(t, h, http.MethodPut, LogLevelPath).Body.String())
+		assert.Equal(t, http.StatusMethodNotAllowed, get(t, h, http.MethodPost, LogLevelPath).Code)
+	})
+
+	t.Run("readiness", func(t *testing.T) {
+		assert.Equal(t, "readiness details", get(t, h, http.MethodGet, ReadinessPath).Body.String())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		assert.Equal(t, "metrics", get(t, h, http.MethodGet, "/metrics").Body.String())
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		rec := get(t, h, http.MethodGet, PprofPath)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "goroutine")
+	})
+}
+
+func TestNewHandler_WithoutOptions(t *testing.T) {
+	h := NewHandler()
+
+	assert.Equal(t, http.StatusNotFound, get(t, h, http.MethodGet, ConfigPath).Code)
+	assert.Equal(t, http.StatusNotFound, get(t, h, http.MethodPut, LogLevelPath).Code)
+	assert.Equal(t, http.StatusNotFound, get(t, h, http.MethodGet, ReadinessPath).Code)
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close(), "a stale socket file is replaced")
+
+	srv, err := NewServer("unix://"+path, NewHandler())
+	require.NoError(t, err)
+	assert.Equal(t, "unix://"+path, srv.Addr())
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://admin" + BuildInfoPath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	require.NoError(t, <-served)
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestServer_KeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := NewServer("unix://"+path, NewHandler())
+	require.Error(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestServer_InvalidAddress(t *testing.T) {
+	for _, addr := range []string{
+		"tcp://:0", "tcp://0.0.0.0:0", "tcp://[::]:0", "tcp://192.0.2.1:0", "tcp://example.com:0",
+		"localhost:0", "unix:/tmp/admin.sock", "systemd:",
+	} {
+		t.Run(addr, func(t *testing.T) {
+			_, err := NewServer(addr, NewHandler())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServer_TCP(t *testing.T) {
+	srv, err := NewServer("tcp://127.0.0.1:0", NewHandler())
+	require.NoError(t, err)
+	go func() { _ = srv.Serve() }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	resp, err := http.Get("http://" + strings.TrimPrefix(srv.Addr(), "tcp://") + RuntimePath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+)
+
+// readHeaderTimeout bounds the reading of the request headers of the admin clients.
+const readHeaderTimeout = 10 * time.Second
+
+// Server is the admin listener.
+type Server struct {
+	lis        net.Listener
+	httpServer *http.Server
+}
+
+// NewServer listens on addr for handler, in the format of the listen addresses,
+// see connectx.ParseListenAddress: a loopback TCP address, e.g.
+// "tcp://localhost:4230", or a unix socket, e.g. "unix:///run/app/admin.sock".
+// The admin endpoints are never served on other interfaces. A stale socket file
+// is replaced, other files are kept, and the socket is only accessible to the
+// user of the process.
+// The profiles of pprof take longer than any write timeout, the server has none.
+func NewServer(addr string, handler http.Handler) (*Server, error) {
+	lis, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		lis: lis,
+		httpServer: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}, nil
+}
+
+// ParseAddress parses the admin address addr, see NewServer.
+func ParseAddress(addr string) (connectx.ListenAddress, error) {
+	parsed, err := connectx.ParseListenAddress(addr)
+	if err != nil {
+		return connectx.ListenAddress{}, fmt.Errorf("invalid admin address: %w", err)
+	}
+
+	switch parsed.Scheme {
+	case connectx.ListenTCP:
+		if err := checkLoopback(parsed.Address); err != nil {
+			return connectx.ListenAddress{}, err
+		}
+	case connectx.ListenUnix:
+	default:
+		return connectx.ListenAddress{}, fmt.Errorf("admin address %q: only tcp and unix addresses are supported", addr)
+	}
+
+	return parsed, nil
+}
+
+func listen(addr string) (net.Listener, error) {
+	parsed, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Scheme == connectx.ListenUnix {
+		lis, err := connectx.ListenUnixSocket(parsed.Address, 0o600, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on admin socket: %w", err)
+		}
+
+		return lis, nil
+	}
+	lis, err := net.Listen("tcp", parsed.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on admin address: %w", err)
+	}
+
+	return lis, nil
+}
+
+// checkLoopback fails when the host of addr is not localhost or a loopback IP,
+// e.g. ":4230", listening on every interface.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid admin address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("admin address %q is not a loopback address, e.g. tcp://localhost:4230", addr)
+	}
+
+	return nil
+}
+
+// Addr returns the address the server listens on, in the listen address format.
+func (s *Server) Addr() string {
+	return connectx.ListenerAddr(s.lis)
+}
+
+// Serve serves the admin endpoints until Shutdown.
+func (s *Server) Serve() error {
+	if err := s.httpServer.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("admin server failed: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown stops the server, waiting for the requests in flight until ctx is
+// done, then closes them. The socket file is removed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		_ = s.httpServer.Close()
+
+		return fmt.Errorf("failed to shut down admin server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package apidocs serves an OpenAPI document, enriched with the apperr catalog,
+// and a self-contained documentation page rendering it.
+package apidocs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Register serves the OpenAPI document at SpecPath and the docs page at DocsPath.
+// spec is the YAML (or JSON) document generated by protoc-gen-openapi.
+func Register(mux *http.ServeMux, spec []byte, catalog []apperr.Definition) error {
+	doc, err := BuildSpec(spec, catalog)
+	if err != nil {
+		return err
+	}
+
+	mux.HandleFunc("GET "+SpecPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+	mux.HandleFunc("GET "+DocsPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(docsPage)
+	})
+
+	return nil
+}
+
+// BuildSpec converts the generated document to JSON and documents the error
+// responses: an Error schema, an ErrorCode enum listing the catalog, and a
+// default error response on every operation.
+func BuildSpec(spec []byte, catalog []apperr.Definition) ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if doc == nil {
+		return nil, errors.New("failed to parse OpenAPI document: empty document")
+	}
+
+	schemas := child(child(doc, "components"), "schemas")
+	schemas["Error"] = errorSchema()
+	schemas["ErrorCode"] = errorCodeSchema(catalog)
+
+	codes := make([]map[string]any, len(catalog))
+	for i, d := range catalog {
+		codes[i] = map[string]any{"code": d.Code, "httpStatus": d.HTTPStatus, "description": d.Description}
+	}
+	doc["x-error-codes"] = codes
+
+	for _, item := range child(doc, "paths") {
+		pathItem, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for _, method := range operationMethods {
+			op, ok := pathItem[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			responses := child(op, "responses")
+			if _, ok := responses["default"]; !ok {
+				responses["default"] = errorResponse()
+			}
+		}
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+
+	return b, nil
+}
+
+func errorSchema() map[string]any {
+	return map[string]any{
+		"type":        "object",
+		"description": "Error body, the application error code is in the X-Error-Code header.",
+		"properties": map[string]any{
+			"code": map[string]any{
+				"type":        "string",
+				"description": "Connect error code, e.g. not_found or invalid_argument.",
+			},
+			"message": map[string]any{"type": "string"},
+			"details": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "object"},
+			},
+		},
+		"required": []string{"code"},
+	}
+}
+
+func errorCodeSchema(catalog []apperr.Definition) map[string]any {
+	var desc strings.Builder
+	desc.WriteString("Application error codes, sent in the X-Error-Code response header.\n\n")
+	desc.WriteString("| Code | HTTP status | Description |\n|---|---|---|\n")
+
+	enum := make([]string, len(catalog))
+	for i, d := range catalog {
+		enum[i] = d.Code
+		desc.WriteString("| `" + d.Code + "` | " + strconv.Itoa(d.HTTPStatus) + " | " + d.Description + " |\n")
+	}
+
+	return map[string]any{"type": "string", "enum": enum, "description": desc.String()}
+}
+
+func errorResponse() map[string]any {
+	return map[string]any{
+		"description": "Error, see ErrorCode for the application error codes.",
+		"headers": map[string]any{
+			"X-Error-Code": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/ErrorCode"},
+			},
+		},
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+}
+
+// child returns m[key] as a map, creating it when missing.
+func child(m map[string]any, key string) map[string]any {
+	if c, ok := m[key].(map[string]any); ok {
+		return c
+	}
+
+	c := map[string]any{}
+	m[key] = c
+
+	return c
+}
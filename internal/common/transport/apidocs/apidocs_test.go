@@ -0,0 +1,87 @@
+package apidocs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+    title: Test API
+    version: v1
+paths:
+    /v1/things/{id}:
+        get:
+            operationId: ThingService_GetThing
+            responses:
+                "200":
+                    description: OK
+components:
+    schemas:
+        Thing:
+            type: object
+`
+
+func TestBuildSpec(t *testing.T) {
+	catalog := []apperr.Definition{
+		{Code: "thing.not_found", HTTPStatus: http.StatusNotFound, Description: "No such thing."},
+	}
+
+	b, err := BuildSpec([]byte(testSpec), catalog)
+	require.NoError(t, err)
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"schemas"`
+		} `json:"components"`
+		ErrorCodes []struct {
+			Code       string `json:"code"`
+			HTTPStatus int    `json:"httpStatus"`
+		} `json:"x-error-codes"` //nolint:tagliatelle // OpenAPI extension
+	}
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	assert.Contains(t, doc.Paths["/v1/things/{id}"]["get"].Responses, "200")
+	assert.Contains(t, doc.Paths["/v1/things/{id}"]["get"].Responses, "default")
+	assert.Contains(t, doc.Components.Schemas, "Thing")
+	assert.Contains(t, doc.Components.Schemas, "Error")
+	assert.Equal(t, []string{"thing.not_found"}, doc.Components.Schemas["ErrorCode"].Enum)
+	require.Len(t, doc.ErrorCodes, 1)
+	assert.Equal(t, http.StatusNotFound, doc.ErrorCodes[0].HTTPStatus)
+}
+
+func TestBuildSpec_Invalid(t *testing.T) {
+	_, err := BuildSpec([]byte("paths: [unclosed"), nil)
+	require.Error(t, err)
+
+	_, err = BuildSpec(nil, nil)
+	require.Error(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, Register(mux, []byte(testSpec), nil))
+
+	for path, contentType := range map[string]string{
+		SpecPath: "application/json",
+		DocsPath: "text/html; charset=utf-8",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, contentType, rec.Header().Get("Content-Type"), path)
+	}
+}
@@ -0,0 +1,142 @@
+package connectx
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Compression algorithms of the Connect, gRPC and REST calls.
+const (
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionBrotli = "br"
+)
+
+// acceptEncodingHeaders are the headers listing the compressions accepted by
+// clients in responses: Connect unary and REST, Connect streaming, gRPC.
+var acceptEncodingHeaders = []string{"Accept-Encoding", "Connect-Accept-Encoding", "Grpc-Accept-Encoding"}
+
+type compression struct {
+	newDecompressor func() connect.Decompressor
+	newCompressor   func() connect.Compressor
+}
+
+var compressions = map[string]compression{
+	CompressionGzip: {
+		newDecompressor: func() connect.Decompressor { return &gzip.Reader{} },
+		newCompressor:   func() connect.Compressor { return gzip.NewWriter(nil) },
+	},
+	CompressionZstd: {
+		newDecompressor: func() connect.Decompressor {
+			// Only fails with invalid options.
+			d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+
+			return zstdDecompressor{d}
+		},
+		newCompressor: func() connect.Compressor {
+			e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+			return e
+		},
+	},
+	CompressionBrotli: {
+		newDecompressor: func() connect.Decompressor { return brotliDecompressor{brotli.NewReader(nil)} },
+		newCompressor:   func() connect.Compressor { return brotli.NewWriter(nil) },
+	},
+}
+
+// zstdDecompressor keeps the decoders reusable: closing a zstd decoder releases it for good.
+type zstdDecompressor struct{ *zstd.Decoder }
+
+func (zstdDecompressor) Close() error { return nil }
+
+type brotliDecompressor struct{ *brotli.Reader }
+
+func (brotliDecompressor) Close() error { return nil }
+
+// NewCompressionOptions returns the handler options registering the
+// compressions accepted by cfg, gzip being removed when not accepted.
+// Use NewCompressionMiddleware to restrict the compressions of the responses.
+func NewCompressionOptions(cfg config.CompressionConfig) ([]connect.HandlerOption, error) {
+	var errs []error
+	if cfg.MinBytes < 0 {
+		errs = append(errs, fmt.Errorf("compression min-bytes must not be negative, got %d", cfg.MinBytes))
+	}
+	for _, name := range cfg.Accept {
+		if _, ok := compressions[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown compression %q", name))
+		}
+	}
+	for _, name := range cfg.Send {
+		if !slices.Contains(cfg.Accept, name) {
+			errs = append(errs, fmt.Errorf("compression %q is sent but not accepted", name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	opts := []connect.HandlerOption{connect.WithCompressMinBytes(cfg.MinBytes)}
+	if !slices.Contains(cfg.Accept, CompressionGzip) {
+		opts = append(opts, connect.WithCompression(CompressionGzip, nil, nil))
+	}
+	for _, name := range cfg.Accept {
+		c := compressions[name]
+		opts = append(opts, connect.WithCompression(name, c.newDecompressor, c.newCompressor))
+	}
+
+	return opts, nil
+}
+
+// NewCompressionMiddleware returns a middleware removing from the
+// Accept-Encoding headers of the requests the compressions cfg does not send,
+// so that responses only use the others. Responses to compressed requests
+// use the compression of the request, as required by gRPC.
+func NewCompressionMiddleware(cfg config.CompressionConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, header := range acceptEncodingHeaders {
+				values := r.Header.Values(header)
+				if len(values) == 0 {
+					continue
+				}
+
+				sent := filterEncodings(values, cfg.Send)
+				if len(sent) == 0 {
+					r.Header.Del(header)
+				} else {
+					r.Header.Set(header, strings.Join(sent, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// filterEncodings returns the encodings of the header values listed in
+// allowed, without their quality value that Connect ignores.
+func filterEncodings(values, allowed []string) []string {
+	var encodings []string
+	for _, value := range values {
+		for encoding := range strings.SplitSeq(value, ",") {
+			name, _, _ := strings.Cut(encoding, ";")
+			name = strings.TrimSpace(name)
+			if slices.Contains(allowed, name) {
+				encodings = append(encodings, name)
+			}
+		}
+	}
+
+	return encodings
+}
@@ -0,0 +1,124 @@
+package connectx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func newCompressionTestServer(t *testing.T, cfg config.CompressionConfig) *httptest.Server {
+	t.Helper()
+
+	opts, err := NewCompressionOptions(cfg)
+	require.NoError(t, err)
+
+	handler := connect.NewUnaryHandler("/test.v1.TestService/Echo",
+		func(_ context.Context, req *connect.Request[wrapperspb.StringValue]) (
+			*connect.Response[wrapperspb.StringValue], error,
+		) {
+			return connect.NewResponse(req.Msg), nil
+		}, opts...)
+	srv := httptest.NewServer(NewCompressionMiddleware(cfg)(handler))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// postEcho sends "hello", compressed with zstd when compress is true, and returns the response.
+func postEcho(t *testing.T, url string, compress bool, acceptEncoding string) *http.Response {
+	t.Helper()
+
+	body := bytes.NewBufferString(`"hello"`)
+	if compress {
+		body = &bytes.Buffer{}
+		w, err := zstd.NewWriter(body)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(`"hello"`))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url+"/test.v1.TestService/Echo", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if compress {
+		req.Header.Set("Content-Encoding", CompressionZstd)
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	res, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+
+	return res
+}
+
+func TestCompression(t *testing.T) {
+	t.Run("Responses use a sent compression", func(t *testing.T) {
+		srv := newCompressionTestServer(t, config.CompressionConfig{
+			Accept: []string{CompressionGzip, CompressionZstd, CompressionBrotli},
+			Send:   []string{CompressionGzip, CompressionBrotli},
+		})
+
+		res := postEcho(t, srv.URL, false, "zstd, br;q=0.9")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, CompressionBrotli, res.Header.Get("Content-Encoding"))
+		body, err := io.ReadAll(brotli.NewReader(res.Body))
+		require.NoError(t, err)
+		assert.JSONEq(t, `"hello"`, string(body))
+
+		// Responses to compressed requests use their compression.
+		res = postEcho(t, srv.URL, true, "br")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, CompressionZstd, res.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("Small responses are not compressed", func(t *testing.T) {
+		srv := newCompressionTestServer(t, config.CompressionConfig{
+			Accept:   []string{CompressionZstd},
+			Send:     []string{CompressionZstd},
+			MinBytes: 1024,
+		})
+
+		res := postEcho(t, srv.URL, true, CompressionZstd)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("Requests must use an accepted compression", func(t *testing.T) {
+		srv := newCompressionTestServer(t, config.CompressionConfig{Accept: []string{CompressionGzip}})
+
+		res := postEcho(t, srv.URL, true, CompressionGzip)
+		assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+	})
+}
+
+func TestNewCompressionOptions_Invalid(t *testing.T) {
+	_, err := NewCompressionOptions(config.CompressionConfig{
+		Accept:   []string{CompressionGzip, "deflate"},
+		Send:     []string{CompressionZstd},
+		MinBytes: -1,
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "min-bytes must not be negative")
+	assert.ErrorContains(t, err, `unknown compression "deflate"`)
+	assert.ErrorContains(t, err, `compression "zstd" is sent but not accepted`)
+}
+
+func TestFilterEncodings(t *testing.T) {
+	assert.Equal(t,
+		[]string{CompressionGzip, CompressionBrotli},
+		filterEncodings([]string{"gzip;q=1.0, deflate", "zstd,br"}, []string{CompressionGzip, CompressionBrotli}))
+	assert.Empty(t, filterEncodings([]string{"identity"}, []string{CompressionGzip}))
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
 )
 
+// statusClientClosedRequest is the non-standard status used for canceled requests.
+const statusClientClosedRequest = 499
+
 func ConnectCodeFromHTTPStatus(st int) connect.Code {
 	switch st {
 	case http.StatusBadRequest:
@@ -20,19 +23,59 @@ func ConnectCodeFromHTTPStatus(st int) connect.Code {
 		return connect.CodeNotFound
 	case http.StatusConflict:
 		return connect.CodeAlreadyExists
+	case http.StatusUnprocessableEntity:
+		return connect.CodeFailedPrecondition
 	case http.StatusTooManyRequests:
 		return connect.CodeResourceExhausted
 	case http.StatusNotImplemented:
 		return connect.CodeUnimplemented
 	case http.StatusServiceUnavailable:
 		return connect.CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return connect.CodeDeadlineExceeded
+	case statusClientClosedRequest:
+		return connect.CodeCanceled
 	default:
 		return connect.CodeInternal
 	}
 }
 
+// HTTPStatusFromConnectCode is the inverse of ConnectCodeFromHTTPStatus.
+// Codes that ConnectCodeFromHTTPStatus never produces follow the Connect protocol mapping.
+func HTTPStatusFromConnectCode(code connect.Code) int {
+	switch code {
+	case connect.CodeInvalidArgument, connect.CodeOutOfRange:
+		return http.StatusBadRequest
+	case connect.CodeFailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case connect.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodeAlreadyExists, connect.CodeAborted:
+		return http.StatusConflict
+	case connect.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case connect.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case connect.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case connect.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case connect.CodeCanceled:
+		return statusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // ToConnectError returns a Connect error that is safe for clients.
-// We also inject the stable error code into response headers via interceptor.
+// The stable error code is sent in the X-Error-Code response header and in a
+// google.rpc.ErrorInfo detail, along with the field violations and retry delay
+// of the AppError. The AppError stays reachable with apperr.As for server-side
+// interceptors, and is rebuilt on the client side by AppErrorFromConnect.
 func ToConnectError(err error) error {
 	ae := apperr.As(err)
 	if ae == nil {
@@ -46,5 +89,21 @@ func ToConnectError(err error) error {
 		msg = "internal error"
 	}
 
-	return connect.NewError(cc, errors.New(msg))
+	cerr := connect.NewError(cc, &safeError{msg: msg, appErr: ae})
+	cerr.Meta().Set(ErrorCodeHeader, ae.Code)
+	addErrorDetails(cerr, ae)
+
+	return cerr
 }
+
+// ErrorCodeHeader carries the apperr code of a failed call.
+const ErrorCodeHeader = "X-Error-Code"
+
+// safeError exposes only the client-safe message, and unwraps to the AppError.
+type safeError struct {
+	msg    string
+	appErr *apperr.AppError
+}
+
+func (e *safeError) Error() string { return e.msg }
+func (e *safeError) Unwrap() error { return e.appErr }
@@ -0,0 +1,87 @@
+package connectx
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync/atomic"
+
+	connectcors "connectrpc.com/cors"
+	"github.com/rs/cors"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// AuthorizationHeader carries the credentials of the authenticated calls.
+const AuthorizationHeader = "Authorization"
+
+// CORSMiddleware answers the CORS preflight requests and adds the CORS
+// headers to the responses, see NewCORSMiddleware.
+type CORSMiddleware struct {
+	cors atomic.Pointer[cors.Cors]
+}
+
+// NewCORSMiddleware returns a middleware answering the CORS preflight requests
+// of browsers and adding the CORS headers to the responses sent to the allowed
+// origins. It allows the methods and headers of the Connect, gRPC-Web and REST
+// protocols and of this package, and exposes the headers that clients read
+// from responses: gRPC-Web status, request id, error code, retry delay...
+func NewCORSMiddleware(cfg config.CORSConfig) (*CORSMiddleware, error) {
+	c, err := newCORS(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &CORSMiddleware{}
+	m.cors.Store(c)
+
+	return m, nil
+}
+
+// Handler returns next wrapped by the middleware.
+func (m *CORSMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.cors.Load().ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
+
+// Reload validates cfg, e.g. new allowed origins, and returns the function
+// applying it to the next requests.
+func (m *CORSMiddleware) Reload(cfg config.CORSConfig) (func(), error) {
+	c, err := newCORS(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { m.cors.Store(c) }, nil
+}
+
+func newCORS(cfg config.CORSConfig) (*cors.Cors, error) {
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		return nil, errors.New(`CORS credentials cannot be allowed for the "*" origin`)
+	}
+
+	c := cors.New(cors.Options{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: append(connectcors.AllowedMethods(), http.MethodPatch, http.MethodDelete),
+		AllowedHeaders: append(connectcors.AllowedHeaders(),
+			AuthorizationHeader,
+			RequestIDHeader,
+			IdempotencyKeyHeader,
+			APIKeyHeader,
+		),
+		ExposedHeaders: append(connectcors.ExposedHeaders(),
+			RequestIDHeader,
+			ErrorCodeHeader,
+			RetryAfterHeader,
+			IdempotentReplayedHeader,
+			DeprecationHeader,
+			SunsetHeader,
+			LinkHeader,
+		),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	})
+
+	return c, nil
+}
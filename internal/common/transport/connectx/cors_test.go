@@ -0,0 +1,114 @@
+package connectx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	cors, err := NewCORSMiddleware(config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           2 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	called := false
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.Header().Set(ErrorCodeHeader, "user.not_found")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	t.Run("Preflight of a Connect call", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/user.v2.UserService/CreateUser", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "connect-protocol-version,content-type,idempotency-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "7200", rec.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, http.MethodPost, rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t,
+			"connect-protocol-version,content-type,idempotency-key",
+			rec.Header().Get("Access-Control-Allow-Headers"))
+	})
+
+	t.Run("Preflight of a REST call", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/v2/users/1", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, http.MethodPatch, rec.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("Call exposes the headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/user.v2.UserService/GetUser", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.True(t, called)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		exposed := rec.Header().Get("Access-Control-Expose-Headers")
+		for _, header := range []string{"Grpc-Status", "Grpc-Message", ErrorCodeHeader, RequestIDHeader, RetryAfterHeader} {
+			assert.Contains(t, exposed, header)
+		}
+	})
+
+	t.Run("Other origins are not allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/user.v2.UserService/CreateUser", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORSMiddleware_WildcardWithCredentials(t *testing.T) {
+	_, err := NewCORSMiddleware(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	require.Error(t, err)
+}
+
+func TestCORSMiddleware_Reload(t *testing.T) {
+	cors, err := NewCORSMiddleware(config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	require.NoError(t, err)
+	handler := cors.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	allowed := func(origin string) string {
+		req := httptest.NewRequest(http.MethodPost, "/user.v2.UserService/GetUser", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	_, err = cors.Reload(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	require.Error(t, err)
+
+	apply, err := cors.Reload(config.CORSConfig{AllowedOrigins: []string{"https://admin.example.com"}})
+	require.NoError(t, err)
+	assert.Empty(t, allowed("https://admin.example.com"), "not applied yet")
+
+	apply()
+	assert.Equal(t, "https://admin.example.com", allowed("https://admin.example.com"))
+	assert.Empty(t, allowed("https://app.example.com"))
+}
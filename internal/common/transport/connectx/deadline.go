@@ -0,0 +1,133 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+var (
+	// ErrCodeDeadlineExceeded is returned when a call does not complete before its deadline.
+	ErrCodeDeadlineExceeded = apperr.Register(apperr.Definition{
+		Code:       "request.deadline_exceeded",
+		HTTPStatus: http.StatusGatewayTimeout,
+		Description: "The request did not complete before its deadline, set by the client or capped by the server. " +
+			"Its effects may or may not have been applied.",
+	})
+	// ErrCodeCanceled is returned when a call is canceled by the client.
+	ErrCodeCanceled = apperr.Register(apperr.Definition{
+		Code:        "request.canceled",
+		HTTPStatus:  statusClientClosedRequest,
+		Description: "The request was canceled by the client. Its effects may or may not have been applied.",
+	})
+)
+
+type deadlines struct {
+	defaultTimeout time.Duration
+	maxTimeout     time.Duration
+}
+
+type deadlineInterceptor struct {
+	global    deadlines
+	overrides map[string]deadlines // by procedure
+}
+
+// NewDeadlineInterceptor returns an interceptor bounding the context of the
+// calls with the deadlines of cfg: calls without client deadline get the
+// default one, and the client deadlines are capped to the maximum.
+// Handlers failing once the context is done return ErrCodeDeadlineExceeded or
+// ErrCodeCanceled, whatever error they got from the canceled work.
+func NewDeadlineInterceptor(cfg config.DeadlinesConfig) connect.Interceptor {
+	in := &deadlineInterceptor{
+		global:    deadlines{defaultTimeout: cfg.Default, maxTimeout: cfg.Max},
+		overrides: map[string]deadlines{},
+	}
+	for _, o := range cfg.Overrides {
+		d := in.global
+		if o.Default > 0 {
+			d.defaultTimeout = o.Default
+		}
+		if o.Max > 0 {
+			d.maxTimeout = o.Max
+		}
+		for _, procedure := range o.Procedures {
+			in.overrides[procedure] = d
+		}
+	}
+
+	return in
+}
+
+func (in *deadlineInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		ctx, cancel := in.withDeadline(ctx, req.Spec().Procedure)
+		defer cancel()
+
+		res, err := next(ctx, req)
+
+		return res, contextError(ctx, err)
+	}
+}
+
+// WrapStreamingClient leaves client streams untouched, their deadline is the one of their context.
+func (in *deadlineInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (in *deadlineInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, cancel := in.withDeadline(ctx, conn.Spec().Procedure)
+		defer cancel()
+
+		return contextError(ctx, next(ctx, conn))
+	}
+}
+
+// withDeadline returns ctx with the default deadline of procedure when it has
+// none, or with the maximum one when its deadline is further.
+func (in *deadlineInterceptor) withDeadline(ctx context.Context, procedure string) (context.Context, context.CancelFunc) {
+	d, ok := in.overrides[procedure]
+	if !ok {
+		d = in.global
+	}
+
+	deadline, ok := ctx.Deadline()
+	switch {
+	case !ok && d.defaultTimeout > 0:
+		return context.WithTimeout(ctx, d.defaultTimeout)
+	case ok && d.maxTimeout > 0 && time.Until(deadline) > d.maxTimeout:
+		return context.WithTimeout(ctx, d.maxTimeout)
+	default:
+		return ctx, func() {}
+	}
+}
+
+// contextError returns the error of a call whose context is done, or err as is.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ae *apperr.AppError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		ae = ErrCodeDeadlineExceeded.New("deadline exceeded")
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		ae = ErrCodeCanceled.New("request canceled")
+	default:
+		return err
+	}
+	ae.Cause = err
+
+	return ToConnectError(ae)
+}
@@ -0,0 +1,81 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestDeadlineInterceptor_WithDeadline(t *testing.T) {
+	in := NewDeadlineInterceptor(config.DeadlinesConfig{
+		Default: 10 * time.Second,
+		Max:     30 * time.Second,
+		Overrides: []config.DeadlineOverride{
+			{Procedures: []string{"/test.v1.TestService/List"}, Default: time.Second},
+		},
+	}).(*deadlineInterceptor)
+
+	remaining := func(ctx context.Context) time.Duration {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+
+		return time.Until(deadline)
+	}
+
+	ctx, cancel := in.withDeadline(context.Background(), "/test.v1.TestService/Create")
+	defer cancel()
+	assert.InDelta(t, 10*time.Second, remaining(ctx), float64(time.Second))
+
+	// Overrides keep the global values they do not set.
+	ctx, cancel = in.withDeadline(context.Background(), "/test.v1.TestService/List")
+	defer cancel()
+	assert.InDelta(t, time.Second, remaining(ctx), float64(100*time.Millisecond))
+
+	client, cancelClient := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelClient()
+	ctx, cancel = in.withDeadline(client, "/test.v1.TestService/List")
+	defer cancel()
+	assert.InDelta(t, 30*time.Second, remaining(ctx), float64(time.Second))
+
+	client, cancelClient = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancelClient()
+	ctx, cancel = in.withDeadline(client, "/test.v1.TestService/Create")
+	defer cancel()
+	assert.InDelta(t, 2*time.Second, remaining(ctx), float64(time.Second))
+}
+
+func TestDeadlineInterceptor_StreamingHandler(t *testing.T) {
+	handler := NewDeadlineInterceptor(config.DeadlinesConfig{Default: time.Millisecond}).WrapStreamingHandler(
+		func(ctx context.Context, _ connect.StreamingHandlerConn) error {
+			<-ctx.Done()
+
+			return fmt.Errorf("failed to list users: %w", errors.New("pq: canceling statement due to statement timeout"))
+		})
+
+	err := handler(context.Background(), newFakeHandlerConn())
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeDeadlineExceeded, cerr.Code())
+	assert.Equal(t, ErrCodeDeadlineExceeded.Code, cerr.Meta().Get(ErrorCodeHeader))
+}
+
+func TestContextError(t *testing.T) {
+	err := errors.New("not found")
+	assert.Equal(t, err, contextError(context.Background(), err))
+	assert.NoError(t, contextError(context.Background(), nil))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, connect.CodeCanceled, connect.CodeOf(contextError(canceled, err)))
+
+	wrapped := fmt.Errorf("failed to get user: %w", context.DeadlineExceeded)
+	assert.Equal(t, connect.CodeDeadlineExceeded, connect.CodeOf(contextError(context.Background(), wrapped)))
+}
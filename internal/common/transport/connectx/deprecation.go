@@ -0,0 +1,81 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+	LinkHeader        = "Link"
+)
+
+// Deprecation describes a deprecated API version. It is announced to clients
+// with the Deprecation (RFC 9745), Sunset (RFC 8594) and Link response headers.
+type Deprecation struct {
+	// Since is when the API was deprecated.
+	Since time.Time
+	// Sunset is when the API stops being served, zero while it is not planned.
+	Sunset time.Time
+	// Successor is the URL of the replacing API, zero when there is none.
+	Successor string
+}
+
+func (d Deprecation) setHeaders(h http.Header) {
+	h.Set(DeprecationHeader, "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set(SunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add(LinkHeader, "<"+d.Successor+`>; rel="successor-version"`)
+	}
+}
+
+type deprecationInterceptor struct {
+	deprecation Deprecation
+}
+
+// NewDeprecationInterceptor returns an interceptor adding the deprecation
+// headers to every response, successful or not, of the handlers it wraps.
+func NewDeprecationInterceptor(d Deprecation) connect.Interceptor {
+	return &deprecationInterceptor{deprecation: d}
+}
+
+func (in *deprecationInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		res, err := next(ctx, req)
+		if req.Spec().IsClient {
+			return res, err
+		}
+
+		// On error, res is usually a typed nil: the headers go to the error metadata.
+		var cerr *connect.Error
+		switch {
+		case err == nil:
+			in.deprecation.setHeaders(res.Header())
+		case errors.As(err, &cerr):
+			in.deprecation.setHeaders(cerr.Meta())
+		}
+
+		return res, err
+	}
+}
+
+// WrapStreamingClient leaves client streams untouched, the headers are sent by servers.
+func (in *deprecationInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (in *deprecationInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		in.deprecation.setHeaders(conn.ResponseHeader())
+
+		return next(ctx, conn)
+	}
+}
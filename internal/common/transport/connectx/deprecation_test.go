@@ -0,0 +1,58 @@
+package connectx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestDeprecationInterceptor(t *testing.T) {
+	d := Deprecation{
+		Since:     time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2/users",
+	}
+	var fail bool
+	handler := NewDeprecationInterceptor(d).WrapUnary(
+		func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			if fail {
+				return nil, connect.NewError(connect.CodeNotFound, nil)
+			}
+
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		})
+
+	assertHeaders := func(h http.Header) {
+		t.Helper()
+		assert.Equal(t, "@1767225600", h.Get(DeprecationHeader))
+		assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", h.Get(SunsetHeader))
+		assert.Equal(t, `</v2/users>; rel="successor-version"`, h.Get(LinkHeader))
+	}
+
+	res, err := handler(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	assertHeaders(res.Header())
+
+	fail = true
+	_, err = handler(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assertHeaders(cerr.Meta())
+}
+
+func TestDeprecationInterceptor_NoSunset(t *testing.T) {
+	conn := newFakeHandlerConn()
+	handler := NewDeprecationInterceptor(Deprecation{Since: time.Unix(100, 0)}).WrapStreamingHandler(
+		func(context.Context, connect.StreamingHandlerConn) error { return nil })
+
+	require.NoError(t, handler(context.Background(), conn))
+	assert.Equal(t, "@100", conn.responseHeader.Get(DeprecationHeader))
+	assert.Empty(t, conn.responseHeader.Get(SunsetHeader))
+	assert.Empty(t, conn.responseHeader.Get(LinkHeader))
+}
@@ -0,0 +1,76 @@
+package connectx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Drainer tracks the in-flight requests of a server, to wait for them on
+// shutdown. Unlike http.Server.Shutdown, it also covers the streams of the
+// HTTP/2 cleartext (h2c) connections, which are hijacked from the http.Server.
+type Drainer struct {
+	mu       sync.Mutex
+	inFlight int
+	idle     chan struct{}
+}
+
+// NewDrainer returns a Drainer without in-flight request.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Middleware counts the requests served by next as in flight.
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		d.inFlight++
+		d.mu.Unlock()
+
+		defer d.done()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (d *Drainer) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight--
+	if d.inFlight == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// InFlight returns the number of requests being served.
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.inFlight
+}
+
+// Wait returns once no request is in flight, or fails when ctx is done first.
+func (d *Drainer) Wait(ctx context.Context) error {
+	d.mu.Lock()
+	if d.inFlight == 0 {
+		d.mu.Unlock()
+
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d requests still in flight: %w", d.InFlight(), ctx.Err())
+	}
+}
@@ -0,0 +1,90 @@
+package connectx
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestDrainer_Wait(t *testing.T) {
+	d := NewDrainer()
+	require.NoError(t, d.Wait(context.Background()))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	}))
+	go handler.ServeHTTP(nil, nil)
+	<-started
+	assert.Equal(t, 1, d.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := d.Wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "1 requests still in flight")
+
+	close(release)
+	require.NoError(t, d.Wait(context.Background()))
+	assert.Zero(t, d.InFlight())
+}
+
+// TestDrainer_H2C checks that a request on an h2c connection, hijacked from
+// the http.Server, completes when the server shuts down.
+func TestDrainer_H2C(t *testing.T) {
+	d := NewDrainer()
+	started := make(chan struct{})
+	srv, err := NewHTTPServer("", d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})), config.HTTPConfig{}, nil)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(lis) }()
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	type result struct {
+		body  string
+		proto int
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := client.Get("http://" + lis.Addr().String())
+		if err != nil {
+			results <- result{err: err}
+
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{body: string(body), proto: resp.ProtoMajor, err: err}
+	}()
+	<-started
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	require.NoError(t, d.Wait(context.Background()))
+
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, 2, res.proto)
+	assert.Equal(t, "done", res.body)
+	require.ErrorIs(t, <-served, http.ErrServerClosed)
+}
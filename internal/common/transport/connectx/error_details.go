@@ -0,0 +1,134 @@
+package connectx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+const (
+	// RequestIDHeader carries the request id, set by the client or generated by the server.
+	RequestIDHeader = "X-Request-Id"
+	// RetryAfterHeader tells clients how many seconds to wait before retrying.
+	RetryAfterHeader = "Retry-After"
+
+	// requestIDField is the AppError field holding the request id of an error rebuilt by AppErrorFromConnect.
+	requestIDField = "request_id"
+)
+
+// addErrorDetails sends the AppError to the client as Connect error details
+// and metadata: a google.rpc.ErrorInfo whose reason is the error code, a
+// google.rpc.BadRequest with the field violations, and a google.rpc.RetryInfo.
+// The Fields of private errors are never sent.
+func addErrorDetails(cerr *connect.Error, ae *apperr.AppError) {
+	info := &errdetails.ErrorInfo{Reason: ae.Code}
+	if ae.IsPublic() && len(ae.Fields) > 0 {
+		info.Metadata = make(map[string]string, len(ae.Fields))
+		for k, v := range ae.Fields {
+			info.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+	addDetail(cerr, info)
+
+	if len(ae.Violations) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(ae.Violations))
+		for i, v := range ae.Violations {
+			violations[i] = &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+				Reason:      v.Reason,
+			}
+		}
+		addDetail(cerr, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	if ae.RetryAfter > 0 {
+		addDetail(cerr, &errdetails.RetryInfo{RetryDelay: durationpb.New(ae.RetryAfter)})
+		seconds := int(math.Ceil(ae.RetryAfter.Seconds()))
+		cerr.Meta().Set(RetryAfterHeader, strconv.Itoa(seconds))
+	}
+}
+
+// addRequestID attaches the request id to a Connect error, as a
+// google.rpc.RequestInfo detail and in the X-Request-Id header.
+func addRequestID(cerr *connect.Error, requestID string) {
+	if requestID == "" {
+		return
+	}
+
+	cerr.Meta().Set(RequestIDHeader, requestID)
+	addDetail(cerr, &errdetails.RequestInfo{RequestId: requestID})
+}
+
+func addDetail(cerr *connect.Error, msg proto.Message) {
+	// NewErrorDetail only fails when msg cannot be marshaled, which does not
+	// happen with the well-known error detail types.
+	if detail, err := connect.NewErrorDetail(msg); err == nil {
+		cerr.AddDetail(detail)
+	}
+}
+
+// AppErrorFromConnect rebuilds, on the client side, the AppError sent by a
+// server with ToConnectError. It returns nil when err is not a Connect error.
+// Errors without an ErrorInfo detail, e.g. those raised by a proxy, get the
+// Connect code as error code. The request id, when any, is in Fields["request_id"].
+func AppErrorFromConnect(err error) *apperr.AppError {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) {
+		return nil
+	}
+
+	ae := &apperr.AppError{
+		Code:       cerr.Meta().Get(ErrorCodeHeader),
+		Message:    cerr.Message(),
+		HTTPStatus: HTTPStatusFromConnectCode(cerr.Code()),
+		Visibility: apperr.Public,
+		When:       time.Now().Unix(),
+		Cause:      err,
+	}
+
+	for _, detail := range cerr.Details() {
+		msg, derr := detail.Value()
+		if derr != nil {
+			continue
+		}
+
+		switch d := msg.(type) {
+		case *errdetails.ErrorInfo:
+			ae.Code = d.GetReason()
+			for k, v := range d.GetMetadata() {
+				_ = apperr.WithField(ae, k, v)
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				ae.Violations = append(ae.Violations, apperr.FieldViolation{
+					Field:       v.GetField(),
+					Description: v.GetDescription(),
+					Reason:      v.GetReason(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			ae.RetryAfter = d.GetRetryDelay().AsDuration()
+		case *errdetails.RequestInfo:
+			_ = apperr.WithField(ae, requestIDField, d.GetRequestId())
+		}
+	}
+
+	if ae.Code == "" {
+		ae.Code = cerr.Code().String()
+	}
+	if def, ok := apperr.Lookup(ae.Code); ok {
+		ae.HTTPStatus = def.HTTPStatus
+	}
+
+	return ae
+}
@@ -0,0 +1,87 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/reqid"
+)
+
+var errCodeTest = apperr.Register(apperr.Definition{
+	Code:        "test.conflict",
+	HTTPStatus:  http.StatusConflict,
+	Description: "Test error.",
+})
+
+func TestAppErrorFromConnect_RoundTrip(t *testing.T) {
+	ae := errCodeTest.New("email already taken")
+	_ = apperr.WithField(ae, "email", "john@example.com")
+	_ = apperr.WithViolations(ae, apperr.FieldViolation{Field: "email", Description: "already taken", Reason: "unique"})
+	_ = apperr.WithRetryAfter(ae, 1500*time.Millisecond)
+
+	err := ToConnectError(ae)
+
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeAlreadyExists, cerr.Code())
+	assert.Equal(t, "test.conflict", cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t, "2", cerr.Meta().Get(RetryAfterHeader))
+
+	got := AppErrorFromConnect(err)
+	require.NotNil(t, got)
+	assert.Equal(t, "test.conflict", got.Code)
+	assert.Equal(t, "email already taken", got.Message)
+	assert.Equal(t, http.StatusConflict, got.HTTPStatus)
+	assert.Equal(t, map[string]any{"email": "john@example.com"}, got.Fields)
+	assert.Equal(t, ae.Violations, got.Violations)
+	assert.Equal(t, 1500*time.Millisecond, got.RetryAfter)
+}
+
+func TestAppErrorFromConnect_PrivateFieldsAreNotSent(t *testing.T) {
+	ae := apperr.WrapPrivate("test.internal", http.StatusInternalServerError, errors.New("pq: connection refused"))
+	_ = apperr.WithField(ae, "dsn", "postgres://secret")
+
+	got := AppErrorFromConnect(ToConnectError(ae))
+	require.NotNil(t, got)
+	assert.Equal(t, "test.internal", got.Code)
+	assert.Equal(t, "internal error", got.Message)
+	assert.Empty(t, got.Fields)
+}
+
+func TestAppErrorFromConnect_WithoutDetails(t *testing.T) {
+	assert.Nil(t, AppErrorFromConnect(errors.New("boom")))
+
+	got := AppErrorFromConnect(connect.NewError(connect.CodeUnavailable, errors.New("upstream down")))
+	require.NotNil(t, got)
+	assert.Equal(t, "unavailable", got.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, got.HTTPStatus)
+}
+
+func TestErrorHeaderInterceptor(t *testing.T) {
+	ctx := reqid.With(context.Background(), "rid-42")
+	handler := NewErrorHeaderInterceptor().WrapUnary(
+		func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, errCodeTest.New("conflict")
+		})
+
+	_, err := handler(ctx, connect.NewRequest(&emptypb.Empty{}))
+
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeAlreadyExists, cerr.Code())
+	assert.Equal(t, "rid-42", cerr.Meta().Get(RequestIDHeader))
+
+	got := AppErrorFromConnect(err)
+	require.NotNil(t, got)
+	assert.Equal(t, "test.conflict", got.Code)
+	assert.Equal(t, "rid-42", got.Fields["request_id"])
+}
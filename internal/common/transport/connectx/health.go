@@ -0,0 +1,74 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+)
+
+const healthPingTimeout = time.Second
+
+// Pinger reports whether a dependency, typically the database, is reachable.
+// *sql.DB and *sqlx.DB implement it.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthChecker is a grpchealth.Checker whose status is driven by the
+// reachability of the database and the shutdown state of the server.
+// The empty service name reports the health of the whole server.
+type HealthChecker struct {
+	pinger       Pinger
+	services     map[string]struct{}
+	shuttingDown atomic.Bool
+}
+
+var _ grpchealth.Checker = (*HealthChecker)(nil)
+
+// NewHealthChecker returns a checker for the given fully-qualified service names.
+// pinger may be nil when the server has no dependency to check.
+func NewHealthChecker(pinger Pinger, services ...string) *HealthChecker {
+	c := &HealthChecker{pinger: pinger, services: make(map[string]struct{}, len(services))}
+	for _, s := range services {
+		c.services[s] = struct{}{}
+	}
+
+	return c
+}
+
+// SetShuttingDown makes every subsequent check report NOT_SERVING.
+func (c *HealthChecker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown was called.
+func (c *HealthChecker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+func (c *HealthChecker) Check(ctx context.Context, req *grpchealth.CheckRequest) (*grpchealth.CheckResponse, error) {
+	if req.Service != "" {
+		if _, ok := c.services[req.Service]; !ok {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New("unknown service "+req.Service))
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		return &grpchealth.CheckResponse{Status: grpchealth.StatusNotServing}, nil
+	}
+
+	if c.pinger != nil {
+		ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+		defer cancel()
+
+		if err := c.pinger.PingContext(ctx); err != nil {
+			return &grpchealth.CheckResponse{Status: grpchealth.StatusNotServing}, nil //nolint:nilerr // reported as status
+		}
+	}
+
+	return &grpchealth.CheckResponse{Status: grpchealth.StatusServing}, nil
+}
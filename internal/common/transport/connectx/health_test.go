@@ -0,0 +1,43 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error { return f(ctx) }
+
+func TestHealthChecker(t *testing.T) {
+	var dbErr error
+	checker := NewHealthChecker(pingerFunc(func(context.Context) error { return dbErr }), "user.v1.UserService")
+
+	check := func(service string) grpchealth.Status {
+		t.Helper()
+
+		res, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: service})
+		require.NoError(t, err)
+
+		return res.Status
+	}
+
+	assert.Equal(t, grpchealth.StatusServing, check(""))
+	assert.Equal(t, grpchealth.StatusServing, check("user.v1.UserService"))
+
+	dbErr = errors.New("connection refused")
+	assert.Equal(t, grpchealth.StatusNotServing, check(""))
+
+	dbErr = nil
+	checker.SetShuttingDown()
+	assert.Equal(t, grpchealth.StatusNotServing, check("user.v1.UserService"))
+
+	_, err := checker.Check(context.Background(), &grpchealth.CheckRequest{Service: "unknown.Service"})
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+}
@@ -0,0 +1,242 @@
+package connectx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client generated key of a mutating call.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	inProgressRetryAfter = time.Second
+)
+
+var (
+	// ErrCodeIdempotencyKeyReused is returned when an idempotency key is sent
+	// again with a different request.
+	ErrCodeIdempotencyKeyReused = apperr.Register(apperr.Definition{
+		Code:       "idempotency.key_reused",
+		HTTPStatus: http.StatusUnprocessableEntity,
+		Description: "The Idempotency-Key was already used with a different request payload. " +
+			"Generate a new key for every distinct request.",
+	})
+	// ErrCodeIdempotencyInProgress is returned when the first call with an
+	// idempotency key is not done yet.
+	ErrCodeIdempotencyInProgress = apperr.Register(apperr.Definition{
+		Code:        "idempotency.in_progress",
+		HTTPStatus:  http.StatusConflict,
+		Description: "A request with the same Idempotency-Key is still in progress, retry after the Retry-After delay.",
+	})
+	// ErrCodeIdempotencyUnavailable is returned when the idempotency store fails.
+	ErrCodeIdempotencyUnavailable = apperr.Register(apperr.Definition{
+		Code:        "idempotency.unavailable",
+		HTTPStatus:  http.StatusServiceUnavailable,
+		Description: "The idempotency keys cannot be checked, the request was not processed.",
+	})
+)
+
+// ErrIdempotencyLeaseLost is returned by the IdempotencyStore when the lease of
+// a reservation expired and the key was reserved again, or purged, before the
+// call holding it was done: the key now belongs to the retry, if any.
+var ErrIdempotencyLeaseLost = errors.New("idempotency key lease lost")
+
+// IdempotencyRecord is what an IdempotencyStore keeps for a key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request first sent with the key.
+	Fingerprint []byte
+	// Completed is false while the first call is in progress.
+	Completed bool
+	// Response is the serialized response message of the completed call.
+	Response []byte
+}
+
+// IdempotencyStore keeps the idempotency keys sent by a client to a procedure
+// until they expire. The client is the principal of the calls, or their IP for
+// the anonymous ones, so that clients cannot replay the responses of others.
+// A reservation is identified by a token unique to the call, for a call that
+// outlived its lease not to complete or release the reservation of a retry.
+type IdempotencyStore interface {
+	// Reserve records key with the request fingerprint for lease, under the
+	// reservation token, and returns nil. When key is already recorded and not
+	// expired, it returns its record instead.
+	Reserve(
+		ctx context.Context, client, key, procedure, reservation string, fingerprint []byte, lease time.Duration,
+	) (*IdempotencyRecord, error)
+	// Complete stores the response of the key reserved by reservation, kept for
+	// ttl, or fails with ErrIdempotencyLeaseLost.
+	Complete(
+		ctx context.Context, client, key, procedure, reservation string, response []byte, ttl time.Duration,
+	) error
+	// Release forgets the key reserved by reservation, so that the request can
+	// be retried, or fails with ErrIdempotencyLeaseLost.
+	Release(ctx context.Context, client, key, procedure, reservation string) error
+}
+
+type idempotencyInterceptor struct {
+	store          IdempotencyStore
+	cfg            config.IdempotencyConfig
+	clientIPHeader string
+}
+
+// NewIdempotencyInterceptor returns an interceptor honoring the Idempotency-Key
+// header on the unary procedures that are not declared free of side effects.
+// The first call with a key is processed and its successful response is kept
+// in store for cfg.TTL: later calls with the same key and the same request get
+// that response back, with the Idempotent-Replayed header, and calls with the
+// same key but another request fail with ErrCodeIdempotencyKeyReused.
+// Failed calls are not kept, they can be retried with the same key. The key of
+// a call in progress is only kept for cfg.Lease, so that the key of a server
+// stopped in the middle of a call is not locked for cfg.TTL: a call outliving
+// its lease neither completes nor releases the key reserved again by a retry.
+// Keys are scoped by client: the principal of the call, else its IP, read from
+// the clientIPHeader header when set as the rate limits do.
+func NewIdempotencyInterceptor(
+	store IdempotencyStore, cfg config.IdempotencyConfig, clientIPHeader string,
+) connect.Interceptor {
+	return &idempotencyInterceptor{store: store, cfg: cfg, clientIPHeader: clientIPHeader}
+}
+
+func (in *idempotencyInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := req.Spec()
+		key := req.Header().Get(IdempotencyKeyHeader)
+		if spec.IsClient || key == "" || spec.IdempotencyLevel == connect.IdempotencyNoSideEffects {
+			return next(ctx, req)
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			return nil, ToConnectError(ErrCodeInvalidRequest.New(
+				fmt.Sprintf("the %s header must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLen)))
+		}
+
+		msg, ok := req.Any().(proto.Message)
+		if !ok {
+			return next(ctx, req)
+		}
+		fingerprint, err := idempotencyFingerprint(msg)
+		if err != nil {
+			return nil, ToConnectError(ErrCodeIdempotencyUnavailable.Wrap(err))
+		}
+
+		client := in.client(ctx, req)
+		reservation := rand.Text()
+		record, err := in.store.Reserve(ctx, client, key, spec.Procedure, reservation, fingerprint, in.cfg.Lease)
+		if err != nil {
+			return nil, ToConnectError(ErrCodeIdempotencyUnavailable.Wrap(err))
+		}
+		if record != nil {
+			return replay(spec, record, fingerprint)
+		}
+
+		res, err := next(ctx, req)
+		// The key outlives a canceled call, whose outcome is decided here.
+		storeCtx := context.WithoutCancel(ctx)
+		if err != nil {
+			_ = in.store.Release(storeCtx, client, key, spec.Procedure, reservation)
+
+			return res, err
+		}
+
+		err = in.complete(storeCtx, client, key, spec.Procedure, reservation, res)
+		if err != nil && !errors.Is(err, ErrIdempotencyLeaseLost) {
+			// The response is still sent, a retry with the same key is processed again.
+			_ = in.store.Release(storeCtx, client, key, spec.Procedure, reservation)
+		}
+
+		return res, nil
+	}
+}
+
+// WrapStreamingClient leaves client streams untouched, idempotency keys are handled by servers.
+func (in *idempotencyInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler leaves streams untouched, their responses cannot be replayed.
+func (in *idempotencyInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// client identifies the client of req: its principal, else its IP.
+func (in *idempotencyInterceptor) client(ctx context.Context, req connect.AnyRequest) string {
+	if p := principal.Get(ctx); p != "" {
+		return RateLimitKeyPrincipal + ":" + p
+	}
+
+	return RateLimitKeyIP + ":" + clientIP(req.Header(), req.Peer(), in.clientIPHeader)
+}
+
+func (in *idempotencyInterceptor) complete(
+	ctx context.Context, client, key, procedure, reservation string, res connect.AnyResponse,
+) error {
+	msg, ok := res.Any().(proto.Message)
+	if !ok {
+		return errors.New("response is not a proto message")
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	//nolint:wrapcheck // the caller checks for ErrIdempotencyLeaseLost, and drops the other errors
+	return in.store.Complete(ctx, client, key, procedure, reservation, body, in.cfg.TTL)
+}
+
+// idempotencyFingerprint hashes the deterministic encoding of msg.
+func idempotencyFingerprint(msg proto.Message) ([]byte, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	sum := sha256.Sum256(body)
+
+	return sum[:], nil
+}
+
+// replay returns the stored response of record, or the error explaining why it cannot.
+func replay(spec connect.Spec, record *IdempotencyRecord, fingerprint []byte) (connect.AnyResponse, error) {
+	if !bytes.Equal(record.Fingerprint, fingerprint) {
+		return nil, ToConnectError(ErrCodeIdempotencyKeyReused.New(
+			"the " + IdempotencyKeyHeader + " was already used with a different request"))
+	}
+	if !record.Completed {
+		return nil, ToConnectError(apperr.WithRetryAfter(ErrCodeIdempotencyInProgress.New(
+			"a request with the same "+IdempotencyKeyHeader+" is in progress"), inProgressRetryAfter))
+	}
+
+	method, ok := spec.Schema.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, ToConnectError(ErrCodeIdempotencyUnavailable.Wrap(
+			fmt.Errorf("no schema for procedure %s", spec.Procedure)))
+	}
+	// The handler sends the response with its codec, a dynamic message encodes like the generated one.
+	msg := dynamicpb.NewMessage(method.Output())
+	if err := proto.Unmarshal(record.Response, msg); err != nil {
+		return nil, ToConnectError(ErrCodeIdempotencyUnavailable.Wrap(
+			fmt.Errorf("failed to unmarshal stored response: %w", err)))
+	}
+
+	res := connect.NewResponse(msg)
+	res.Header().Set(IdempotentReplayedHeader, "true")
+
+	return res, nil
+}
@@ -0,0 +1,296 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+const (
+	testCreateProcedure = "/test.v1.TestService/Create"
+	testClientIPHeader  = "X-Forwarded-For"
+	testPrincipalHeader = "X-Test-Principal"
+)
+
+// memIdempotencyStore is an IdempotencyStore without expiration, recording
+// the last lease and ttl.
+type memIdempotencyStore struct {
+	mu           sync.Mutex
+	records      map[string]*IdempotencyRecord
+	reservations map[string]string
+	lease        time.Duration
+	ttl          time.Duration
+	// beforeDone, when set, runs before Complete and Release, e.g. to let a
+	// retry take over the key.
+	beforeDone func(id string)
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{records: map[string]*IdempotencyRecord{}, reservations: map[string]string{}}
+}
+
+func (s *memIdempotencyStore) Reserve(
+	_ context.Context, client, key, procedure, reservation string, fingerprint []byte, lease time.Duration,
+) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lease = lease
+
+	if record, ok := s.records[client+procedure+key]; ok {
+		return record, nil
+	}
+	s.records[client+procedure+key] = &IdempotencyRecord{Fingerprint: fingerprint}
+	s.reservations[client+procedure+key] = reservation
+
+	return nil, nil
+}
+
+// held returns the record of id reserved by reservation, not completed.
+func (s *memIdempotencyStore) held(id, reservation string) (*IdempotencyRecord, error) {
+	if s.beforeDone != nil {
+		s.beforeDone(id)
+	}
+	record, ok := s.records[id]
+	if !ok || record.Completed || s.reservations[id] != reservation {
+		return nil, ErrIdempotencyLeaseLost
+	}
+
+	return record, nil
+}
+
+func (s *memIdempotencyStore) Complete(
+	_ context.Context, client, key, procedure, reservation string, response []byte, ttl time.Duration,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ttl = ttl
+
+	record, err := s.held(client+procedure+key, reservation)
+	if err != nil {
+		return err
+	}
+	record.Completed = true
+	record.Response = response
+
+	return nil
+}
+
+func (s *memIdempotencyStore) Release(_ context.Context, client, key, procedure, reservation string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.held(client+procedure+key, reservation); err != nil {
+		return err
+	}
+	delete(s.records, client+procedure+key)
+	delete(s.reservations, client+procedure+key)
+
+	return nil
+}
+
+// testCreateMethod describes test.v1.TestService.Create, taking and returning a StringValue.
+func testCreateMethod(t *testing.T) protoreflect.MethodDescriptor {
+	t.Helper()
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/test.proto"),
+		Package:    proto.String("test.v1"),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("TestService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Create"),
+				InputType:  proto.String(".google.protobuf.StringValue"),
+				OutputType: proto.String(".google.protobuf.StringValue"),
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return file.Services().Get(0).Methods().Get(0)
+}
+
+// newIdempotentTestClient serves a Create procedure counting its calls, and
+// failing for the "fail" value.
+func newIdempotentTestClient(
+	t *testing.T, store IdempotencyStore,
+) (*connect.Client[wrapperspb.StringValue, wrapperspb.StringValue], *int) {
+	t.Helper()
+
+	calls := 0
+	mux := http.NewServeMux()
+	mux.Handle(testCreateProcedure, connect.NewUnaryHandler(testCreateProcedure,
+		func(_ context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+			calls++
+			if req.Msg.GetValue() == "fail" {
+				return nil, connect.NewError(connect.CodeInternal, errors.New("failed"))
+			}
+
+			return connect.NewResponse(wrapperspb.String(req.Msg.GetValue() + "-created")), nil
+		},
+		connect.WithSchema(testCreateMethod(t)),
+		connect.WithInterceptors(NewIdempotencyInterceptor(
+			store, config.IdempotencyConfig{TTL: time.Hour, Lease: time.Minute}, testClientIPHeader)),
+	))
+	// The principal of the calls is the one of the test header, as set by an authentication middleware.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := r.Header.Get(testPrincipalHeader); p != "" {
+			r = r.WithContext(principal.With(r.Context(), p))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+		server.Client(), server.URL+testCreateProcedure), &calls
+}
+
+func idempotentRequest(key, value string) *connect.Request[wrapperspb.StringValue] {
+	req := connect.NewRequest(wrapperspb.String(value))
+	if key != "" {
+		req.Header().Set(IdempotencyKeyHeader, key)
+	}
+
+	return req
+}
+
+func TestIdempotencyInterceptor_Replay(t *testing.T) {
+	store := newMemIdempotencyStore()
+	client, calls := newIdempotentTestClient(t, store)
+	ctx := context.Background()
+
+	first, err := client.CallUnary(ctx, idempotentRequest("key-1", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice-created", first.Msg.GetValue())
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	store.mu.Lock()
+	assert.Equal(t, time.Minute, store.lease, "the key of the call in progress is leased")
+	assert.Equal(t, time.Hour, store.ttl, "the response is kept for the ttl")
+	store.mu.Unlock()
+
+	replayed, err := client.CallUnary(ctx, idempotentRequest("key-1", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice-created", replayed.Msg.GetValue())
+	assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, *calls)
+
+	_, err = client.CallUnary(ctx, idempotentRequest("key-1", "bob"))
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeFailedPrecondition, cerr.Code())
+	assert.Equal(t, ErrCodeIdempotencyKeyReused.Code, cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t, 1, *calls)
+
+	// Without key, every call is processed.
+	_, err = client.CallUnary(ctx, idempotentRequest("", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyInterceptor_FailedCallsAreNotKept(t *testing.T) {
+	client, calls := newIdempotentTestClient(t, newMemIdempotencyStore())
+	ctx := context.Background()
+
+	_, err := client.CallUnary(ctx, idempotentRequest("key-1", "fail"))
+	require.Error(t, err)
+	_, err = client.CallUnary(ctx, idempotentRequest("key-1", "fail"))
+	require.Error(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyInterceptor_InProgress(t *testing.T) {
+	store := newMemIdempotencyStore()
+	client, calls := newIdempotentTestClient(t, store)
+
+	fingerprint, err := idempotencyFingerprint(wrapperspb.String("alice"))
+	require.NoError(t, err)
+	_, err = store.Reserve(context.Background(), "ip:127.0.0.1", "key-1", testCreateProcedure, "first", fingerprint, time.Hour)
+	require.NoError(t, err)
+
+	_, err = client.CallUnary(context.Background(), idempotentRequest("key-1", "alice"))
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeAlreadyExists, cerr.Code())
+	assert.Equal(t, ErrCodeIdempotencyInProgress.Code, cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t, "1", cerr.Meta().Get(RetryAfterHeader))
+	assert.Equal(t, 0, *calls)
+}
+
+func TestIdempotencyInterceptor_LeaseLost(t *testing.T) {
+	store := newMemIdempotencyStore()
+	client, calls := newIdempotentTestClient(t, store)
+	ctx := context.Background()
+	// The lease of the call expires while it runs, and a retry reserves the key again.
+	store.beforeDone = func(id string) { store.reservations[id] = "retry" }
+
+	for _, value := range []string{"alice", "fail"} {
+		_, err := client.CallUnary(ctx, idempotentRequest("key-"+value, value))
+		if value == "fail" {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err, "the response is sent")
+		}
+
+		store.mu.Lock()
+		record := store.records["ip:127.0.0.1"+testCreateProcedure+"key-"+value]
+		store.mu.Unlock()
+		require.NotNil(t, record, "the key of the retry is not released")
+		assert.False(t, record.Completed, "the key of the retry is not completed")
+	}
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyInterceptor_ScopedByClient(t *testing.T) {
+	client, calls := newIdempotentTestClient(t, newMemIdempotencyStore())
+	ctx := context.Background()
+	call := func(header, value string) *connect.Response[wrapperspb.StringValue] {
+		t.Helper()
+
+		req := idempotentRequest("key-1", "alice")
+		req.Header().Set(header, value)
+		res, err := client.CallUnary(ctx, req)
+		require.NoError(t, err)
+
+		return res
+	}
+
+	call(testClientIPHeader, "192.0.2.1")
+	assert.Empty(t, call(testClientIPHeader, "192.0.2.2").Header().Get(IdempotentReplayedHeader),
+		"another IP does not get the response")
+	assert.Equal(t, "true", call(testClientIPHeader, "192.0.2.1").Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, *calls)
+
+	call(testPrincipalHeader, "alice")
+	assert.Empty(t, call(testPrincipalHeader, "bob").Header().Get(IdempotentReplayedHeader),
+		"another principal does not get the response")
+	assert.Equal(t, "true", call(testPrincipalHeader, "alice").Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 4, *calls)
+}
+
+func TestIdempotencyInterceptor_KeyTooLong(t *testing.T) {
+	client, calls := newIdempotentTestClient(t, newMemIdempotencyStore())
+
+	_, err := client.CallUnary(context.Background(), idempotentRequest(strings.Repeat("k", 256), "alice"))
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	assert.Equal(t, 0, *calls)
+}
@@ -2,133 +2,277 @@ package connectx
 
 import (
 	"context"
+	"errors"
+	"io"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
 	"github.com/pivaldi/go-cleanstack/internal/common/platform/reqid"
 )
 
-type Interceptors struct{}
+// NewInterceptors returns the default interceptor chain enabled in
+// cfg.Interceptors, from the outermost to the innermost: request id, logging,
+// error details, deadline and validation.
+func NewInterceptors(server config.ServerConfig, logger logging.Logger) ([]connect.Interceptor, error) {
+	var interceptors []connect.Interceptor
+	cfg := server.Interceptors
 
-func (i Interceptors) All() []connect.Interceptor {
-	return []connect.Interceptor{
-		requestIDInterceptor{},
-		loggingInterceptor{},
-		errorHeaderInterceptor{},
+	if cfg.RequestID {
+		interceptors = append(interceptors, NewRequestIDInterceptor())
 	}
+	if cfg.Logging {
+		interceptors = append(interceptors, NewLoggingInterceptor(logger))
+	}
+	if cfg.ErrorDetails {
+		interceptors = append(interceptors, NewErrorHeaderInterceptor())
+	}
+	if cfg.Deadline {
+		interceptors = append(interceptors, NewDeadlineInterceptor(server.Deadlines))
+	}
+	if cfg.Validation {
+		validator, err := NewValidationInterceptor()
+		if err != nil {
+			return nil, err
+		}
+		interceptors = append(interceptors, validator)
+	}
+
+	return interceptors, nil
 }
 
 type requestIDInterceptor struct{}
 
+// NewRequestIDInterceptor returns an interceptor putting the X-Request-Id of the
+// request, or a generated one, in the context and in the response headers.
+// On the client side, it forwards the request id of the context.
+func NewRequestIDInterceptor() connect.Interceptor {
+	return requestIDInterceptor{}
+}
+
 func (in requestIDInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		rid := req.Header().Get("X-Request-Id")
-		if rid == "" {
-			rid = reqid.New()
+		rid := requestID(ctx, req.Header().Get(RequestIDHeader))
+		req.Header().Set(RequestIDHeader, rid) // ensure downstream sees it
+
+		res, err := next(reqid.With(ctx, rid), req)
+		// On error, res is usually a typed nil and the id is sent by the error header interceptor.
+		if err == nil {
+			res.Header().Set(RequestIDHeader, rid)
 		}
-		req.Header().Set("X-Request-Id", rid) // ensure downstream sees it
 
-		return next(reqid.With(ctx, rid), req)
+		return res, err
 	}
 }
+
 func (in requestIDInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
-	return next
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		rid := requestID(ctx, "")
+		conn := next(reqid.With(ctx, rid), spec)
+		conn.RequestHeader().Set(RequestIDHeader, rid)
+
+		return conn
+	}
 }
+
 func (in requestIDInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
-	return next
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		rid := requestID(ctx, conn.RequestHeader().Get(RequestIDHeader))
+		conn.ResponseHeader().Set(RequestIDHeader, rid)
+
+		return next(reqid.With(ctx, rid), conn)
+	}
+}
+
+// requestID returns the id received in header, else the one of ctx, else a new one.
+func requestID(ctx context.Context, header string) string {
+	if header != "" {
+		return header
+	}
+	if rid := reqid.Get(ctx); rid != "" {
+		return rid
+	}
+
+	return reqid.New()
 }
 
 type errorHeaderInterceptor struct{}
 
+// NewErrorHeaderInterceptor returns an interceptor converting the AppErrors
+// returned by handlers with ToConnectError, and adding the request id to every
+// error, so that clients can quote it when reporting a problem.
+// It must be installed after the request id interceptor.
+func NewErrorHeaderInterceptor() connect.Interceptor {
+	return errorHeaderInterceptor{}
+}
+
 func (in errorHeaderInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		res, err := next(ctx, req)
-		if err == nil {
-			return res, nil
+		if err == nil || req.Spec().IsClient {
+			return res, err
 		}
 
-		// We can’t mutate response headers on error without a response.
-		return res, err
+		return res, handlerError(ctx, err)
 	}
 }
 
+// WrapStreamingClient leaves client streams untouched: received errors already
+// carry their details, see AppErrorFromConnect.
 func (in errorHeaderInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
 	return next
 }
+
 func (in errorHeaderInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
-	return next
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := next(ctx, conn); err != nil {
+			return handlerError(ctx, err)
+		}
+
+		return nil
+	}
+}
+
+func handlerError(ctx context.Context, err error) error {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) && apperr.As(err) != nil {
+		err = ToConnectError(err)
+	}
+	if errors.As(err, &cerr) {
+		addRequestID(cerr, reqid.Get(ctx))
+	}
+
+	return err
 }
 
 type loggingInterceptor struct {
 	logger logging.Logger
 }
 
-func NewLoggingInterceptor(logger logging.Logger) *loggingInterceptor {
+// NewLoggingInterceptor returns an interceptor logging every call, with the
+// details of the AppError of failed calls.
+func NewLoggingInterceptor(logger logging.Logger) connect.Interceptor {
 	return &loggingInterceptor{
 		logger: logger,
 	}
 }
 
-func (in loggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+func (in *loggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		start := time.Now()
 		res, err := next(ctx, req)
-		dur := time.Since(start)
-
-		rid := reqid.Get(ctx)
-		fields := []logging.Field{
-			logging.String("request_id", rid),
-			logging.String("procedure", req.Spec().Procedure),
-			logging.String("protocol", req.Peer().Protocol),
-			logging.Duration("duration", dur),
-			logging.String("peer", req.Peer().Addr),
-		}
+		in.log(ctx, req.Spec(), req.Peer(), time.Since(start), err)
 
-		if err == nil {
-			in.logger.Info("rpc", fields...)
-			return res, nil
+		return res, err
+	}
+}
+
+func (in *loggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &loggingClientConn{
+			StreamingClientConn: next(ctx, spec),
+			interceptor:         in,
+			ctx:                 ctx,
+			start:               time.Now(),
 		}
+	}
+}
 
-		ae := apperr.As(err)
-		if ae == nil {
-			in.logger.Error("rpc_error", append(fields, logging.String("error", err.Error()))...)
+func (in *loggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		start := time.Now()
+		err := next(ctx, conn)
+		in.log(ctx, conn.Spec(), conn.Peer(), time.Since(start), err)
 
-			return res, err
-		}
+		return err
+	}
+}
 
-		// structured error for Kibana
-		fields = append(fields,
-			logging.String("err_code", ae.Code),
-			logging.String("err_visibility", string(ae.Visibility)),
-			logging.Int("http_status", ae.HTTPStatus),
-			logging.String("op", ae.Op),
-		)
+func (in *loggingInterceptor) log(ctx context.Context, spec connect.Spec, peer connect.Peer, dur time.Duration, err error) {
+	fields := []logging.Field{
+		logging.String("request_id", reqid.Get(ctx)),
+		logging.String("procedure", spec.Procedure),
+		logging.String("stream_type", spec.StreamType.String()),
+		logging.String("protocol", peer.Protocol),
+		logging.Duration("duration", dur),
+		logging.String("peer", peer.Addr),
+	}
 
-		if ae.Fields != nil {
-			fields = append(fields, logging.Any("err_fields", ae.Fields))
-		}
-		if ae.Req != nil {
-			fields = append(fields, logging.Any("req_decoded", ae.Req))
-		}
-		if ae.Cause != nil {
-			fields = append(fields, logging.String("cause", ae.Cause.Error()))
-		}
-		if ae.Stack != "" {
-			fields = append(fields, logging.String("stack", ae.Stack))
-		}
+	if err == nil {
+		in.logger.InfoContext(ctx, "rpc", fields...)
 
-		in.logger.Error("rpc_error", fields...)
+		return
+	}
 
-		return res, err
+	ae := apperr.As(err)
+	if ae == nil {
+		in.logger.ErrorContext(ctx, "rpc_error", append(fields, logging.String("error", err.Error()))...)
+
+		return
+	}
+
+	// structured error for Kibana
+	fields = append(fields,
+		logging.String("err_code", ae.Code),
+		logging.String("err_visibility", string(ae.Visibility)),
+		logging.Int("http_status", ae.HTTPStatus),
+		logging.String("op", ae.Op),
+	)
+
+	if ae.Fields != nil {
+		fields = append(fields, logging.Any("err_fields", ae.Fields))
+	}
+	if ae.Req != nil {
+		fields = append(fields, logging.Any("req_decoded", ae.Req))
 	}
+	if ae.Cause != nil {
+		fields = append(fields, logging.String("cause", ae.Cause.Error()))
+	}
+	if ae.Stack != "" {
+		fields = append(fields, logging.String("stack", ae.Stack))
+	}
+
+	in.logger.ErrorContext(ctx, "rpc_error", fields...)
 }
 
-func (in loggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
-	return next
+// loggingClientConn logs a client stream once its response is closed, with the
+// first error received.
+type loggingClientConn struct {
+	connect.StreamingClientConn
+
+	interceptor *loggingInterceptor
+	ctx         context.Context //nolint:containedctx // the conn lives as long as the stream
+	start       time.Time
+
+	mu   sync.Mutex
+	err  error
+	once sync.Once
 }
-func (in loggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
-	return next
+
+func (c *loggingClientConn) Receive(msg any) error {
+	err := c.StreamingClientConn.Receive(msg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = err
+		}
+		c.mu.Unlock()
+	}
+
+	return err //nolint:wrapcheck // stream errors are returned as is
+}
+
+func (c *loggingClientConn) CloseResponse() error {
+	err := c.StreamingClientConn.CloseResponse()
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.interceptor.log(c.ctx, c.Spec(), c.Peer(), time.Since(c.start), c.err)
+	})
+
+	return err //nolint:wrapcheck // stream errors are returned as is
 }
@@ -0,0 +1,119 @@
+package connectx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logger/zap"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/reqid"
+)
+
+// fakeHandlerConn is the server side of a stream without network.
+type fakeHandlerConn struct {
+	requestHeader  http.Header
+	responseHeader http.Header
+}
+
+func newFakeHandlerConn() *fakeHandlerConn {
+	return &fakeHandlerConn{requestHeader: http.Header{}, responseHeader: http.Header{}}
+}
+
+func (c *fakeHandlerConn) Spec() connect.Spec {
+	return connect.Spec{Procedure: "/test.v1.TestService/Watch", StreamType: connect.StreamTypeServer}
+}
+func (c *fakeHandlerConn) Peer() connect.Peer           { return connect.Peer{} }
+func (c *fakeHandlerConn) Receive(any) error            { return nil }
+func (c *fakeHandlerConn) RequestHeader() http.Header   { return c.requestHeader }
+func (c *fakeHandlerConn) Send(any) error               { return nil }
+func (c *fakeHandlerConn) ResponseHeader() http.Header  { return c.responseHeader }
+func (c *fakeHandlerConn) ResponseTrailer() http.Header { return http.Header{} }
+
+func TestNewInterceptors(t *testing.T) {
+	interceptors, err := NewInterceptors(config.ServerConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, interceptors)
+
+	interceptors, err = NewInterceptors(config.ServerConfig{Interceptors: config.InterceptorsConfig{
+		RequestID:    true,
+		Logging:      true,
+		ErrorDetails: true,
+		Deadline:     true,
+		Validation:   true,
+	}}, zap.NewNop())
+	require.NoError(t, err)
+	assert.Len(t, interceptors, 5)
+}
+
+func TestRequestIDInterceptor_StreamingHandler(t *testing.T) {
+	var gotRID string
+	handler := NewRequestIDInterceptor().WrapStreamingHandler(
+		func(ctx context.Context, _ connect.StreamingHandlerConn) error {
+			gotRID = reqid.Get(ctx)
+
+			return nil
+		})
+
+	conn := newFakeHandlerConn()
+	require.NoError(t, handler(context.Background(), conn))
+	assert.NotEmpty(t, gotRID)
+	assert.Equal(t, gotRID, conn.responseHeader.Get(RequestIDHeader))
+
+	conn = newFakeHandlerConn()
+	conn.requestHeader.Set(RequestIDHeader, "from-client")
+	require.NoError(t, handler(context.Background(), conn))
+	assert.Equal(t, "from-client", gotRID)
+	assert.Equal(t, "from-client", conn.responseHeader.Get(RequestIDHeader))
+}
+
+func TestErrorHeaderInterceptor_StreamingHandler(t *testing.T) {
+	chain := NewRequestIDInterceptor().WrapStreamingHandler(NewErrorHeaderInterceptor().WrapStreamingHandler(
+		func(context.Context, connect.StreamingHandlerConn) error {
+			return errCodeTest.New("conflict")
+		}))
+
+	conn := newFakeHandlerConn()
+	conn.requestHeader.Set(RequestIDHeader, "rid-7")
+	err := chain(context.Background(), conn)
+
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeAlreadyExists, cerr.Code())
+	assert.Equal(t, "test.conflict", cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t, "rid-7", cerr.Meta().Get(RequestIDHeader))
+}
+
+func TestLoggingInterceptor_StreamingHandler(t *testing.T) {
+	called := false
+	handler := NewLoggingInterceptor(zap.NewNop()).WrapStreamingHandler(
+		func(context.Context, connect.StreamingHandlerConn) error {
+			called = true
+
+			return errCodeTest.New("conflict")
+		})
+
+	err := handler(context.Background(), newFakeHandlerConn())
+	assert.True(t, called)
+	assert.Error(t, err)
+}
+
+func TestRequestIDInterceptor_UnaryError(t *testing.T) {
+	// Generated handlers return a typed nil response along with their errors.
+	handler := NewRequestIDInterceptor().WrapUnary(
+		func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			var res *connect.Response[emptypb.Empty]
+
+			return res, connect.NewError(connect.CodeNotFound, nil)
+		})
+
+	assert.NotPanics(t, func() {
+		_, err := handler(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+	})
+}
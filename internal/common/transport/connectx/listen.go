@@ -0,0 +1,285 @@
+package connectx
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Schemes of the listen addresses, see ParseListenAddress.
+const (
+	ListenTCP     = "tcp"
+	ListenUnix    = "unix"
+	ListenSystemd = "systemd"
+)
+
+// Environment of the systemd socket activation, see sd_listen_fds(3).
+const (
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+)
+
+var listenExamples = map[string]string{
+	ListenTCP:  "tcp://:4224",
+	ListenUnix: "unix:///run/cleanstack/api.sock",
+}
+
+// listenFDsStart is the first file descriptor passed by systemd.
+var listenFDsStart = 3
+
+// ListenAddress is a parsed listen address.
+type ListenAddress struct {
+	// Scheme is ListenTCP, ListenUnix or ListenSystemd.
+	Scheme string
+	// Address is the host and port of TCP, the path of unix sockets, and the
+	// FileDescriptorName of the systemd sockets, every socket when empty.
+	Address string
+}
+
+// ParseListenAddress parses addr: "tcp://host:port", "unix:///path/to.sock",
+// or "systemd:" for the sockets passed by systemd socket activation,
+// "systemd:name" for those of the FileDescriptorName name.
+func ParseListenAddress(addr string) (ListenAddress, error) {
+	scheme, rest, ok := strings.Cut(addr, ":")
+	if !ok {
+		return ListenAddress{}, fmt.Errorf("listen address %q without scheme, e.g. tcp://:4224", addr)
+	}
+
+	switch scheme {
+	case ListenTCP, ListenUnix:
+		address, ok := strings.CutPrefix(rest, "//")
+		if !ok || address == "" {
+			return ListenAddress{}, fmt.Errorf("invalid listen address %q, e.g. %s", addr, listenExamples[scheme])
+		}
+		if scheme == ListenTCP {
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				return ListenAddress{}, fmt.Errorf("invalid listen address %q: %w", addr, err)
+			}
+		}
+
+		return ListenAddress{Scheme: scheme, Address: address}, nil
+	case ListenSystemd:
+		return ListenAddress{Scheme: scheme, Address: rest}, nil
+	default:
+		return ListenAddress{}, fmt.Errorf("listen address %q: unknown scheme %q", addr, scheme)
+	}
+}
+
+// Listen returns the listeners of the addresses of cfg, see ParseListenAddress.
+// A stale unix socket file is replaced, and the socket gets the mode and group
+// of cfg.UnixSocket. The systemd sockets are taken once, from the environment
+// set by systemd for the process. On error, the listeners opened are closed.
+func Listen(cfg config.ServerConfig) ([]net.Listener, error) {
+	if len(cfg.Listen) == 0 {
+		return nil, errors.New("no listen address")
+	}
+	addrs := make([]ListenAddress, 0, len(cfg.Listen))
+	for _, addr := range cfg.Listen {
+		parsed, err := ParseListenAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, parsed)
+	}
+	mode, err := parseSocketMode(cfg.UnixSocket.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		listeners []net.Listener
+		activated []activatedListener
+	)
+	closeAll := func() {
+		for _, lis := range listeners {
+			_ = lis.Close()
+		}
+		closeActivated(activated)
+	}
+	for _, addr := range addrs {
+		switch addr.Scheme {
+		case ListenTCP:
+			lis, err := net.Listen("tcp", addr.Address)
+			if err != nil {
+				closeAll()
+
+				return nil, fmt.Errorf("failed to listen on %s: %w", addr.Address, err)
+			}
+			listeners = append(listeners, lis)
+		case ListenUnix:
+			lis, err := listenUnix(addr.Address, mode, cfg.UnixSocket.Group)
+			if err != nil {
+				closeAll()
+
+				return nil, err
+			}
+			listeners = append(listeners, lis)
+		case ListenSystemd:
+			if activated == nil {
+				if activated, err = systemdListeners(); err != nil {
+					closeAll()
+
+					return nil, err
+				}
+			}
+			var found bool
+			for i, a := range activated {
+				if a.Listener != nil && (addr.Address == "" || addr.Address == a.name) {
+					listeners = append(listeners, a.Listener)
+					activated[i].Listener = nil
+					found = true
+				}
+			}
+			if !found {
+				closeAll()
+
+				return nil, fmt.Errorf("no socket passed by systemd for %q", ListenSystemd+":"+addr.Address)
+			}
+		}
+	}
+	// The sockets passed by systemd for none of the addresses are not served.
+	closeActivated(activated)
+
+	return listeners, nil
+}
+
+// ListenerAddr returns the address of lis in the listen address format, e.g.
+// for logs.
+func ListenerAddr(lis net.Listener) string {
+	return lis.Addr().Network() + "://" + lis.Addr().String()
+}
+
+func parseSocketMode(mode string) (fs.FileMode, error) {
+	if mode == "" {
+		return 0o660, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("invalid unix socket mode %q, e.g. \"0660\"", mode)
+	}
+
+	return fs.FileMode(m), nil
+}
+
+// ListenUnixSocket listens on the unix socket path, as the unix addresses of
+// Listen: a stale socket file is replaced, other files are kept, and the
+// socket gets mode and, when not empty, group.
+func ListenUnixSocket(path string, mode fs.FileMode, group string) (net.Listener, error) {
+	return listenUnix(path, mode, group)
+}
+
+func listenUnix(path string, mode fs.FileMode, group string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			_ = lis.Close()
+
+			return nil, fmt.Errorf("unknown unix socket group: %w", err)
+		}
+		gid, _ := strconv.Atoi(g.Gid)
+		if err := os.Chown(path, -1, gid); err != nil {
+			_ = lis.Close()
+
+			return nil, fmt.Errorf("failed to set unix socket group: %w", err)
+		}
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = lis.Close()
+
+		return nil, fmt.Errorf("failed to set unix socket mode: %w", err)
+	}
+
+	return lis, nil
+}
+
+// removeStaleSocket removes the socket file left at path by a process that
+// did not stop cleanly. Other files are kept, for listen to fail.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check unix socket: %w", err)
+	case info.Mode().Type() != fs.ModeSocket:
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale unix socket: %w", err)
+	}
+
+	return nil
+}
+
+type activatedListener struct {
+	net.Listener // nil once taken by an address
+	name         string
+}
+
+func closeActivated(activated []activatedListener) {
+	for _, a := range activated {
+		if a.Listener != nil {
+			_ = a.Close()
+		}
+	}
+}
+
+// systemdListeners returns the listeners passed by systemd to the process, by
+// FileDescriptorName, and unsets the environment describing them, not to pass
+// it to the child processes.
+func systemdListeners() ([]activatedListener, error) {
+	defer func() {
+		_ = os.Unsetenv(listenPIDEnv)
+		_ = os.Unsetenv(listenFDsEnv)
+		_ = os.Unsetenv(listenFDNamesEnv)
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv(listenPIDEnv)); err != nil || pid != os.Getpid() {
+		return nil, errors.New("no socket passed by systemd, see systemd.socket(5)")
+	}
+	n, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	if err != nil || n <= 0 {
+		return nil, errors.New("no socket passed by systemd, see systemd.socket(5)")
+	}
+	names := strings.Split(os.Getenv(listenFDNamesEnv), ":")
+
+	listeners := make([]activatedListener, 0, n)
+	for i := range n {
+		fd := listenFDsStart + i
+		syscall.CloseOnExec(fd)
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(fd), name)
+		lis, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			closeActivated(listeners)
+
+			return nil, fmt.Errorf("systemd socket %s is not a stream listener: %w", name, err)
+		}
+		listeners = append(listeners, activatedListener{Listener: lis, name: name})
+	}
+
+	return listeners, nil
+}
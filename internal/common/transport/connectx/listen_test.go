@@ -0,0 +1,119 @@
+package connectx
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	for addr, want := range map[string]ListenAddress{
+		"tcp://:4224":                 {Scheme: ListenTCP, Address: ":4224"},
+		"tcp://127.0.0.1:4224":        {Scheme: ListenTCP, Address: "127.0.0.1:4224"},
+		"unix:///run/cleanstack.sock": {Scheme: ListenUnix, Address: "/run/cleanstack.sock"},
+		"systemd:":                    {Scheme: ListenSystemd},
+		"systemd:api":                 {Scheme: ListenSystemd, Address: "api"},
+	} {
+		got, err := ParseListenAddress(addr)
+		require.NoError(t, err, addr)
+		assert.Equal(t, want, got, addr)
+	}
+
+	for _, addr := range []string{":4224", "tcp://", "tcp://localhost", "unix:/run/cleanstack.sock", "udp://:53"} {
+		_, err := ParseListenAddress(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listeners, err := Listen(config.ServerConfig{
+		Listen:     []string{"tcp://127.0.0.1:0", "unix://" + path},
+		UnixSocket: config.UnixSocketConfig{Mode: "0600"},
+	})
+	require.NoError(t, err, "a stale socket file is replaced")
+	require.Len(t, listeners, 2)
+	assert.Contains(t, ListenerAddr(listeners[0]), "tcp://127.0.0.1:")
+	assert.Equal(t, "unix://"+path, ListenerAddr(listeners[1]))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	for _, lis := range listeners {
+		require.NoError(t, lis.Close())
+	}
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestListen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	for _, cfg := range []config.ServerConfig{
+		{},
+		{Listen: []string{"tcp://:4224"}, UnixSocket: config.UnixSocketConfig{Mode: "rw"}},
+		{Listen: []string{"unix://" + path}},
+		{Listen: []string{"systemd:"}},
+	} {
+		_, err := Listen(cfg)
+		assert.Error(t, err, cfg.Listen)
+	}
+}
+
+func TestListen_Systemd(t *testing.T) {
+	api, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	other, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	// The sockets are passed as raw file descriptors, closed by Listen.
+	fds := make([]int, 0, 2)
+	for _, lis := range []net.Listener{api, other} {
+		raw, err := lis.(*net.TCPListener).SyscallConn()
+		require.NoError(t, err)
+		require.NoError(t, raw.Control(func(fd uintptr) {
+			dup, dupErr := syscall.Dup(int(fd))
+			require.NoError(t, dupErr)
+			fds = append(fds, dup)
+		}))
+	}
+	require.NoError(t, api.Close())
+	require.NoError(t, other.Close())
+	if fds[1] != fds[0]+1 {
+		for _, fd := range fds {
+			_ = syscall.Close(fd)
+		}
+		t.Skip("the file descriptors of the listeners are not consecutive")
+	}
+
+	start := listenFDsStart
+	listenFDsStart = fds[0]
+	t.Cleanup(func() { listenFDsStart = start })
+	t.Setenv(listenPIDEnv, strconv.Itoa(os.Getpid()))
+	t.Setenv(listenFDsEnv, "2")
+	t.Setenv(listenFDNamesEnv, "api:other")
+
+	listeners, err := Listen(config.ServerConfig{Listen: []string{"systemd:api"}})
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	assert.Equal(t, "tcp://"+api.Addr().String(), ListenerAddr(listeners[0]))
+	require.NoError(t, listeners[0].Close())
+	assert.Empty(t, os.Getenv(listenFDsEnv), "the environment is not passed to the child processes")
+
+	_, err = Listen(config.ServerConfig{Listen: []string{"systemd:"}})
+	require.Error(t, err, "the sockets are taken once")
+}
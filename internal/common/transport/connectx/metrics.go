@@ -0,0 +1,120 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+)
+
+// codeOK is the code label of the calls without error.
+const codeOK = "ok"
+
+type metricsInterceptor struct {
+	ignored  map[string]struct{}
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// NewMetricsInterceptor returns an interceptor measuring the calls handled by
+// the server, but the ignored procedures:
+//   - rpc_requests_total, by procedure and code;
+//   - rpc_request_duration_seconds, by procedure;
+//   - rpc_requests_in_flight, by procedure.
+//
+// Only the procedures of the registered services reach the interceptor, which
+// bounds the cardinality of the procedure label.
+func NewMetricsInterceptor(reg *metrics.Registry, ignored []string) (connect.Interceptor, error) {
+	in := &metricsInterceptor{
+		ignored: make(map[string]struct{}, len(ignored)),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rpc_requests_total",
+			Help: "Number of RPC calls handled, by procedure and code.",
+		}, []string{"procedure", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rpc_request_duration_seconds",
+			Help:    "Duration of the RPC calls handled, by procedure.",
+			Buckets: reg.Buckets(),
+		}, []string{"procedure"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rpc_requests_in_flight",
+			Help: "Number of RPC calls being handled, by procedure.",
+		}, []string{"procedure"}),
+	}
+	for _, procedure := range ignored {
+		in.ignored[procedure] = struct{}{}
+	}
+
+	if err := reg.Register(in.requests, in.duration, in.inFlight); err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped by the registry
+	}
+
+	return in, nil
+}
+
+func (in *metricsInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		done := in.start(req.Spec().Procedure)
+		res, err := next(ctx, req)
+		done(err)
+
+		return res, err
+	}
+}
+
+func (in *metricsInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (in *metricsInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		done := in.start(conn.Spec().Procedure)
+		err := next(ctx, conn)
+		done(err)
+
+		return err
+	}
+}
+
+// start measures a call of procedure, until the returned function is called
+// with its error.
+func (in *metricsInterceptor) start(procedure string) func(err error) {
+	if _, ok := in.ignored[procedure]; ok {
+		return func(error) {}
+	}
+
+	start := time.Now()
+	inFlight := in.inFlight.WithLabelValues(procedure)
+	inFlight.Inc()
+
+	return func(err error) {
+		inFlight.Dec()
+		in.duration.WithLabelValues(procedure).Observe(time.Since(start).Seconds())
+		in.requests.WithLabelValues(procedure, codeOf(err)).Inc()
+	}
+}
+
+// codeOf returns the Connect code of err, the one of its AppError when it was
+// not converted yet, or "ok".
+func codeOf(err error) string {
+	if err == nil {
+		return codeOK
+	}
+
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) && apperr.As(err) != nil {
+		err = ToConnectError(err)
+	}
+
+	return connect.CodeOf(err).String()
+}
@@ -0,0 +1,67 @@
+package connectx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/metrics"
+)
+
+func TestMetricsInterceptor(t *testing.T) {
+	reg := metrics.NewRegistry(config.MetricsConfig{Namespace: "test", Buckets: []float64{0.1, 1}})
+	interceptor, err := NewMetricsInterceptor(reg, []string{"/test.v1.TestService/Ignored"})
+	require.NoError(t, err)
+
+	echo := func(_ context.Context, req *connect.Request[wrapperspb.StringValue]) (
+		*connect.Response[wrapperspb.StringValue], error,
+	) {
+		if req.Msg.GetValue() == "missing" {
+			return nil, apperr.NotFound("test.not_found", "not found")
+		}
+
+		return connect.NewResponse(req.Msg), nil
+	}
+	mux := http.NewServeMux()
+	for _, procedure := range []string{"/test.v1.TestService/Echo", "/test.v1.TestService/Ignored"} {
+		mux.Handle(procedure, connect.NewUnaryHandler(procedure, echo, connect.WithInterceptors(interceptor)))
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for _, call := range []struct{ procedure, value string }{
+		{"/test.v1.TestService/Echo", "hello"},
+		{"/test.v1.TestService/Echo", "hello"},
+		{"/test.v1.TestService/Echo", "missing"},
+		{"/test.v1.TestService/Ignored", "hello"},
+	} {
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			http.DefaultClient, srv.URL+call.procedure)
+		_, _ = client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String(call.value)))
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body),
+		`test_rpc_requests_total{code="ok",procedure="/test.v1.TestService/Echo"} 2`)
+	assert.Contains(t, string(body),
+		`test_rpc_requests_total{code="not_found",procedure="/test.v1.TestService/Echo"} 1`)
+	assert.Contains(t, string(body),
+		`test_rpc_request_duration_seconds_count{procedure="/test.v1.TestService/Echo"} 3`)
+	assert.Contains(t, string(body),
+		`test_rpc_requests_in_flight{procedure="/test.v1.TestService/Echo"} 0`)
+	assert.NotContains(t, string(body), "Ignored")
+	assert.Contains(t, string(body), "go_goroutines")
+}
@@ -0,0 +1,227 @@
+package connectx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Paths of the liveness and readiness probes.
+const (
+	LivenessPath  = "/livez"
+	ReadinessPath = "/readyz"
+)
+
+// Statuses of the probes and their checks.
+const (
+	ProbeStatusOK     = "ok"
+	ProbeStatusFailed = "failed"
+)
+
+// CheckFunc checks a dependency of the server, failing when the server cannot
+// serve requests.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// ProbeReport is the body of the probes.
+type ProbeReport struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks,omitempty"`
+	CheckedAt time.Time              `json:"checkedAt"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Probes serves the liveness and readiness probes of the server. The server
+// is live as long as it answers, and ready when every registered check passes.
+// The checks run concurrently, each bounded by the configured timeout, and
+// their report is reused for the configured TTL.
+type Probes struct {
+	cfg config.HealthConfig
+
+	// runMu serializes the runs of the checks, for concurrent probes to share a report.
+	runMu sync.Mutex
+
+	mu     sync.Mutex
+	checks []namedCheck
+	report *ProbeReport
+}
+
+// NewProbes returns probes without check.
+func NewProbes(cfg config.HealthConfig) *Probes {
+	return &Probes{cfg: cfg}
+}
+
+// Register adds a readiness check, replacing the one of the same name.
+func (p *Probes) Register(name string, check CheckFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.report = nil
+	for i, c := range p.checks {
+		if c.name == name {
+			p.checks[i].check = check
+
+			return
+		}
+	}
+	p.checks = append(p.checks, namedCheck{name: name, check: check})
+}
+
+// Ready runs the readiness checks, or returns their last report when it is
+// more recent than the cache TTL.
+func (p *Probes) Ready(ctx context.Context) ProbeReport {
+	if report, ok := p.cached(); ok {
+		return report
+	}
+
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	// Another probe may have run the checks while this one was waiting.
+	if report, ok := p.cached(); ok {
+		return report
+	}
+
+	p.mu.Lock()
+	checks := p.checks
+	p.mu.Unlock()
+
+	report := p.run(ctx, checks)
+
+	p.mu.Lock()
+	p.report = &report
+	p.mu.Unlock()
+
+	return report
+}
+
+func (p *Probes) cached() (ProbeReport, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.report == nil || time.Since(p.report.CheckedAt) >= p.cfg.CacheTTL {
+		return ProbeReport{}, false
+	}
+
+	return *p.report, true
+}
+
+func (p *Probes) run(ctx context.Context, checks []namedCheck) ProbeReport {
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			results[i] = p.runCheck(ctx, c.check)
+		})
+	}
+	wg.Wait()
+
+	report := ProbeReport{
+		Status:    ProbeStatusOK,
+		Checks:    make(map[string]CheckResult, len(checks)),
+		CheckedAt: time.Now(),
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != ProbeStatusOK {
+			report.Status = ProbeStatusFailed
+		}
+	}
+
+	return report
+}
+
+func (p *Probes) runCheck(ctx context.Context, check CheckFunc) CheckResult {
+	if p.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: ProbeStatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = ProbeStatusFailed
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// LivenessHandler answers 200 as long as the server serves HTTP requests.
+func (p *Probes) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeProbeReport(w, ProbeReport{Status: ProbeStatusOK, CheckedAt: time.Now()})
+	})
+}
+
+// ReadinessHandler answers 200 when every check passes, 503 otherwise, with
+// the status of every check. Their errors, which may reveal hosts, driver
+// messages or schema versions, are left out: it is served publicly.
+func (p *Probes) ReadinessHandler() http.Handler {
+	return p.readinessHandler(false)
+}
+
+// ReadinessDetailsHandler is ReadinessHandler with the errors of the checks,
+// for the admin listener.
+func (p *Probes) ReadinessDetailsHandler() http.Handler {
+	return p.readinessHandler(true)
+}
+
+func (p *Probes) readinessHandler(details bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The checks are shared by the concurrent probes, a probe giving up does not cancel them.
+		report := p.Ready(context.WithoutCancel(r.Context()))
+		if !details {
+			report = report.withoutErrors()
+		}
+		writeProbeReport(w, report)
+	})
+}
+
+// withoutErrors returns a copy of r without the errors of the checks.
+func (r ProbeReport) withoutErrors() ProbeReport {
+	checks := make(map[string]CheckResult, len(r.Checks))
+	for name, check := range r.Checks {
+		check.Error = ""
+		checks[name] = check
+	}
+	r.Checks = checks
+
+	return r
+}
+
+func writeProbeReport(w http.ResponseWriter, report ProbeReport) {
+	status := http.StatusOK
+	if report.Status != ProbeStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode probe report: %v", err), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,100 @@
+package connectx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func getProbe(t *testing.T, h http.Handler, path string) (int, ProbeReport) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report ProbeReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+
+	return rec.Code, report
+}
+
+func TestProbes_Readiness(t *testing.T) {
+	probes := NewProbes(config.HealthConfig{Timeout: 20 * time.Millisecond})
+
+	var dbErr error
+	probes.Register("database", func(context.Context) error { return dbErr })
+	probes.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+	probes.Register("slow", func(context.Context) error { return nil })
+
+	code, report := getProbe(t, probes.ReadinessHandler(), ReadinessPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ProbeStatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, ProbeStatusOK, report.Checks["database"].Status)
+
+	dbErr = errors.New("connection refused")
+	probes.Register("timeout", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	code, report = getProbe(t, probes.ReadinessHandler(), ReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ProbeStatusFailed, report.Status)
+	assert.Equal(t, ProbeStatusFailed, report.Checks["database"].Status)
+	assert.Empty(t, report.Checks["database"].Error, "the errors are not public")
+
+	code, report = getProbe(t, probes.ReadinessDetailsHandler(), ReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["timeout"].Error)
+	assert.Equal(t, ProbeStatusOK, report.Checks["slow"].Status)
+}
+
+func TestProbes_Cache(t *testing.T) {
+	probes := NewProbes(config.HealthConfig{CacheTTL: time.Hour})
+
+	var runs atomic.Int32
+	probes.Register("database", func(context.Context) error {
+		runs.Add(1)
+
+		return nil
+	})
+
+	for range 3 {
+		probes.Ready(context.Background())
+	}
+	assert.Equal(t, int32(1), runs.Load())
+
+	// Registering a check invalidates the cached report.
+	probes.Register("other", func(context.Context) error { return nil })
+	report := probes.Ready(context.Background())
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestProbes_Liveness(t *testing.T) {
+	probes := NewProbes(config.HealthConfig{})
+	probes.Register("database", func(context.Context) error { return errors.New("connection refused") })
+
+	code, report := getProbe(t, probes.LivenessHandler(), LivenessPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ProbeStatusOK, report.Status)
+	assert.Empty(t, report.Checks)
+}
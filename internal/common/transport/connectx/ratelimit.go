@@ -0,0 +1,323 @@
+package connectx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+// APIKeyHeader carries the API key of the clients. The rate limits by API key
+// only apply once it is verified, see principal.WithAPIKey.
+const APIKeyHeader = "X-Api-Key"
+
+// Rate limit keys, identifying the clients of a policy.
+const (
+	RateLimitKeyIP        = "ip"
+	RateLimitKeyPrincipal = "principal"
+	RateLimitKeyAPIKey    = "api-key"
+)
+
+const (
+	rateLimitAnyProcedure = "*"
+	memoryRateLimitSweep  = time.Minute
+)
+
+var (
+	// ErrCodeRateLimited is returned when a client exceeds the rate limit of a procedure.
+	ErrCodeRateLimited = apperr.Register(apperr.Definition{
+		Code:        "rate_limit.exceeded",
+		HTTPStatus:  http.StatusTooManyRequests,
+		Description: "Too many requests, retry after the delay of the Retry-After header and google.rpc.RetryInfo detail.",
+	})
+	// ErrCodeRateLimitUnavailable is returned when the rate limit store fails.
+	ErrCodeRateLimitUnavailable = apperr.Register(apperr.Definition{
+		Code:        "rate_limit.unavailable",
+		HTTPStatus:  http.StatusServiceUnavailable,
+		Description: "The rate limits cannot be checked, the request was not processed.",
+	})
+)
+
+// RateLimit is a token bucket of Burst tokens, refilled with Requests tokens per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Rate is the refill rate, in tokens per second.
+func (l RateLimit) Rate() float64 { return float64(l.Requests) / l.Period.Seconds() }
+
+// RetryAfter returns how long a bucket holding tokens waits for a full token,
+// 0 when it already has one.
+func (l RateLimit) RetryAfter(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil((1 - tokens) / l.Rate() * float64(time.Second)))
+}
+
+// RateLimitStore keeps the token buckets of the clients.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of key, created full. It returns 0 when
+	// a token was taken, else how long until one is available.
+	Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error)
+}
+
+type rateLimitPolicy struct {
+	name  string
+	key   string
+	limit RateLimit
+}
+
+// RateLimitInterceptor limits the calls of the procedures with a policy, see NewRateLimitInterceptor.
+type RateLimitInterceptor struct {
+	store RateLimitStore
+	rules atomic.Pointer[rateLimitRules]
+}
+
+type rateLimitRules struct {
+	clientIPHeader string
+	policies       map[string]*rateLimitPolicy // by procedure, rateLimitAnyProcedure for the default one
+}
+
+// NewRateLimitInterceptor returns an interceptor limiting the calls of the
+// procedures with a policy in cfg. Limited calls fail with CodeResourceExhausted,
+// the ErrCodeRateLimited code, and the delay before a token is available in a
+// google.rpc.RetryInfo detail and the Retry-After header.
+// A stream takes a single token, when it starts.
+func NewRateLimitInterceptor(cfg config.RateLimitConfig, store RateLimitStore) (*RateLimitInterceptor, error) {
+	rules, err := newRateLimitRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &RateLimitInterceptor{store: store}
+	in.rules.Store(rules)
+
+	return in, nil
+}
+
+// Reload validates the policies and client IP header of cfg, and returns the
+// function applying them to the next calls. The buckets of the clients are
+// kept, a changed limit applies to their tokens left.
+func (in *RateLimitInterceptor) Reload(cfg config.RateLimitConfig) (func(), error) {
+	rules, err := newRateLimitRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { in.rules.Store(rules) }, nil
+}
+
+func newRateLimitRules(cfg config.RateLimitConfig) (*rateLimitRules, error) {
+	rules := &rateLimitRules{
+		clientIPHeader: cfg.ClientIPHeader,
+		policies:       map[string]*rateLimitPolicy{},
+	}
+
+	for _, p := range cfg.Policies {
+		policy, err := newRateLimitPolicy(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, procedure := range p.Procedures {
+			if _, ok := rules.policies[procedure]; ok {
+				return nil, fmt.Errorf("rate limit policy %q: procedure %s has several policies", p.Name, procedure)
+			}
+			rules.policies[procedure] = policy
+		}
+	}
+
+	return rules, nil
+}
+
+func newRateLimitPolicy(p config.RateLimitPolicy) (*rateLimitPolicy, error) {
+	if p.Name == "" {
+		return nil, errors.New("rate limit policy without name")
+	}
+	switch p.Key {
+	case RateLimitKeyIP, RateLimitKeyPrincipal, RateLimitKeyAPIKey:
+	default:
+		return nil, fmt.Errorf("rate limit policy %q: unknown key %q", p.Name, p.Key)
+	}
+	if p.Requests <= 0 || p.Period <= 0 || p.Burst < 0 {
+		return nil, fmt.Errorf("rate limit policy %q: requests and period must be positive", p.Name)
+	}
+
+	limit := RateLimit{Requests: p.Requests, Period: p.Period, Burst: p.Burst}
+	if limit.Burst == 0 {
+		limit.Burst = limit.Requests
+	}
+
+	return &rateLimitPolicy{name: p.Name, key: p.Key, limit: limit}, nil
+}
+
+func (in *RateLimitInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := in.take(ctx, req.Spec(), req.Header(), req.Peer()); err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient leaves client streams untouched, rate limits are enforced by servers.
+func (in *RateLimitInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (in *RateLimitInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := in.take(ctx, conn.Spec(), conn.RequestHeader(), conn.Peer()); err != nil {
+			return err
+		}
+
+		return next(ctx, conn)
+	}
+}
+
+// take takes a token for the call, and returns the Connect error of limited calls.
+func (in *RateLimitInterceptor) take(ctx context.Context, spec connect.Spec, header http.Header, peer connect.Peer) error {
+	rules := in.rules.Load()
+	policy, ok := rules.policies[spec.Procedure]
+	if !ok {
+		if policy, ok = rules.policies[rateLimitAnyProcedure]; !ok {
+			return nil
+		}
+	}
+
+	key := policy.name + "|" + rules.clientKey(ctx, policy.key, header, peer)
+	retryAfter, err := in.store.Take(ctx, key, policy.limit)
+	if err != nil {
+		return ToConnectError(ErrCodeRateLimitUnavailable.Wrap(err))
+	}
+	if retryAfter > 0 {
+		return ToConnectError(apperr.WithRetryAfter(ErrCodeRateLimited.New("too many requests"), retryAfter))
+	}
+
+	return nil
+}
+
+// clientKey identifies the client of a call by kind, falling back to its IP.
+// Only the identities verified by an authentication layer are trusted, else
+// clients would get a bucket per random value.
+func (r *rateLimitRules) clientKey(ctx context.Context, kind string, header http.Header, peer connect.Peer) string {
+	switch kind {
+	case RateLimitKeyPrincipal:
+		if p := principal.Get(ctx); p != "" {
+			return RateLimitKeyPrincipal + ":" + p
+		}
+	case RateLimitKeyAPIKey:
+		if apiKey := principal.APIKey(ctx); apiKey != "" {
+			// API keys are secrets, only their hash is stored.
+			sum := sha256.Sum256([]byte(apiKey))
+
+			return RateLimitKeyAPIKey + ":" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	return RateLimitKeyIP + ":" + clientIP(header, peer, r.clientIPHeader)
+}
+
+// clientIP returns the last address of the clientIPHeader header when set and
+// present, else the host of the peer address.
+func clientIP(header http.Header, peer connect.Peer, clientIPHeader string) string {
+	if clientIPHeader != "" {
+		if values := header.Values(clientIPHeader); len(values) > 0 {
+			addrs := strings.Split(values[len(values)-1], ",")
+			if ip := strings.TrimSpace(addrs[len(addrs)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(peer.Addr); err == nil {
+		return host
+	}
+
+	return peer.Addr
+}
+
+// MemoryRateLimitStore keeps the token buckets in memory, for a single replica.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     RateLimit
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	if retryAfter := limit.RetryAfter(b.tokens); retryAfter > 0 {
+		return retryAfter, nil
+	}
+	b.tokens--
+
+	return 0, nil
+}
+
+// sweep drops the full buckets, at most once per memoryRateLimitSweep: they
+// are the same as new ones.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryRateLimitSweep {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func (b *memoryBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate())
+	b.updatedAt = now
+}
@@ -0,0 +1,136 @@
+package connectx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Requests: 1, Period: time.Second, Burst: 2}
+	ctx := context.Background()
+
+	for range 2 {
+		retryAfter, err := store.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.Zero(t, retryAfter)
+	}
+
+	retryAfter, err := store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Other keys have their own bucket.
+	retryAfter, err = store.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	retryAfter, err = store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	retryAfter, err = store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// Full buckets are swept.
+	now = now.Add(time.Hour)
+	_, err = store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+}
+
+func TestNewRateLimitInterceptor_InvalidPolicies(t *testing.T) {
+	valid := config.RateLimitPolicy{Name: "p", Procedures: []string{"*"}, Key: "ip", Requests: 1, Period: time.Second}
+
+	for name, mutate := range map[string]func(p *config.RateLimitPolicy){
+		"no name":     func(p *config.RateLimitPolicy) { p.Name = "" },
+		"unknown key": func(p *config.RateLimitPolicy) { p.Key = "cookie" },
+		"no requests": func(p *config.RateLimitPolicy) { p.Requests = 0 },
+		"no period":   func(p *config.RateLimitPolicy) { p.Period = 0 },
+	} {
+		p := valid
+		mutate(&p)
+		_, err := NewRateLimitInterceptor(config.RateLimitConfig{Policies: []config.RateLimitPolicy{p}}, nil)
+		assert.Error(t, err, name)
+	}
+
+	_, err := NewRateLimitInterceptor(config.RateLimitConfig{Policies: []config.RateLimitPolicy{valid, valid}}, nil)
+	assert.Error(t, err, "procedure with several policies")
+}
+
+func TestRateLimitInterceptor_StreamingHandler(t *testing.T) {
+	in, err := NewRateLimitInterceptor(config.RateLimitConfig{Policies: []config.RateLimitPolicy{
+		{Name: "watch", Procedures: []string{"/test.v1.TestService/Watch"}, Key: "ip", Requests: 1, Period: time.Minute},
+	}}, NewMemoryRateLimitStore())
+	require.NoError(t, err)
+
+	handler := in.WrapStreamingHandler(func(context.Context, connect.StreamingHandlerConn) error { return nil })
+	require.NoError(t, handler(context.Background(), newFakeHandlerConn()))
+
+	err = handler(context.Background(), newFakeHandlerConn())
+	var cerr *connect.Error
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, connect.CodeResourceExhausted, cerr.Code())
+	assert.Equal(t, ErrCodeRateLimited.Code, cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t, "60", cerr.Meta().Get(RetryAfterHeader))
+}
+
+func TestRateLimitInterceptor_Reload(t *testing.T) {
+	watch := config.RateLimitPolicy{
+		Name: "watch", Procedures: []string{"/test.v1.TestService/Watch"}, Key: "ip", Requests: 1, Period: time.Minute,
+	}
+	in, err := NewRateLimitInterceptor(config.RateLimitConfig{}, NewMemoryRateLimitStore())
+	require.NoError(t, err)
+	handler := in.WrapStreamingHandler(func(context.Context, connect.StreamingHandlerConn) error { return nil })
+
+	_, err = in.Reload(config.RateLimitConfig{Policies: []config.RateLimitPolicy{watch, watch}})
+	require.Error(t, err)
+
+	apply, err := in.Reload(config.RateLimitConfig{Policies: []config.RateLimitPolicy{watch}})
+	require.NoError(t, err)
+	for range 2 {
+		require.NoError(t, handler(context.Background(), newFakeHandlerConn()), "not applied yet")
+	}
+
+	apply()
+	require.NoError(t, handler(context.Background(), newFakeHandlerConn()))
+	require.Error(t, handler(context.Background(), newFakeHandlerConn()))
+}
+
+func TestRateLimitRules_ClientKey(t *testing.T) {
+	rules := &rateLimitRules{clientIPHeader: "X-Forwarded-For"}
+	peer := connect.Peer{Addr: "10.0.0.1:51234"}
+	ctx := context.Background()
+
+	assert.Equal(t, "ip:10.0.0.1", rules.clientKey(ctx, RateLimitKeyIP, http.Header{}, peer))
+
+	header := http.Header{}
+	header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	header.Add("X-Forwarded-For", "3.3.3.3")
+	assert.Equal(t, "ip:3.3.3.3", rules.clientKey(ctx, RateLimitKeyIP, header, peer))
+
+	assert.Equal(t, "ip:10.0.0.1", rules.clientKey(ctx, RateLimitKeyPrincipal, http.Header{}, peer))
+	assert.Equal(t, "principal:42", rules.clientKey(principal.With(ctx, "42"), RateLimitKeyPrincipal, http.Header{}, peer))
+
+	header = http.Header{}
+	header.Set(APIKeyHeader, "secret")
+	assert.Equal(t, "ip:10.0.0.1", rules.clientKey(ctx, RateLimitKeyAPIKey, header, peer),
+		"an API key not verified is not trusted")
+	key := rules.clientKey(principal.WithAPIKey(ctx, "secret"), RateLimitKeyAPIKey, header, peer)
+	assert.Contains(t, key, "api-key:")
+	assert.NotContains(t, key, "secret")
+}
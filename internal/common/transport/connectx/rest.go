@@ -0,0 +1,479 @@
+package connectx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const jsonContentType = "application/json"
+
+var errRESTBodyTooLarge = errors.New("request body too large")
+
+// restRoute is one google.api.http binding of a unary method.
+type restRoute struct {
+	httpMethod   string
+	template     *pathTemplate
+	procedure    string
+	method       protoreflect.MethodDescriptor
+	body         string
+	responseBody string
+}
+
+// restHandler transcodes RESTful requests, as declared by the google.api.http
+// annotations, into unary Connect JSON requests served by the wrapped handler.
+// Everything registered on the Connect handler (interceptors, options) applies.
+type restHandler struct {
+	next         http.Handler
+	maxBodyBytes int
+	routes       []*restRoute
+}
+
+// NewRESTHandler returns an http.Handler serving the google.api.http rules of the
+// given services. Calls are forwarded in-process to next, which must be the
+// Connect handler of those services (as returned by the generated NewXxxHandler).
+// Connect error codes are mapped back to HTTP statuses with HTTPStatusFromConnectCode.
+// Request bodies larger than maxBodyBytes are refused, unbounded when 0, as the
+// http max-request-bytes setting.
+func NewRESTHandler(
+	next http.Handler, maxBodyBytes int, services ...protoreflect.ServiceDescriptor,
+) (http.Handler, error) {
+	h := &restHandler{next: next, maxBodyBytes: maxBodyBytes}
+
+	for _, svc := range services {
+		methods := svc.Methods()
+		for i := range methods.Len() {
+			method := methods.Get(i)
+			if method.IsStreamingClient() || method.IsStreamingServer() {
+				continue
+			}
+
+			rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if !ok || rule == nil {
+				continue
+			}
+
+			procedure := "/" + string(svc.FullName()) + "/" + string(method.Name())
+			for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+				route, err := newRESTRoute(r, procedure, method)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", procedure, err)
+				}
+				h.routes = append(h.routes, route)
+			}
+		}
+	}
+
+	// Most specific templates first, so "/v1/users/by-email/{email}" wins over "/v1/users/{id=**}".
+	sort.SliceStable(h.routes, func(i, j int) bool {
+		return h.routes[i].template.literals() > h.routes[j].template.literals()
+	})
+
+	return h, nil
+}
+
+func newRESTRoute(rule *annotations.HttpRule, procedure string, method protoreflect.MethodDescriptor) (*restRoute, error) {
+	var httpMethod, path string
+
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		httpMethod, path = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		httpMethod, path = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		httpMethod, path = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		httpMethod, path = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		httpMethod, path = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		httpMethod, path = p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return nil, errors.New("http rule without pattern")
+	}
+
+	tpl, err := parsePathTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restRoute{
+		httpMethod:   httpMethod,
+		template:     tpl,
+		procedure:    procedure,
+		method:       method,
+		body:         rule.GetBody(),
+		responseBody: rule.GetResponseBody(),
+	}, nil
+}
+
+func (h *restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pathMatched := false
+	for _, route := range h.routes {
+		vars, ok := route.template.match(r.URL.EscapedPath())
+		if !ok {
+			continue
+		}
+		pathMatched = true
+
+		if route.httpMethod != r.Method {
+			continue
+		}
+
+		h.serveRoute(w, r, route, vars)
+
+		return
+	}
+
+	if pathMatched {
+		writeRESTError(w, http.StatusMethodNotAllowed, connect.CodeUnimplemented, "method not allowed")
+
+		return
+	}
+
+	writeRESTError(w, http.StatusNotFound, connect.CodeNotFound, "no route for "+r.URL.Path)
+}
+
+func (h *restHandler) serveRoute(w http.ResponseWriter, r *http.Request, route *restRoute, vars map[string]string) {
+	msg := dynamicpb.NewMessage(route.method.Input())
+	if err := route.bindRequest(r, msg, vars, h.maxBodyBytes); err != nil {
+		if errors.Is(err, errRESTBodyTooLarge) {
+			writeRESTError(w, http.StatusRequestEntityTooLarge, connect.CodeResourceExhausted, err.Error())
+
+			return
+		}
+		writeRESTError(w, http.StatusBadRequest, connect.CodeInvalidArgument, err.Error())
+
+		return
+	}
+
+	payload, err := protojson.Marshal(msg)
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, connect.CodeInternal, "failed to encode request")
+
+		return
+	}
+
+	inner := r.Clone(r.Context())
+	inner.Method = http.MethodPost
+	inner.URL.Path = route.procedure
+	inner.URL.RawPath = ""
+	inner.URL.RawQuery = ""
+	inner.RequestURI = ""
+	inner.Body = io.NopCloser(bytes.NewReader(payload))
+	inner.ContentLength = int64(len(payload))
+	inner.Header.Del("Content-Length")
+	inner.Header.Del("Content-Encoding")
+	inner.Header.Del("Accept-Encoding")
+	inner.Header.Set("Content-Type", jsonContentType)
+	inner.Header.Set("Connect-Protocol-Version", "1")
+
+	rec := newResponseRecorder()
+	h.next.ServeHTTP(rec, inner)
+
+	for k, values := range rec.header {
+		if k == "Content-Length" || k == "Content-Type" {
+			continue
+		}
+		w.Header()[k] = values
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+
+	if rec.status != http.StatusOK {
+		writeTranscodedError(w, rec)
+
+		return
+	}
+
+	body := rec.body.Bytes()
+	if route.responseBody != "" {
+		if body, err = selectResponseBody(body, route); err != nil {
+			writeRESTError(w, http.StatusInternalServerError, connect.CodeInternal, "failed to encode response")
+
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// bindRequest fills msg from the body, the path variables and the query string,
+// in that order of precedence for the body and then path variables.
+func (route *restRoute) bindRequest(
+	r *http.Request, msg protoreflect.Message, vars map[string]string, maxBodyBytes int,
+) error {
+	if err := route.bindBody(r, msg, maxBodyBytes); err != nil {
+		return err
+	}
+
+	for fieldPath, value := range vars {
+		if err := setFieldPath(msg, fieldPath, []string{value}); err != nil {
+			return fmt.Errorf("path parameter %q: %w", fieldPath, err)
+		}
+	}
+
+	if route.body == "*" {
+		return nil
+	}
+
+	for key, values := range r.URL.Query() {
+		if _, bound := vars[key]; bound || key == route.body {
+			continue
+		}
+
+		// Unknown query parameters are ignored, e.g. cache busters.
+		if err := setFieldPath(msg, key, values); err != nil && !errors.Is(err, errUnknownField) {
+			return fmt.Errorf("query parameter %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (route *restRoute) bindBody(r *http.Request, msg protoreflect.Message, maxBodyBytes int) error {
+	if route.body == "" || r.Body == nil {
+		return nil
+	}
+
+	var body io.Reader = r.Body
+	if maxBodyBytes > 0 {
+		body = io.LimitReader(r.Body, int64(maxBodyBytes)+1)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if maxBodyBytes > 0 && len(raw) > maxBodyBytes {
+		return errRESTBodyTooLarge
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	target := msg
+	if route.body != "*" {
+		fd := findField(msg.Descriptor(), route.body)
+		if fd == nil || fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("body field %q must be a message field", route.body)
+		}
+		target = msg.Mutable(fd).Message()
+	}
+
+	if err := protojson.Unmarshal(raw, target.Interface()); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	return nil
+}
+
+func selectResponseBody(body []byte, route *restRoute) ([]byte, error) {
+	out := dynamicpb.NewMessage(route.method.Output())
+	if err := protojson.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	fd := findField(out.Descriptor(), route.responseBody)
+	if fd == nil {
+		return nil, fmt.Errorf("unknown response_body field %q", route.responseBody)
+	}
+
+	if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+		return nil, fmt.Errorf("response_body field %q must be a message field", route.responseBody)
+	}
+
+	b, err := protojson.Marshal(out.Get(fd).Message().Interface())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	return b, nil
+}
+
+var errUnknownField = errors.New("unknown field")
+
+// findField looks a field up by its proto name, then by its JSON name.
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+
+	return fields.ByJSONName(name)
+}
+
+// setFieldPath sets the scalar field designated by a dotted path such as "user.email".
+func setFieldPath(msg protoreflect.Message, fieldPath string, values []string) error {
+	names := strings.Split(fieldPath, ".")
+	for _, name := range names[:len(names)-1] {
+		fd := findField(msg.Descriptor(), name)
+		if fd == nil {
+			return fmt.Errorf("%w %q", errUnknownField, name)
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("field %q is not a message", name)
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+
+	fd := findField(msg.Descriptor(), names[len(names)-1])
+	if fd == nil {
+		return fmt.Errorf("%w %q", errUnknownField, names[len(names)-1])
+	}
+	if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return fmt.Errorf("field %q cannot be set from a string", fd.Name())
+	}
+
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, v := range values {
+			val, err := parseScalar(fd, v)
+			if err != nil {
+				return err
+			}
+			list.Append(val)
+		}
+
+		return nil
+	}
+
+	val, err := parseScalar(fd, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, val)
+
+	return nil
+}
+
+//nolint:gocyclo // one case per protobuf scalar kind
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	var (
+		v   protoreflect.Value
+		err error
+	)
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.BytesKind:
+		var b []byte
+		b, err = base64.URLEncoding.DecodeString(s)
+		if err != nil {
+			b, err = base64.StdEncoding.DecodeString(s)
+		}
+		v = protoreflect.ValueOfBytes(b)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(s, 10, 32)
+		v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+	default:
+		return v, fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+
+	if err != nil {
+		return v, fmt.Errorf("invalid %s value %q", fd.Kind(), s)
+	}
+
+	return v, nil
+}
+
+// restError is the JSON error body of REST responses, the same shape as Connect errors.
+type restError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// writeTranscodedError rewrites a Connect error with the HTTP status of its code.
+func writeTranscodedError(w http.ResponseWriter, rec *responseRecorder) {
+	var body restError
+	if err := json.Unmarshal(rec.body.Bytes(), &body); err != nil || body.Code == "" {
+		writeRESTError(w, rec.status, connect.CodeUnknown, http.StatusText(rec.status))
+
+		return
+	}
+
+	var code connect.Code
+	if err := code.UnmarshalText([]byte(body.Code)); err != nil {
+		code = connect.CodeUnknown
+	}
+
+	w.WriteHeader(HTTPStatusFromConnectCode(code))
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeRESTError(w http.ResponseWriter, status int, code connect.Code, msg string) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(restError{Code: code.String(), Message: msg})
+}
+
+// responseRecorder buffers the response of the in-process Connect call.
+type responseRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.body.Write(b)
+	if err != nil {
+		return n, fmt.Errorf("failed to buffer response: %w", err)
+	}
+
+	return n, nil
+}
+
+func (r *responseRecorder) WriteHeader(status int) { r.status = status }
@@ -0,0 +1,186 @@
+package connectx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// pathTemplate is a parsed google.api.http path template, e.g. "/v1/users/{id}"
+// or "/v1/{name=shelves/*}:publish". Supported segments are literals, "*", "**"
+// (last position only) and variables, optionally with a sub-pattern.
+type pathTemplate struct {
+	raw      string
+	segments []templateSegment
+	vars     []templateVar
+	verb     string
+}
+
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	segmentSingle              // "*": exactly one segment
+	segmentMulti               // "**": zero or more trailing segments
+)
+
+type templateSegment struct {
+	kind    segmentKind
+	literal string
+}
+
+// templateVar binds the segments [start, end) to a field path.
+// end is -1 when the variable ends with "**".
+type templateVar struct {
+	fieldPath string
+	start     int
+	end       int
+}
+
+var errInvalidTemplate = errors.New("invalid path template")
+
+func parsePathTemplate(raw string) (*pathTemplate, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return nil, fmt.Errorf("%w %q: must start with /", errInvalidTemplate, raw)
+	}
+
+	tpl := &pathTemplate{raw: raw}
+	rest := raw[1:]
+
+	// The verb is a ":name" suffix outside of any variable.
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i:], "}") {
+		tpl.verb = rest[i+1:]
+		rest = rest[:i]
+	}
+
+	for rest != "" {
+		if strings.HasPrefix(rest, "{") {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return nil, fmt.Errorf("%w %q: unterminated variable", errInvalidTemplate, raw)
+			}
+
+			if err := tpl.addVariable(rest[1:end]); err != nil {
+				return nil, fmt.Errorf("%w %q: %w", errInvalidTemplate, raw, err)
+			}
+
+			rest = strings.TrimPrefix(rest[end+1:], "/")
+
+			continue
+		}
+
+		seg, tail, _ := strings.Cut(rest, "/")
+		if err := tpl.addSegment(seg); err != nil {
+			return nil, fmt.Errorf("%w %q: %w", errInvalidTemplate, raw, err)
+		}
+		rest = tail
+	}
+
+	for i, seg := range tpl.segments {
+		if seg.kind == segmentMulti && i != len(tpl.segments)-1 {
+			return nil, fmt.Errorf("%w %q: ** must be the last segment", errInvalidTemplate, raw)
+		}
+	}
+
+	return tpl, nil
+}
+
+func (t *pathTemplate) addSegment(seg string) error {
+	switch {
+	case seg == "":
+		return errors.New("empty segment")
+	case seg == "*":
+		t.segments = append(t.segments, templateSegment{kind: segmentSingle})
+	case seg == "**":
+		t.segments = append(t.segments, templateSegment{kind: segmentMulti})
+	case strings.ContainsAny(seg, "{}=*"):
+		return fmt.Errorf("invalid literal %q", seg)
+	default:
+		t.segments = append(t.segments, templateSegment{kind: segmentLiteral, literal: seg})
+	}
+
+	return nil
+}
+
+func (t *pathTemplate) addVariable(body string) error {
+	fieldPath, pattern, hasPattern := strings.Cut(body, "=")
+	if fieldPath == "" {
+		return errors.New("empty variable name")
+	}
+	if !hasPattern {
+		pattern = "*"
+	}
+
+	v := templateVar{fieldPath: fieldPath, start: len(t.segments)}
+	for seg := range strings.SplitSeq(pattern, "/") {
+		if err := t.addSegment(seg); err != nil {
+			return err
+		}
+	}
+
+	v.end = len(t.segments)
+	if t.segments[len(t.segments)-1].kind == segmentMulti {
+		v.end = -1
+	}
+	t.vars = append(t.vars, v)
+
+	return nil
+}
+
+// literals returns the number of literal segments, used to prefer the most
+// specific template when several match.
+func (t *pathTemplate) literals() int {
+	n := 0
+	for _, seg := range t.segments {
+		if seg.kind == segmentLiteral {
+			n++
+		}
+	}
+
+	return n
+}
+
+// match matches an escaped URL path and returns the unescaped variable values.
+func (t *pathTemplate) match(path string) (map[string]string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if t.verb != "" {
+		var ok bool
+		if path, ok = strings.CutSuffix(path, ":"+t.verb); !ok {
+			return nil, false
+		}
+	}
+
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	multi := len(t.segments) > 0 && t.segments[len(t.segments)-1].kind == segmentMulti
+	if (!multi && len(parts) != len(t.segments)) || (multi && len(parts) < len(t.segments)-1) {
+		return nil, false
+	}
+
+	for i, seg := range t.segments {
+		if seg.kind == segmentLiteral && parts[i] != seg.literal {
+			return nil, false
+		}
+	}
+
+	values := make(map[string]string, len(t.vars))
+	for _, v := range t.vars {
+		end := v.end
+		if end < 0 {
+			end = len(parts)
+		}
+
+		raw := strings.Join(parts[v.start:end], "/")
+		value, err := url.PathUnescape(raw)
+		if err != nil {
+			return nil, false
+		}
+		values[v.fieldPath] = value
+	}
+
+	return values, true
+}
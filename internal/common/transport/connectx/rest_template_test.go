@@ -0,0 +1,66 @@
+package connectx
+
+import (
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathTemplate_Match(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     map[string]string
+		ok       bool
+	}{
+		{"/v1/users", "/v1/users", map[string]string{}, true},
+		{"/v1/users/{id}", "/v1/users/42", map[string]string{"id": "42"}, true},
+		{"/v1/users/{id}", "/v1/users/42/extra", nil, false},
+		{"/v1/users/by-email/{email}", "/v1/users/by-email/a%40b.c", map[string]string{"email": "a@b.c"}, true},
+		{"/v1/{name=shelves/*}", "/v1/shelves/1", map[string]string{"name": "shelves/1"}, true},
+		{"/v1/{name=files/**}", "/v1/files/a/b/c", map[string]string{"name": "files/a/b/c"}, true},
+		{"/v1/{name=shelves/*}:publish", "/v1/shelves/1:publish", map[string]string{"name": "shelves/1"}, true},
+		{"/v1/{name=shelves/*}:publish", "/v1/shelves/1", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.path, func(t *testing.T) {
+			tpl, err := parsePathTemplate(tt.template)
+			require.NoError(t, err)
+
+			got, ok := tpl.match(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplate_Invalid(t *testing.T) {
+	for _, raw := range []string{"v1/users", "/v1/{id", "/v1/**/users", "/v1//users"} {
+		_, err := parsePathTemplate(raw)
+		assert.ErrorIs(t, err, errInvalidTemplate, raw)
+	}
+}
+
+func TestHTTPStatusFromConnectCode_RoundTrip(t *testing.T) {
+	for _, code := range []connect.Code{
+		connect.CodeInvalidArgument,
+		connect.CodeUnauthenticated,
+		connect.CodePermissionDenied,
+		connect.CodeNotFound,
+		connect.CodeAlreadyExists,
+		connect.CodeFailedPrecondition,
+		connect.CodeResourceExhausted,
+		connect.CodeUnimplemented,
+		connect.CodeUnavailable,
+		connect.CodeDeadlineExceeded,
+		connect.CodeCanceled,
+		connect.CodeInternal,
+	} {
+		assert.Equal(t, code, ConnectCodeFromHTTPStatus(HTTPStatusFromConnectCode(code)), code.String())
+	}
+}
@@ -0,0 +1,284 @@
+package connectx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const restTestMaxBodyBytes = 64
+
+// restTestService describes rest.v1.ItemService, whose methods take and return
+// a rest.v1.Item:
+//
+//	message Item {
+//	  string id = 1; int64 limit = 2; bool active = 3; repeated string tags = 4;
+//	  Color color = 5; Detail detail = 6;
+//	}
+//	message Detail { string name = 1; int64 count = 2; }
+//	enum Color { COLOR_UNSPECIFIED = 0; BLUE = 1; }
+func restTestService(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type,
+		label descriptorpb.FieldDescriptorProto_Label, typeName string,
+	) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+
+		return f
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	method := func(name string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+		opts := &descriptorpb.MethodOptions{}
+		proto.SetExtension(opts, annotations.E_Http, rule)
+
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".rest.v1.Item"),
+			OutputType: proto.String(".rest.v1.Item"),
+			Options:    opts,
+		}
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("rest/v1/item.proto"),
+		Package: proto.String("rest.v1"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Color"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("BLUE"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("limit", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+					field("active", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL, optional, ""),
+					field("tags", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING,
+						descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ""),
+					field("color", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional, ".rest.v1.Color"),
+					field("detail", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".rest.v1.Detail"),
+				},
+			},
+			{
+				Name: proto.String("Detail"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ItemService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetItem", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Get{Get: "/v1/items/{id}"},
+				}),
+				method("CreateItem", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Post{Post: "/v1/items"},
+					Body:    "*",
+				}),
+				method("UpdateDetail", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Patch{Patch: "/v1/items/{id}/detail"},
+					Body:    "detail",
+				}),
+				method("Fail", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Get{Get: "/v1/fail/{id}"},
+				}),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return file.Services().Get(0)
+}
+
+// newRESTTestHandler returns the REST handler of rest.v1.ItemService, over a
+// Connect stand-in echoing the request, and failing Fail with the Connect code
+// of the id.
+func newRESTTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if r.URL.Path == "/rest.v1.ItemService/Fail" {
+			var req struct{ ID string }
+			_ = json.Unmarshal(body, &req)
+			w.Header().Set("Content-Type", jsonContentType)
+			// Connect statuses differ from the REST ones, e.g. 400 for failed_precondition.
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(restError{Code: req.ID, Message: "failed"})
+
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("X-Procedure", r.URL.Path)
+		_, _ = w.Write(body)
+	})
+
+	h, err := NewRESTHandler(next, restTestMaxBodyBytes, restTestService(t))
+	require.NoError(t, err)
+
+	return h
+}
+
+func serveREST(t *testing.T, h http.Handler, method, target, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, reader))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), rec.Body.String())
+
+	return rec, got
+}
+
+func TestRESTHandler_Bind(t *testing.T) {
+	h := newRESTTestHandler(t)
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		procedure string
+		want      map[string]any
+	}{
+		{
+			name:      "path variable",
+			method:    http.MethodGet,
+			target:    "/v1/items/a%2Fb",
+			procedure: "/rest.v1.ItemService/GetItem",
+			want:      map[string]any{"id": "a/b"},
+		},
+		{
+			name:      "typed and repeated query parameters",
+			method:    http.MethodGet,
+			target:    "/v1/items/42?limit=10&active=true&tags=a&tags=b&color=BLUE&cache=1",
+			procedure: "/rest.v1.ItemService/GetItem",
+			want: map[string]any{
+				"id": "42", "limit": "10", "active": true, "tags": []any{"a", "b"}, "color": "BLUE",
+			},
+		},
+		{
+			name:      "path variable over query parameter",
+			method:    http.MethodGet,
+			target:    "/v1/items/42?id=7",
+			procedure: "/rest.v1.ItemService/GetItem",
+			want:      map[string]any{"id": "42"},
+		},
+		{
+			name:      "whole message body ignores the query",
+			method:    http.MethodPost,
+			target:    "/v1/items?limit=5",
+			body:      `{"id":"1","detail":{"name":"n"}}`,
+			procedure: "/rest.v1.ItemService/CreateItem",
+			want:      map[string]any{"id": "1", "detail": map[string]any{"name": "n"}},
+		},
+		{
+			name:      "field body with path and query",
+			method:    http.MethodPatch,
+			target:    "/v1/items/42/detail?limit=5",
+			body:      `{"name":"n","count":3}`,
+			procedure: "/rest.v1.ItemService/UpdateDetail",
+			want: map[string]any{
+				"id": "42", "limit": "5", "detail": map[string]any{"name": "n", "count": "3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, got := serveREST(t, h, tt.method, tt.target, tt.body)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.procedure, rec.Header().Get("X-Procedure"))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRESTHandler_Errors(t *testing.T) {
+	h := newRESTTestHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{"invalid typed query parameter", http.MethodGet, "/v1/items/42?limit=ten", "", 400, "invalid_argument"},
+		{"invalid bool query parameter", http.MethodGet, "/v1/items/42?active=maybe", "", 400, "invalid_argument"},
+		{"invalid enum query parameter", http.MethodGet, "/v1/items/42?color=RED", "", 400, "invalid_argument"},
+		{"invalid JSON body", http.MethodPost, "/v1/items", `{"id":`, 400, "invalid_argument"},
+		{
+			"oversize body", http.MethodPost, "/v1/items",
+			`{"id":"` + strings.Repeat("x", restTestMaxBodyBytes) + `"}`, 413, "resource_exhausted",
+		},
+		{"unknown path", http.MethodGet, "/v1/other", "", 404, "not_found"},
+		{"method not allowed", http.MethodDelete, "/v1/items/42", "", 405, "unimplemented"},
+		{"transcoded failed_precondition", http.MethodGet, "/v1/fail/failed_precondition", "", 422, "failed_precondition"},
+		{"transcoded not_found", http.MethodGet, "/v1/fail/not_found", "", 404, "not_found"},
+		{"transcoded resource_exhausted", http.MethodGet, "/v1/fail/resource_exhausted", "", 429, "resource_exhausted"},
+		{"transcoded unknown code", http.MethodGet, "/v1/fail/bogus", "", 500, "bogus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, got := serveREST(t, h, tt.method, tt.target, tt.body)
+
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			assert.Equal(t, jsonContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.code, got["code"])
+		})
+	}
+}
+
+func TestRESTHandler_UnboundedBody(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	})
+	h, err := NewRESTHandler(next, 0, restTestService(t))
+	require.NoError(t, err)
+
+	id := strings.Repeat("x", 2*restTestMaxBodyBytes)
+	rec, got := serveREST(t, h, http.MethodPost, "/v1/items", `{"id":"`+id+`"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, id, got["id"])
+}
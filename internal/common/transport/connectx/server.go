@@ -0,0 +1,109 @@
+package connectx
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+// Bounds of the HTTP/2 SETTINGS_MAX_FRAME_SIZE, see RFC 9113.
+const (
+	minHTTP2FrameSize = 1 << 14
+	maxHTTP2FrameSize = 1<<24 - 1
+)
+
+// NewHTTPServer returns the HTTP server of handler on addr, tuned by cfg. It
+// serves HTTP/1.1 and HTTP/2 over TLS with tlsConfig, or in cleartext (h2c)
+// for the gRPC clients when tlsConfig is nil. Its Shutdown sends a GOAWAY to the HTTP/2 connections, for the clients to
+// stop opening streams on them.
+// It fails when cfg has values out of their bounds or inconsistent with each other.
+func NewHTTPServer(addr string, handler http.Handler, cfg config.HTTPConfig, tlsConfig *tls.Config) (*http.Server, error) {
+	if err := validateHTTPConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	h2server := &http2.Server{
+		MaxConcurrentStreams:         cfg.HTTP2.MaxConcurrentStreams,
+		MaxReadFrameSize:             cfg.HTTP2.MaxReadFrameSize,
+		MaxUploadBufferPerConnection: cfg.HTTP2.MaxUploadBufferPerConnection,
+		MaxUploadBufferPerStream:     cfg.HTTP2.MaxUploadBufferPerStream,
+		IdleTimeout:                  cfg.IdleTimeout,
+		WriteByteTimeout:             cfg.WriteTimeout,
+	}
+
+	if tlsConfig == nil {
+		handler = h2c.NewHandler(handler, h2server)
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if err := http2.ConfigureServer(server, h2server); err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP/2: %w", err)
+	}
+
+	return server, nil
+}
+
+// validateHTTPConfig returns the errors of the values of cfg out of their
+// bounds or inconsistent with each other.
+func validateHTTPConfig(cfg config.HTTPConfig) error {
+	var errs []error
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read-header-timeout", cfg.ReadHeaderTimeout},
+		{"read-timeout", cfg.ReadTimeout},
+		{"write-timeout", cfg.WriteTimeout},
+		{"idle-timeout", cfg.IdleTimeout},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("http %s must not be negative, got %s", timeout.name, timeout.value))
+		}
+	}
+	if cfg.ReadTimeout > 0 && cfg.ReadHeaderTimeout > cfg.ReadTimeout {
+		errs = append(errs, fmt.Errorf("http read-header-timeout %s exceeds read-timeout %s",
+			cfg.ReadHeaderTimeout, cfg.ReadTimeout))
+	}
+
+	for _, size := range []struct {
+		name  string
+		value int
+	}{
+		{"max-header-bytes", cfg.MaxHeaderBytes},
+		{"max-request-bytes", cfg.MaxRequestBytes},
+		{"max-response-bytes", cfg.MaxResponseBytes},
+		{"http2 max-upload-buffer-per-connection", int(cfg.HTTP2.MaxUploadBufferPerConnection)},
+		{"http2 max-upload-buffer-per-stream", int(cfg.HTTP2.MaxUploadBufferPerStream)},
+	} {
+		if size.value < 0 {
+			errs = append(errs, fmt.Errorf("http %s must not be negative, got %d", size.name, size.value))
+		}
+	}
+
+	h2 := cfg.HTTP2
+	if h2.MaxReadFrameSize != 0 && (h2.MaxReadFrameSize < minHTTP2FrameSize || h2.MaxReadFrameSize > maxHTTP2FrameSize) {
+		errs = append(errs, fmt.Errorf("http2 max-read-frame-size must be between %d and %d, got %d",
+			minHTTP2FrameSize, maxHTTP2FrameSize, h2.MaxReadFrameSize))
+	}
+	if h2.MaxUploadBufferPerConnection > 0 && h2.MaxUploadBufferPerStream > h2.MaxUploadBufferPerConnection {
+		errs = append(errs, fmt.Errorf("http2 max-upload-buffer-per-stream %d exceeds max-upload-buffer-per-connection %d",
+			h2.MaxUploadBufferPerStream, h2.MaxUploadBufferPerConnection))
+	}
+
+	return errors.Join(errs...)
+}
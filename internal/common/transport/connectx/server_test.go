@@ -0,0 +1,54 @@
+package connectx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+)
+
+func TestNewHTTPServer(t *testing.T) {
+	srv, err := NewHTTPServer(":4224", http.NotFoundHandler(), config.HTTPConfig{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      35 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    64 << 10,
+		HTTP2:             config.HTTP2Config{MaxConcurrentStreams: 250, MaxReadFrameSize: 1 << 20},
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":4224", srv.Addr)
+	assert.Equal(t, 5*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 35*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 64<<10, srv.MaxHeaderBytes)
+}
+
+func TestNewHTTPServer_Invalid(t *testing.T) {
+	_, err := NewHTTPServer(":4224", http.NotFoundHandler(), config.HTTPConfig{
+		ReadHeaderTimeout: time.Minute,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       -time.Second,
+		MaxRequestBytes:   -1,
+		HTTP2: config.HTTP2Config{
+			MaxReadFrameSize:             1024,
+			MaxUploadBufferPerConnection: 1 << 20,
+			MaxUploadBufferPerStream:     2 << 20,
+		},
+	}, nil)
+	require.Error(t, err)
+
+	for _, msg := range []string{
+		"http idle-timeout must not be negative",
+		"http read-header-timeout 1m0s exceeds read-timeout 30s",
+		"http max-request-bytes must not be negative",
+		"http2 max-read-frame-size must be between 16384 and 16777215, got 1024",
+		"http2 max-upload-buffer-per-stream 2097152 exceeds max-upload-buffer-per-connection 1048576",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
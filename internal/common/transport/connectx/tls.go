@@ -0,0 +1,236 @@
+package connectx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/logging"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	config.ClientAuthNone:     tls.NoClientCert,
+	config.ClientAuthOptional: tls.VerifyClientCertIfGiven,
+	config.ClientAuthRequire:  tls.RequireAndVerifyClientCert,
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// CertReloader holds the certificate of the server and the CAs of the client
+// certificates, loaded from the files of a TLSConfig, and reloads them once
+// the files change, for the certificates to rotate without restart.
+type CertReloader struct {
+	cfg        config.TLSConfig
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]fileStamp
+}
+
+// NewCertReloader returns the reloader of the files of cfg, loaded once. It
+// fails when cfg is invalid or the files cannot be loaded.
+func NewCertReloader(cfg config.TLSConfig) (*CertReloader, error) {
+	if err := validateTLSConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	r := &CertReloader{
+		cfg:        cfg,
+		minVersion: tlsVersions[cfg.MinVersion],
+		clientAuth: clientAuthTypes[cfg.ClientAuth],
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// validateTLSConfig returns the errors of the values of cfg.
+func validateTLSConfig(cfg config.TLSConfig) error {
+	var errs []error
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		errs = append(errs, errors.New("tls cert-file and key-file are required"))
+	}
+	if _, ok := tlsVersions[cfg.MinVersion]; !ok {
+		errs = append(errs, fmt.Errorf("tls min-version must be 1.2 or 1.3, got %q", cfg.MinVersion))
+	}
+	if _, ok := clientAuthTypes[cfg.ClientAuth]; !ok {
+		errs = append(errs, fmt.Errorf("tls client-auth must be none, optional or require, got %q", cfg.ClientAuth))
+	}
+	if cfg.ClientAuth != config.ClientAuthNone && cfg.ClientCAFile == "" {
+		errs = append(errs, fmt.Errorf("tls client-auth %q requires a client-ca-file", cfg.ClientAuth))
+	}
+	if cfg.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("tls reload-interval must not be negative, got %s", cfg.ReloadInterval))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reload loads the files again if any of them changed since the last load,
+// reporting whether it did. On error, the previous certificates are kept, and
+// the files are loaded again by the next call: a certificate and its key
+// rotated one after the other are loaded once both are written.
+func (r *CertReloader) Reload() (bool, error) {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+
+	stamps := make(map[string]fileStamp, len(files))
+	changed := false
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("failed to stat TLS file: %w", err)
+		}
+		stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		r.mu.RLock()
+		changed = changed || r.stamps[file] != stamps[file]
+		r.mu.RUnlock()
+	}
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read TLS client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("no certificate found in TLS client CA file %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.stamps = &cert, clientCAs, stamps
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch reloads the files every reload interval of the config, until ctx is
+// done. It returns at once when the interval is 0.
+func (r *CertReloader) Watch(ctx context.Context, logger logging.Logger) {
+	if r.cfg.ReloadInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				logger.Error("failed to reload TLS certificates, keeping the previous ones",
+					logging.String("error", err.Error()))
+
+				continue
+			}
+			if reloaded {
+				logger.Info("reloaded TLS certificates", logging.String("cert_file", r.cfg.CertFile))
+			}
+		}
+	}
+}
+
+// TLSConfig returns the TLS config of the server, whose handshakes use the
+// certificates loaded last.
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     r.minVersion,
+		GetCertificate: r.getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   r.minVersion,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+func (r *CertReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+type clientCertKey struct{}
+
+// ClientCertificate returns the verified client certificate of the call of
+// ctx, set by ClientCertMiddleware, or nil.
+func ClientCertificate(ctx context.Context) *x509.Certificate {
+	cert, _ := ctx.Value(clientCertKey{}).(*x509.Certificate)
+
+	return cert
+}
+
+// ClientCertMiddleware puts the verified client certificate of the requests
+// in their context, see ClientCertificate, and its identity as their
+// principal: its first URI SAN (a SPIFFE id for instance), or else its subject
+// common name. Certificates which are not verified against the client CAs are
+// ignored.
+func ClientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		ctx := context.WithValue(r.Context(), clientCertKey{}, cert)
+		if id := certIdentity(cert); id != "" {
+			ctx = principal.With(ctx, id)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func certIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,243 @@
+package connectx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/config"
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/principal"
+)
+
+// testCert is a certificate and its key, signed by its parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	if keyFile != "" {
+		require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+type testPKI struct {
+	ca     *testCert
+	cfg    config.TLSConfig
+	client *testCert
+}
+
+func newTestPKI(t *testing.T, clientAuth string) *testPKI {
+	t.Helper()
+
+	dir := t.TempDir()
+	ca := newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	pki := &testPKI{
+		ca: ca,
+		cfg: config.TLSConfig{
+			Enabled:      true,
+			CertFile:     filepath.Join(dir, "server.crt"),
+			KeyFile:      filepath.Join(dir, "server.key"),
+			ClientAuth:   clientAuth,
+			ClientCAFile: filepath.Join(dir, "ca.crt"),
+			MinVersion:   "1.2",
+		},
+		client: newTestCert(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "billing"},
+			URIs:        []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/billing"}},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, ca),
+	}
+	ca.write(t, pki.cfg.ClientCAFile, "")
+	pki.writeServerCert(t, "server-1")
+
+	return pki
+}
+
+func (p *testPKI) writeServerCert(t *testing.T, commonName string) {
+	t.Helper()
+
+	newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, p.ca).write(t, p.cfg.CertFile, p.cfg.KeyFile)
+}
+
+// serve starts a TLS server answering the principal of the requests.
+func (p *testPKI) serve(t *testing.T, reloader *CertReloader) string {
+	t.Helper()
+
+	srv, err := NewHTTPServer("", ClientCertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, principal.Get(r.Context()))
+	})), config.HTTPConfig{}, reloader.TLSConfig())
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.ServeTLS(lis, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return "https://" + lis.Addr().String()
+}
+
+func (p *testPKI) get(url string, certs ...tls.Certificate) (*http.Response, string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(p.ca.cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+
+	return resp, string(body), err
+}
+
+func TestCertReloader_MutualTLS(t *testing.T) {
+	pki := newTestPKI(t, config.ClientAuthRequire)
+	reloader, err := NewCertReloader(pki.cfg)
+	require.NoError(t, err)
+	url := pki.serve(t, reloader)
+
+	resp, body, err := pki.get(url, pki.client.tlsCertificate())
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "spiffe://example.com/billing", body)
+
+	_, _, err = pki.get(url)
+	require.Error(t, err, "a client without certificate is rejected")
+
+	other := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "intruder"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, nil)
+	_, _, err = pki.get(url, other.tlsCertificate())
+	require.Error(t, err, "a client certificate of another CA is rejected")
+}
+
+func TestCertReloader_OptionalClientCert(t *testing.T) {
+	pki := newTestPKI(t, config.ClientAuthOptional)
+	reloader, err := NewCertReloader(pki.cfg)
+	require.NoError(t, err)
+	url := pki.serve(t, reloader)
+
+	_, body, err := pki.get(url)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	pki := newTestPKI(t, config.ClientAuthNone)
+	reloader, err := NewCertReloader(pki.cfg)
+	require.NoError(t, err)
+	url := pki.serve(t, reloader)
+
+	resp, _, err := pki.get(url)
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files are not reloaded")
+
+	// A certificate being written is not loaded, the previous one is kept.
+	pki.writeServerCert(t, "server-2")
+	certPEM, err := os.ReadFile(pki.cfg.CertFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(pki.cfg.CertFile, []byte("garbage"), 0o600))
+	_, err = reloader.Reload()
+	require.Error(t, err)
+	resp, _, err = pki.get(url)
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	require.NoError(t, os.WriteFile(pki.cfg.CertFile, certPEM, 0o600))
+	reloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	resp, _, err = pki.get(url)
+	require.NoError(t, err)
+	assert.Equal(t, "server-2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestNewCertReloader_Invalid(t *testing.T) {
+	_, err := NewCertReloader(config.TLSConfig{ClientAuth: config.ClientAuthRequire, MinVersion: "1.1"})
+	require.Error(t, err)
+	for _, msg := range []string{
+		"tls cert-file and key-file are required",
+		`tls min-version must be 1.2 or 1.3, got "1.1"`,
+		`tls client-auth "require" requires a client-ca-file`,
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	_, err = NewCertReloader(config.TLSConfig{
+		CertFile:   "missing.crt",
+		KeyFile:    "missing.key",
+		ClientAuth: config.ClientAuthNone,
+		MinVersion: "1.3",
+	})
+	require.ErrorContains(t, err, "failed to stat TLS file")
+}
@@ -0,0 +1,122 @@
+package connectx
+
+import (
+	"context"
+	"strings"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the calls.
+const tracerName = "github.com/pivaldi/go-cleanstack/internal/common/transport/connectx"
+
+// serverFaults are the codes of the failed calls whose span is an error, the
+// other ones being caused by the client.
+var serverFaults = map[connect.Code]bool{
+	connect.CodeUnknown:          true,
+	connect.CodeDeadlineExceeded: true,
+	connect.CodeUnimplemented:    true,
+	connect.CodeInternal:         true,
+	connect.CodeUnavailable:      true,
+	connect.CodeDataLoss:         true,
+}
+
+type tracingInterceptor struct {
+	tracer trace.Tracer
+}
+
+// NewTracingInterceptor returns an interceptor tracing the calls with the
+// global tracer provider and propagator. On the server side, it continues the
+// trace of the W3C traceparent header of the request, or starts a new one, in
+// a span named after the procedure. On the client side, it sends the trace of
+// the context in the traceparent header.
+func NewTracingInterceptor() connect.Interceptor {
+	return &tracingInterceptor{tracer: otel.Tracer(tracerName)}
+}
+
+func (in *tracingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := req.Spec()
+		if spec.IsClient {
+			ctx, span := in.start(ctx, spec, req.Peer(), trace.SpanKindClient)
+			defer span.End()
+
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header()))
+			res, err := next(ctx, req)
+			endSpan(span, err, false)
+
+			return res, err
+		}
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header()))
+		ctx, span := in.start(ctx, spec, req.Peer(), trace.SpanKindServer)
+		defer span.End()
+
+		res, err := next(ctx, req)
+		endSpan(span, err, true)
+
+		return res, err
+	}
+}
+
+func (in *tracingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(conn.RequestHeader()))
+
+		return conn
+	}
+}
+
+func (in *tracingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(conn.RequestHeader()))
+		ctx, span := in.start(ctx, conn.Spec(), conn.Peer(), trace.SpanKindServer)
+		defer span.End()
+
+		err := next(ctx, conn)
+		endSpan(span, err, true)
+
+		return err
+	}
+}
+
+func (in *tracingInterceptor) start(
+	ctx context.Context,
+	spec connect.Spec,
+	peer connect.Peer,
+	kind trace.SpanKind,
+) (context.Context, trace.Span) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(spec.Procedure, "/"), "/")
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "connect_rpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+		attribute.String("rpc.protocol", peer.Protocol),
+	}
+	if peer.Addr != "" {
+		attrs = append(attrs, attribute.String("network.peer.address", peer.Addr))
+	}
+
+	return in.tracer.Start(ctx, strings.TrimPrefix(spec.Procedure, "/"),
+		trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of the call of span. On the server side, only
+// the failures of the server mark the span as an error.
+func endSpan(span trace.Span, err error, server bool) {
+	if err == nil {
+		return
+	}
+
+	code := connect.CodeOf(ToConnectError(err))
+	span.SetAttributes(attribute.String("rpc.connect_rpc.error_code", code.String()))
+	if !server || serverFaults[code] {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,125 @@
+package connectx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTestTracingInterceptor(t *testing.T) (*tracingInterceptor, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return &tracingInterceptor{tracer: provider.Tracer(tracerName)}, recorder
+}
+
+func TestTracingInterceptor_Server(t *testing.T) {
+	interceptor, recorder := newTestTracingInterceptor(t)
+
+	var handled trace.SpanContext
+	echo := func(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (
+		*connect.Response[wrapperspb.StringValue], error,
+	) {
+		handled = trace.SpanContextFromContext(ctx)
+		switch req.Msg.GetValue() {
+		case "missing":
+			return nil, apperr.NotFound("test.not_found", "not found")
+		case "broken":
+			return nil, assert.AnError
+		}
+
+		return connect.NewResponse(req.Msg), nil
+	}
+	procedure := "/test.v1.TestService/Echo"
+	srv := httptest.NewServer(connect.NewUnaryHandler(procedure, echo, connect.WithInterceptors(interceptor)))
+	t.Cleanup(srv.Close)
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](http.DefaultClient, srv.URL+procedure)
+
+	t.Run("continues the trace of traceparent", func(t *testing.T) {
+		req := connect.NewRequest(wrapperspb.String("hello"))
+		req.Header().Set("Traceparent", traceparent)
+		_, err := client.CallUnary(context.Background(), req)
+		require.NoError(t, err)
+
+		spans := recorder.Ended()
+		require.NotEmpty(t, spans)
+		span := spans[len(spans)-1]
+		assert.Equal(t, "test.v1.TestService/Echo", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.Equal(t, span.SpanContext(), handled)
+		assert.Contains(t, span.Attributes(), attribute.String("rpc.service", "test.v1.TestService"))
+		assert.Contains(t, span.Attributes(), attribute.String("rpc.method", "Echo"))
+	})
+
+	t.Run("starts a trace without traceparent", func(t *testing.T) {
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		require.NoError(t, err)
+
+		span := recorder.Ended()[len(recorder.Ended())-1]
+		assert.True(t, span.SpanContext().IsValid())
+		assert.False(t, span.Parent().IsValid())
+	})
+
+	t.Run("client errors are not span errors", func(t *testing.T) {
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("missing")))
+		require.Error(t, err)
+
+		span := recorder.Ended()[len(recorder.Ended())-1]
+		assert.Equal(t, codes.Unset, span.Status().Code)
+		assert.Contains(t, span.Attributes(), attribute.String("rpc.connect_rpc.error_code", "not_found"))
+	})
+
+	t.Run("server errors are span errors", func(t *testing.T) {
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("broken")))
+		require.Error(t, err)
+
+		span := recorder.Ended()[len(recorder.Ended())-1]
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Contains(t, span.Attributes(), attribute.String("rpc.connect_rpc.error_code", "internal"))
+	})
+}
+
+func TestTracingInterceptor_Client(t *testing.T) {
+	interceptor, recorder := newTestTracingInterceptor(t)
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Traceparent")
+		w.Header().Set("Content-Type", "application/proto")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+		http.DefaultClient, srv.URL+"/test.v1.TestService/Echo", connect.WithInterceptors(interceptor))
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	sc := spans[0].SpanContext()
+	assert.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", received)
+}
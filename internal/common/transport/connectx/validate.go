@@ -0,0 +1,134 @@
+package connectx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+// ErrCodeInvalidRequest is returned when a request breaks the buf.validate
+// rules declared in its proto definition.
+var ErrCodeInvalidRequest = apperr.Register(apperr.Definition{
+	Code:        "request.invalid",
+	HTTPStatus:  http.StatusBadRequest,
+	Description: "The request breaks the constraints of the API schema, listed in the google.rpc.BadRequest error detail.",
+})
+
+// validationInterceptor rejects the requests breaking their buf.validate rules
+// before they reach the handler.
+type validationInterceptor struct {
+	validator protovalidate.Validator
+}
+
+// NewValidationInterceptor returns an interceptor validating every request
+// message with protovalidate. Failures are returned as CodeInvalidArgument
+// errors carrying a google.rpc.BadRequest detail with one field violation per
+// broken rule.
+func NewValidationInterceptor() (connect.Interceptor, error) {
+	v, err := protovalidate.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validator: %w", err)
+	}
+
+	return &validationInterceptor{validator: v}, nil
+}
+
+func (in *validationInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := in.validate(req.Any()); err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient validates the messages before sending them.
+func (in *validationInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &validatingClientConn{StreamingClientConn: next(ctx, spec), interceptor: in}
+	}
+}
+
+// WrapStreamingHandler validates every received message.
+func (in *validationInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &validatingHandlerConn{StreamingHandlerConn: conn, interceptor: in})
+	}
+}
+
+func (in *validationInterceptor) validate(msg any) error {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	err := in.validator.Validate(m)
+	if err == nil {
+		return nil
+	}
+
+	var verr *protovalidate.ValidationError
+	if !errors.As(err, &verr) {
+		// Compilation or runtime errors are bugs in the rules, not in the request.
+		return ToConnectError(apperr.WrapPrivate(ErrCodeInvalidRequest.Code, http.StatusInternalServerError, err))
+	}
+
+	return validationErrorToConnect(verr)
+}
+
+// validationErrorToConnect converts a protovalidate error to an InvalidArgument
+// Connect error listing the field violations.
+func validationErrorToConnect(verr *protovalidate.ValidationError) error {
+	violations := make([]apperr.FieldViolation, len(verr.Violations))
+	msgs := make([]string, len(verr.Violations))
+	for i, v := range verr.Violations {
+		violations[i] = apperr.FieldViolation{
+			Field:       protovalidate.FieldPathString(v.Proto.GetField()),
+			Description: v.Proto.GetMessage(),
+			Reason:      v.Proto.GetRuleId(),
+		}
+		msgs[i] = violations[i].Field + ": " + violations[i].Description
+	}
+
+	ae := ErrCodeInvalidRequest.New("invalid request: " + strings.Join(msgs, "; "))
+	ae.Violations = violations
+
+	return ToConnectError(ae)
+}
+
+type validatingClientConn struct {
+	connect.StreamingClientConn
+
+	interceptor *validationInterceptor
+}
+
+func (c *validatingClientConn) Send(msg any) error {
+	if err := c.interceptor.validate(msg); err != nil {
+		return err
+	}
+
+	return c.StreamingClientConn.Send(msg) //nolint:wrapcheck // stream errors are returned as is
+}
+
+type validatingHandlerConn struct {
+	connect.StreamingHandlerConn
+
+	interceptor *validationInterceptor
+}
+
+func (c *validatingHandlerConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err //nolint:wrapcheck // stream errors are returned as is
+	}
+
+	return c.interceptor.validate(msg)
+}
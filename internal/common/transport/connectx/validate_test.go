@@ -0,0 +1,46 @@
+package connectx
+
+import (
+	"errors"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"buf.build/go/protovalidate"
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pivaldi/go-cleanstack/internal/common/platform/apperr"
+)
+
+func violation(field, ruleID, msg string) *protovalidate.Violation {
+	return &protovalidate.Violation{Proto: &validate.Violation{
+		Field:   &validate.FieldPath{Elements: []*validate.FieldPathElement{{FieldName: proto.String(field)}}},
+		RuleId:  proto.String(ruleID),
+		Message: proto.String(msg),
+	}}
+}
+
+func TestValidationErrorToConnect(t *testing.T) {
+	err := validationErrorToConnect(&protovalidate.ValidationError{Violations: []*protovalidate.Violation{
+		violation("offset", "int32.gte", "value must be greater than or equal to 0"),
+		violation("limit", "int32.lte", "value must be less than or equal to 100"),
+	}})
+
+	var cerr *connect.Error
+	require.True(t, errors.As(err, &cerr))
+	assert.Equal(t, connect.CodeInvalidArgument, cerr.Code())
+	assert.Equal(t, ErrCodeInvalidRequest.Code, cerr.Meta().Get(ErrorCodeHeader))
+	assert.Equal(t,
+		"invalid request: offset: value must be greater than or equal to 0; limit: value must be less than or equal to 100",
+		cerr.Message())
+
+	ae := AppErrorFromConnect(err)
+	require.NotNil(t, ae)
+	assert.Equal(t, ErrCodeInvalidRequest.Code, ae.Code)
+	assert.Equal(t, []apperr.FieldViolation{
+		{Field: "offset", Description: "value must be greater than or equal to 0", Reason: "int32.gte"},
+		{Field: "limit", Description: "value must be less than or equal to 100", Reason: "int32.lte"},
+	}, ae.Violations)
+}
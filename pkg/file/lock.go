@@ -51,13 +51,15 @@ func SaveCurrentPID(fileName string) error {
 }
 
 // CreatePidFile opens the named file, applies exclusive lock and writes
-// current process id to file.
+// current process id to file. When another process holds the lock, the
+// error wraps ErrWouldBlock and the file is left untouched.
 func CreatePidFile(name string, perm os.FileMode) (lock *LockFile, err error) {
 	if lock, err = OpenLockFile(name, perm); err != nil {
 		return
 	}
 	if err = lock.Lock(); err != nil {
-		_ = lock.Remove()
+		// The file belongs to the process holding the lock.
+		_ = lock.Close()
 		return
 	}
 	if err = lock.WritePid(); err != nil {
@@ -170,22 +172,7 @@ func (file *LockFile) Remove() error {
 
 // GetFdName returns file name for given descriptor.
 func GetFdName(fd uintptr) (name string, err error) {
-	path := fmt.Sprintf("/proc/self/fd/%d", int(fd))
-
-	var (
-		fi os.FileInfo
-		n  int
-	)
-
-	if fi, err = os.Lstat(path); err != nil {
-		return
-	}
-
-	buf := make([]byte, fi.Size()+1)
-
-	if n, err = syscall.Readlink(path, buf); err == nil {
-		name = string(buf[:n])
-	}
-
-	return
+	// The size of the /proc/self/fd links is not the length of their target,
+	// which os.Readlink reads whole.
+	return os.Readlink(fmt.Sprintf("/proc/self/fd/%d", int(fd)))
 }
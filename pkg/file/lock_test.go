@@ -1,6 +1,7 @@
 package file
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -376,6 +377,23 @@ func TestCreatePidFile(t *testing.T) {
 		}
 	})
 
+	t.Run("keeps the pid file of the process holding the lock", func(t *testing.T) {
+		pidPath := filepath.Join(t.TempDir(), "test.pid")
+		lockFile, err := CreatePidFile(pidPath, 0644)
+		if err != nil {
+			t.Fatalf("CreatePidFile() returned error: %v", err)
+		}
+		defer lockFile.Remove()
+
+		_, err = CreatePidFile(pidPath, 0644)
+		if !errors.Is(err, ErrWouldBlock) {
+			t.Fatalf("expected ErrWouldBlock, got %v", err)
+		}
+		if !Exists(pidPath) {
+			t.Error("pid file of the process holding the lock was removed")
+		}
+	})
+
 	t.Run("returns error for invalid path", func(t *testing.T) {
 		_, err := CreatePidFile("/nonexistent/dir/test.pid", 0644)
 		if err == nil {
@@ -9,15 +9,22 @@ fi
 case "$APP_ENV" in
 development)
   export APP_PORT=4224
+  # The port of [platform.server] listen in configs/config_$APP_ENV.toml.
+  export APP_INTERNAL_PORT=4224
   export DB_PORT=5435
+  export APP_SCHEME=http
   ;;
 staging)
   export APP_PORT=4225
+  export APP_INTERNAL_PORT=4225
   export DB_PORT=5436
+  export APP_SCHEME=https
   ;;
 production)
   export APP_PORT=4226
+  export APP_INTERNAL_PORT=4224
   export DB_PORT=5437
+  export APP_SCHEME=https
   ;;
 *)
   echo "Error: APP_ENV must be one of: development, staging, production"